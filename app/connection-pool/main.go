@@ -31,6 +31,12 @@ type Config struct {
 	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
 	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
 	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
+
+	// Pool settings of the demo, GetDatabaseConnection applies them
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"14"`
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"2"`
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"15m"`
 }
 
 func main() {
@@ -52,11 +58,18 @@ func main() {
 		DBPostgreSQLUser:     config.DBPostgreSQLUser,
 		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
 		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
+		DBMaxOpenConns:       config.DBMaxOpenConns,
+		DBMaxIdleConns:       config.DBMaxIdleConns,
+		DBConnMaxLifetime:    config.DBConnMaxLifetime,
+	}
+	db, err := databaseConfig.GetDatabaseConnection()
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
 	}
-	db := databaseConfig.GetDatabaseConnection()
 	logger.Info("Database client connected!")
 
-	// Configure the connection pool and to acquire/release connections.
+	// The pool is configured already, sql.DB is used to acquire/release connections.
 	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatalf("failed to get sql.DB from gorm: %v", err)
@@ -69,10 +82,7 @@ func main() {
 		}
 	}()
 
-	// Configure pool settings
-	sqlDB.SetMaxOpenConns(14)                  // maximum open connections
-	sqlDB.SetMaxIdleConns(2)                   // maximum idle connections
-	sqlDB.SetConnMaxLifetime(15 * time.Minute) // connection max lifetime
+	logger.Info("Pool configured", "max_open_conns", sqlDB.Stats().MaxOpenConnections)
 
 	logger.Info("delay 5 seconds, this is to observe initial connections")
 	time.Sleep(5 * time.Second)
@@ -0,0 +1,19 @@
+package common
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/util/httperror"
+)
+
+// ErrorResponse writes the response for an error returned by a service.
+func ErrorResponse(c echo.Context, err error) error {
+	status := httperror.Status(err)
+	if status >= http.StatusInternalServerError {
+		return c.JSON(status, map[string]interface{}{"message": http.StatusText(status)})
+	}
+
+	return c.JSON(status, map[string]interface{}{"message": errs.Message(err)})
+}
@@ -1,6 +1,7 @@
 package inventory
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -8,30 +9,37 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
 	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
 )
 
 type Controller struct {
 	logger       *slog.Logger
 	inventorySvc inventory.Service
+	rbacSvc      rbac.Service
 }
 
 func NewController(
 	logger *slog.Logger,
 	s inventory.Service,
+	rbacSvc rbac.Service,
 ) *Controller {
 	return &Controller{
 		logger:       logger,
 		inventorySvc: s,
+		rbacSvc:      rbacSvc,
 	}
 }
 
 type InventoryRequest struct {
-	Code        string `json:"code" validate:"required"`
-	Name        string `json:"name" validate:"required"`
-	Stock       int    `json:"stock"`
-	Description string `json:"description"`
-	Status      string `json:"status" validate:"required,oneof=active broken"`
+	Code        string   `json:"code" validate:"required"`
+	Name        string   `json:"name" validate:"required"`
+	Stock       int      `json:"stock"`
+	Description string   `json:"description"`
+	Status      string   `json:"status" validate:"required,oneof=active broken"`
+	Tags        []string `json:"tags"`
 }
 
 func (ctrl *Controller) Create(c echo.Context) error {
@@ -46,30 +54,26 @@ func (ctrl *Controller) Create(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
 	}
 
-	if err := ctrl.inventorySvc.Create(inventory.Inventory{
+	if err := ctrl.inventorySvc.Create(c.Request().Context(), inventory.Inventory{
 		Code:        req.Code,
 		Name:        req.Name,
 		Stock:       req.Stock,
 		Description: req.Description,
 		Status:      req.Status,
+		Tags:        req.Tags,
 	}); err != nil {
 		ctrl.logger.Error("inventory.Create Service Error", slog.Any("error", err))
-
-		if strings.Contains(err.Error(), "duplicate key") {
-			return c.JSON(http.StatusConflict, map[string]string{"message": "Data conflict"})
-		}
-
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return common.ErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{"message": "OK", "data": "data"})
 }
 
-func (ctrl *Controller) GetAll(c echo.Context) error {
+func paging(c echo.Context) (page int, limit int) {
 	pReq := c.QueryParam("page")
 	lReq := c.QueryParam("limit")
-	page, _ := strconv.Atoi(pReq)
-	limit, _ := strconv.Atoi(lReq)
+	page, _ = strconv.Atoi(pReq)
+	limit, _ = strconv.Atoi(lReq)
 
 	if page < 1 {
 		page = 1
@@ -77,11 +81,23 @@ func (ctrl *Controller) GetAll(c echo.Context) error {
 	if limit < 1 {
 		limit = 10
 	}
+	return
+}
+
+func splitTags(tagsReq string) []string {
+	if tagsReq == "" {
+		return nil
+	}
+	return strings.Split(tagsReq, ",")
+}
+
+func (ctrl *Controller) GetAll(c echo.Context) error {
+	page, limit := paging(c)
 
-	invs, err := ctrl.inventorySvc.GetAll(page, limit)
+	invs, err := ctrl.inventorySvc.GetAll(c.Request().Context(), inventory.Filter{Tags: splitTags(c.QueryParam("tags"))}, page, limit)
 	if err != nil {
 		ctrl.logger.Error("inventory.GetAll Service Error", slog.Any("error", err))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return common.ErrorResponse(c, err)
 	}
 
 	if len(invs) == 0 {
@@ -97,14 +113,10 @@ func (ctrl *Controller) GetByCode(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Code parameter is required"})
 	}
 
-	inv, err := ctrl.inventorySvc.GetByCode(code)
+	inv, err := ctrl.inventorySvc.GetByCode(c.Request().Context(), code)
 	if err != nil {
 		ctrl.logger.Error("inventory.GetByCode Service Error", slog.Any("error", err))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
-	}
-
-	if inv.Code == "" {
-		return c.JSON(http.StatusNotFound, map[string]string{"message": "Data not found"})
+		return common.ErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": inv})
@@ -123,7 +135,7 @@ func (ctrl *Controller) Update(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
 	}
 
-	if err := ctrl.inventorySvc.Update(inventory.Inventory{
+	if err := ctrl.inventorySvc.Update(c.Request().Context(), inventory.Inventory{
 		Code:        req.Code,
 		Name:        req.Name,
 		Stock:       req.Stock,
@@ -131,12 +143,7 @@ func (ctrl *Controller) Update(c echo.Context) error {
 		Status:      req.Status,
 	}); err != nil {
 		ctrl.logger.Error("inventory.Update Service Error", slog.Any("error", err))
-
-		if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
-			return c.JSON(http.StatusConflict, map[string]string{"message": "Data conflict"})
-		}
-
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return common.ErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
@@ -148,10 +155,180 @@ func (ctrl *Controller) Delete(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Code parameter is required"})
 	}
 
-	if err := ctrl.inventorySvc.Delete(code); err != nil {
+	if err := ctrl.inventorySvc.Delete(c.Request().Context(), code); err != nil {
 		ctrl.logger.Error("inventory.Delete Service Error", slog.Any("error", err))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
+}
+
+type TagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1"`
+}
+
+func (ctrl *Controller) AddTags(c echo.Context) error {
+	var req TagsRequest
+	if err := c.Bind(&req); err != nil {
+		ctrl.logger.Error("inventory.AddTags Bind Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		ctrl.logger.Error("inventory.AddTags Validation Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	if err := ctrl.inventorySvc.AddTags(c.Request().Context(), c.Param("code"), req.Tags); err != nil {
+		ctrl.logger.Error("inventory.AddTags Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
+}
+
+func (ctrl *Controller) RemoveTag(c echo.Context) error {
+	if err := ctrl.inventorySvc.RemoveTags(c.Request().Context(), c.Param("code"), []string{c.Param("tag")}); err != nil {
+		ctrl.logger.Error("inventory.RemoveTag Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
+}
+
+type SavedSearchRequest struct {
+	Name string   `json:"name" validate:"required,max=100"`
+	Tags []string `json:"tags"`
+}
+
+func (ctrl *Controller) CreateSavedSearch(c echo.Context) error {
+	var req SavedSearchRequest
+	if err := c.Bind(&req); err != nil {
+		ctrl.logger.Error("inventory.CreateSavedSearch Bind Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		ctrl.logger.Error("inventory.CreateSavedSearch Validation Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	userID, _ := c.Get("id").(string)
+	id, err := ctrl.inventorySvc.CreateSavedSearch(c.Request().Context(), inventory.SavedSearch{
+		UserID: userID,
+		Name:   req.Name,
+		Filter: inventory.Filter{Tags: req.Tags},
+	})
+	if err != nil {
+		ctrl.logger.Error("inventory.CreateSavedSearch Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{"message": "OK", "data": map[string]string{"id": id}})
+}
+
+func (ctrl *Controller) GetSavedSearches(c echo.Context) error {
+	userID, _ := c.Get("id").(string)
+	searches, err := ctrl.inventorySvc.GetSavedSearches(c.Request().Context(), userID)
+	if err != nil {
+		ctrl.logger.Error("inventory.GetSavedSearches Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	if len(searches) == 0 {
+		searches = []inventory.SavedSearch{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": searches})
+}
+
+func (ctrl *Controller) RunSavedSearch(c echo.Context) error {
+	page, limit := paging(c)
+
+	userID, _ := c.Get("id").(string)
+	invs, err := ctrl.inventorySvc.RunSavedSearch(c.Request().Context(), userID, c.Param("id"), page, limit)
+	if err != nil {
+		ctrl.logger.Error("inventory.RunSavedSearch Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	if len(invs) == 0 {
+		invs = []inventory.Inventory{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": invs})
+}
+
+func (ctrl *Controller) DeleteSavedSearch(c echo.Context) error {
+	userID, _ := c.Get("id").(string)
+	if err := ctrl.inventorySvc.DeleteSavedSearch(c.Request().Context(), userID, c.Param("id")); err != nil {
+		ctrl.logger.Error("inventory.DeleteSavedSearch Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
 }
+
+type BulkOperationRequest struct {
+	Op          string   `json:"op"`
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Stock       int      `json:"stock"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Tags        []string `json:"tags"`
+}
+
+type BulkRequest struct {
+	Atomic     bool                   `json:"atomic"`
+	Operations []BulkOperationRequest `json:"operations" validate:"required,min=1"`
+}
+
+func (ctrl *Controller) Bulk(c echo.Context) error {
+	var req BulkRequest
+	if err := c.Bind(&req); err != nil {
+		ctrl.logger.Error("inventory.Bulk Bind Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		ctrl.logger.Error("inventory.Bulk Validation Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	role, _ := c.Get("role").(string)
+	ops := make([]inventory.BulkOperation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		// Deleting needs inventory:delete, the same as DELETE /inventories/:code
+		if op.Op == inventory.BulkOpDelete {
+			if err := ctrl.rbacSvc.Authorize(c.Request().Context(), role, rbac.InventoryDelete); err != nil {
+				return common.ErrorResponse(c, err)
+			}
+		}
+
+		ops = append(ops, inventory.BulkOperation{
+			Op: op.Op,
+			Inventory: inventory.Inventory{
+				Code:        op.Code,
+				Name:        op.Name,
+				Stock:       op.Stock,
+				Description: op.Description,
+				Status:      op.Status,
+				Tags:        op.Tags,
+			},
+		})
+	}
+
+	results, err := ctrl.inventorySvc.Bulk(c.Request().Context(), ops, req.Atomic)
+	if err != nil {
+		ctrl.logger.Error("inventory.Bulk Service Error", slog.Any("error", err))
+
+		if errors.Is(err, inventory.ErrBulkRolledBack) {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{"message": errs.Message(err), "data": results})
+		}
+
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": results})
+}
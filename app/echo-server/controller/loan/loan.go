@@ -0,0 +1,156 @@
+package loan
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
+	"github.com/pobyzaarif/belajarGo2/service/loan"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+)
+
+type Controller struct {
+	logger  *slog.Logger
+	loanSvc loan.Service
+	rbacSvc rbac.Service
+}
+
+func NewController(
+	logger *slog.Logger,
+	s loan.Service,
+	rbacSvc rbac.Service,
+) *Controller {
+	return &Controller{
+		logger:  logger,
+		loanSvc: s,
+		rbacSvc: rbacSvc,
+	}
+}
+
+// scopedUserID returns the user id a request is limited to, empty for roles
+// with loans:read_all, the caller's own id for the others.
+func (ctrl *Controller) scopedUserID(c echo.Context) (userID string, err error) {
+	role, _ := c.Get("role").(string)
+	err = ctrl.rbacSvc.Authorize(c.Request().Context(), role, rbac.LoansReadAll)
+	if errors.Is(err, rbac.ErrPermissionDenied) {
+		userID, _ = c.Get("id").(string)
+		return userID, nil
+	}
+	return "", err
+}
+
+type CheckoutRequest struct {
+	InventoryCode string    `json:"inventory_code" validate:"required"`
+	UserID        string    `json:"user_id" validate:"required"`
+	Quantity      int       `json:"quantity" validate:"required,min=1"`
+	DueAt         time.Time `json:"due_at" validate:"required"`
+}
+
+func (ctrl *Controller) Checkout(c echo.Context) error {
+	var req CheckoutRequest
+	if err := c.Bind(&req); err != nil {
+		ctrl.logger.Error("loan.Checkout Bind Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		ctrl.logger.Error("loan.Checkout Validation Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	id, err := ctrl.loanSvc.Checkout(c.Request().Context(), loan.Loan{
+		InventoryCode: req.InventoryCode,
+		UserID:        req.UserID,
+		Quantity:      req.Quantity,
+		DueAt:         req.DueAt,
+	})
+	if err != nil {
+		ctrl.logger.Error("loan.Checkout Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{"message": "OK", "data": map[string]string{"id": id}})
+}
+
+func (ctrl *Controller) Checkin(c echo.Context) error {
+	if err := ctrl.loanSvc.Checkin(c.Request().Context(), c.Param("id")); err != nil {
+		ctrl.logger.Error("loan.Checkin Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
+}
+
+func (ctrl *Controller) GetAll(c echo.Context) error {
+	pReq := c.QueryParam("page")
+	lReq := c.QueryParam("limit")
+	page, _ := strconv.Atoi(pReq)
+	limit, _ := strconv.Atoi(lReq)
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	userID, err := ctrl.scopedUserID(c)
+	if err != nil {
+		return common.ErrorResponse(c, err)
+	}
+
+	loans, err := ctrl.loanSvc.GetAll(c.Request().Context(), userID, page, limit)
+	if err != nil {
+		ctrl.logger.Error("loan.GetAll Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	if len(loans) == 0 {
+		loans = []loan.Loan{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": loans})
+}
+
+func (ctrl *Controller) GetByID(c echo.Context) error {
+	l, err := ctrl.loanSvc.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		ctrl.logger.Error("loan.GetByID Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	// Hide other users' loans instead of revealing they exist
+	userID, err := ctrl.scopedUserID(c)
+	if err != nil {
+		return common.ErrorResponse(c, err)
+	}
+	if userID != "" && l.UserID != userID {
+		return common.ErrorResponse(c, loan.ErrNotFound)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": l})
+}
+
+func (ctrl *Controller) GetOverdue(c echo.Context) error {
+	userID, err := ctrl.scopedUserID(c)
+	if err != nil {
+		return common.ErrorResponse(c, err)
+	}
+
+	loans, err := ctrl.loanSvc.GetOverdue(c.Request().Context(), userID)
+	if err != nil {
+		ctrl.logger.Error("loan.GetOverdue Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	if len(loans) == 0 {
+		loans = []loan.Loan{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": loans})
+}
@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+)
+
+type Controller struct {
+	logger  *slog.Logger
+	rbacSvc rbac.Service
+}
+
+func NewController(
+	logger *slog.Logger,
+	s rbac.Service,
+) *Controller {
+	return &Controller{
+		logger:  logger,
+		rbacSvc: s,
+	}
+}
+
+// GetAll godoc
+// @Summary      List roles
+// @Description  Every role with its permissions
+// @Tags         Admin
+// @Produce      json
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/roles [get]
+func (ctrl *Controller) GetAll(c echo.Context) error {
+	roles, err := ctrl.rbacSvc.GetAll(c.Request().Context())
+	if err != nil {
+		ctrl.logger.Error("rbac.GetAll Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]interface{}{
+		"roles":       roles,
+		"permissions": rbac.Permissions,
+	}})
+}
+
+type setPermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// SetPermissions godoc
+// @Summary      Set the permissions of a role
+// @Description  Replace the permissions of a role, they apply to the tokens already handed out
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        role path string true "Role"
+// @Param        request body setPermissionsRequest true "Set permissions request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/roles/{role}/permissions [put]
+func (ctrl *Controller) SetPermissions(c echo.Context) error {
+	request := new(setPermissionsRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	adminID, _ := c.Get("id").(string)
+	adminRole, _ := c.Get("role").(string)
+	role, err := ctrl.rbacSvc.SetPermissions(c.Request().Context(), adminID, adminRole, c.Param("role"), request.Permissions)
+	if err != nil {
+		ctrl.logger.Error("rbac.SetPermissions Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": role})
+}
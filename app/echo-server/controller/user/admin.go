@@ -0,0 +1,219 @@
+package user
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+)
+
+// userResponse is what the API shows of a user, never the password.
+type userResponse struct {
+	ID                      string     `json:"id"`
+	Email                   string     `json:"email"`
+	Fullname                string     `json:"fullname"`
+	Role                    string     `json:"role"`
+	IsEmailVerified         bool       `json:"is_email_verified"`
+	VerificationEmailStatus string     `json:"verification_email_status,omitempty"`
+	VerificationEmailSentAt *time.Time `json:"verification_email_sent_at,omitempty"`
+	IsActive                bool       `json:"is_active"`
+	DeactivatedAt           *time.Time `json:"deactivated_at,omitempty"`
+}
+
+func newUserResponse(u user.User) userResponse {
+	return userResponse{
+		ID:                      u.ID,
+		Email:                   u.Email,
+		Fullname:                u.Fullname,
+		Role:                    u.Role,
+		IsEmailVerified:         u.IsEmailVerified,
+		VerificationEmailStatus: u.VerificationEmailStatus,
+		VerificationEmailSentAt: u.VerificationEmailSentAt,
+		IsActive:                u.DeactivatedAt == nil,
+		DeactivatedAt:           u.DeactivatedAt,
+	}
+}
+
+// GetAll godoc
+// @Summary      List users
+// @Description  List users by email, search matches a part of the email or the fullname
+// @Tags         Admin
+// @Produce      json
+// @Param        search query string false "Part of the email or fullname"
+// @Param        role query string false "Role"
+// @Param        page query int false "Page, from 1"
+// @Param        limit query int false "Users per page, 10 by default"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users [get]
+func (ctrl *Controller) GetAll(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	filter := user.Filter{Search: c.QueryParam("search"), Role: c.QueryParam("role")}
+	users, err := ctrl.userSvc.GetAll(c.Request().Context(), filter, page, limit)
+	if err != nil {
+		ctrl.logger.Error("user.GetAll Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	data := make([]userResponse, 0, len(users))
+	for _, u := range users {
+		data = append(data, newUserResponse(u))
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": data})
+}
+
+// GetByID godoc
+// @Summary      Get a user
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users/{id} [get]
+func (ctrl *Controller) GetByID(c echo.Context) error {
+	u, err := ctrl.userSvc.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		ctrl.logger.Error("user.GetByID Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": newUserResponse(u)})
+}
+
+type userChangeRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// ChangeRole godoc
+// @Summary      Change the role of a user
+// @Description  Give a user another role, they are logged out everywhere
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Param        request body userChangeRoleRequest true "Change role request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users/{id}/role [put]
+func (ctrl *Controller) ChangeRole(c echo.Context) error {
+	request := new(userChangeRoleRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	adminID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.ChangeRole(c.Request().Context(), adminID, c.Param("id"), request.Role); err != nil {
+		ctrl.logger.Error("user.ChangeRole Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// Deactivate godoc
+// @Summary      Deactivate a user
+// @Description  Keep a user from logging in, they are logged out everywhere
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users/{id}/deactivate [post]
+func (ctrl *Controller) Deactivate(c echo.Context) error {
+	adminID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.DeactivateUser(c.Request().Context(), adminID, c.Param("id")); err != nil {
+		ctrl.logger.Error("user.DeactivateUser Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// Activate godoc
+// @Summary      Activate a user
+// @Description  Let a deactivated user log in again
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users/{id}/activate [post]
+func (ctrl *Controller) Activate(c echo.Context) error {
+	adminID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.ActivateUser(c.Request().Context(), adminID, c.Param("id")); err != nil {
+		ctrl.logger.Error("user.ActivateUser Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// ForceVerifyEmail godoc
+// @Summary      Verify the email of a user
+// @Description  Mark the email of a user verified without the activation link
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users/{id}/verify-email [post]
+func (ctrl *Controller) ForceVerifyEmail(c echo.Context) error {
+	adminID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.VerifyUserEmail(c.Request().Context(), adminID, c.Param("id")); err != nil {
+		ctrl.logger.Error("user.VerifyUserEmail Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// AdminResetPassword godoc
+// @Summary      Reset the password of a user
+// @Description  Replace the password of a user with a random one and email them a link to set a new one, they are logged out everywhere
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /admin/users/{id}/reset-password [post]
+func (ctrl *Controller) AdminResetPassword(c echo.Context) error {
+	adminID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.ResetUserPassword(c.Request().Context(), adminID, c.Param("id")); err != nil {
+		ctrl.logger.Error("user.ResetUserPassword Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
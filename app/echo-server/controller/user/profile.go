@@ -0,0 +1,158 @@
+package user
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
+)
+
+// Me godoc
+// @Summary      Get my profile
+// @Description  The account of the user of the access token
+// @Tags         Users
+// @Produce      json
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/me [get]
+func (ctrl *Controller) Me(c echo.Context) error {
+	userID, _ := c.Get("id").(string)
+	u, err := ctrl.userSvc.GetByID(c.Request().Context(), userID)
+	if err != nil {
+		ctrl.logger.Error("user.GetByID Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": newUserResponse(u)})
+}
+
+type userUpdateProfileRequest struct {
+	Fullname string `json:"fullname" validate:"required,max=100"`
+}
+
+// UpdateMe godoc
+// @Summary      Update my profile
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userUpdateProfileRequest true "Update profile request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/me [patch]
+func (ctrl *Controller) UpdateMe(c echo.Context) error {
+	request := new(userUpdateProfileRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	userID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.UpdateProfile(c.Request().Context(), userID, request.Fullname); err != nil {
+		ctrl.logger.Error("user.UpdateProfile Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+type userChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+}
+
+// ChangePassword godoc
+// @Summary      Change my password
+// @Description  Set a new password with the current one, every session of the user is logged out
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userChangePasswordRequest true "Change password request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      429 {object} map[string]interface{} "Too Many Requests"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/me/password [post]
+func (ctrl *Controller) ChangePassword(c echo.Context) error {
+	request := new(userChangePasswordRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	userID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.ChangePassword(c.Request().Context(), userID, request.CurrentPassword, request.Password); err != nil {
+		ctrl.logger.Error("user.ChangePassword Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+type userChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RequestEmailChange godoc
+// @Summary      Change my email
+// @Description  Email a link to the new address, the email only changes once it is opened
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userChangeEmailRequest true "Change email request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      409 {object} map[string]interface{} "Conflict"
+// @Failure      429 {object} map[string]interface{} "Too Many Requests"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/me/email [post]
+func (ctrl *Controller) RequestEmailChange(c echo.Context) error {
+	request := new(userChangeEmailRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	userID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.RequestEmailChange(c.Request().Context(), userID, request.Email, request.Password); err != nil {
+		ctrl.logger.Error("user.RequestEmailChange Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// ConfirmEmailChange godoc
+// @Summary      Confirm an email change
+// @Description  Switch to the new email with the code of the link emailed to it
+// @Tags         Users
+// @Produce      json
+// @Param        code query string true "Email change code"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      409 {object} map[string]interface{} "Conflict"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/email-change [get]
+func (ctrl *Controller) ConfirmEmailChange(c echo.Context) error {
+	if err := ctrl.userSvc.ConfirmEmailChange(c.Request().Context(), c.QueryParam("code")); err != nil {
+		ctrl.logger.Error("user.ConfirmEmailChange Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
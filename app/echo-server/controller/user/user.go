@@ -1,12 +1,14 @@
 package user
 
 import (
+	"bytes"
+	"html/template"
 	"log/slog"
 	"net/http"
-	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
 	"github.com/pobyzaarif/belajarGo2/service/user"
 )
 
@@ -40,6 +42,7 @@ type userRegisterRequest struct {
 // @Param        request body userRegisterRequest true "User registration request"
 // @Success      201 {object} map[string]interface{} "Created"
 // @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      409 {object} map[string]interface{} "Conflict"
 // @Failure      500 {object} map[string]interface{} "Internal Server Error"
 // @Router       /users/register [post]
 func (ctrl *Controller) Register(c echo.Context) error {
@@ -52,16 +55,14 @@ func (ctrl *Controller) Register(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
 	}
 
-	_, err := ctrl.userSvc.Register(user.User{
+	_, err := ctrl.userSvc.Register(c.Request().Context(), user.User{
 		Email:    request.Email,
 		Password: request.Password,
 		Fullname: request.Fullname,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "registered") {
-			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
-		}
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": http.StatusText(http.StatusInternalServerError)})
+		ctrl.logger.Error("user.Register Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{"message": http.StatusText(http.StatusCreated)})
@@ -94,27 +95,415 @@ func (ctrl *Controller) Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
 	}
 
-	accessToken, err := ctrl.userSvc.Login(request.Email, request.Password)
+	tokens, err := ctrl.userSvc.Login(c.Request().Context(), request.Email, request.Password, c.RealIP())
 	if err != nil {
-		if strings.Contains(err.Error(), "email address") {
-			return c.JSON(http.StatusUnauthorized, map[string]interface{}{"message": err.Error()})
-		}
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": http.StatusText(http.StatusInternalServerError)})
+		ctrl.logger.Error("user.Login Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": accessToken})
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": tokens})
+}
+
+type userLoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// LoginMFA godoc
+// @Summary      Login with two-factor authentication
+// @Description  Complete a login that answered mfa_required with a code of the authenticator app or a recovery code
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userLoginMFARequest true "Two-factor login request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      429 {object} map[string]interface{} "Too Many Requests"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/login/mfa [post]
+func (ctrl *Controller) LoginMFA(c echo.Context) error {
+	request := new(userLoginMFARequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	tokens, err := ctrl.userSvc.LoginMFA(c.Request().Context(), request.MFAToken, request.Code, c.RealIP())
+	if err != nil {
+		ctrl.logger.Error("user.LoginMFA Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": tokens})
+}
+
+type userRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Refresh godoc
+// @Summary      Refresh the access token
+// @Description  Trade a refresh token for a new access token and refresh token, a refresh token works once
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userRefreshRequest true "Refresh token request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/token/refresh [post]
+func (ctrl *Controller) Refresh(c echo.Context) error {
+	request := new(userRefreshRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	tokens, err := ctrl.userSvc.Refresh(c.Request().Context(), request.RefreshToken)
+	if err != nil {
+		ctrl.logger.Error("user.Refresh Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": tokens})
+}
+
+type userLogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the access token of the request and, when given, the refresh token
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userLogoutRequest false "Logout request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/logout [post]
+func (ctrl *Controller) Logout(c echo.Context) error {
+	request := new(userLogoutRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	token, _ := c.Get("token").(user.AccessToken)
+	if err := ctrl.userSvc.Logout(c.Request().Context(), token, request.RefreshToken); err != nil {
+		ctrl.logger.Error("user.Logout Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// LogoutAll godoc
+// @Summary      Logout everywhere
+// @Description  Revoke every access token and refresh token of the user, on every device
+// @Tags         Users
+// @Produce      json
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/logout-all [post]
+func (ctrl *Controller) LogoutAll(c echo.Context) error {
+	token, _ := c.Get("token").(user.AccessToken)
+	if err := ctrl.userSvc.LogoutAll(c.Request().Context(), token); err != nil {
+		ctrl.logger.Error("user.LogoutAll Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+type userResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResendVerification godoc
+// @Summary      Resend the verification email
+// @Description  Email a new activation link, at most once a minute, the answer is the same whether the email is registered or not
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userResendVerificationRequest true "Resend verification request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      429 {object} map[string]interface{} "Too Many Requests"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/email-verification/resend [post]
+func (ctrl *Controller) ResendVerification(c echo.Context) error {
+	request := new(userResendVerificationRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := ctrl.userSvc.ResendVerification(c.Request().Context(), request.Email); err != nil {
+		ctrl.logger.Error("user.ResendVerification Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
 }
 
 func (ctrl *Controller) VerifyEmail(c echo.Context) error {
 	encCode := c.Param("code")
 
-	err := ctrl.userSvc.VerifyEmail(encCode)
+	err := ctrl.userSvc.VerifyEmail(c.Request().Context(), encCode)
 	if err != nil {
-		if strings.Contains(err.Error(), "invalid or expired") {
-			return c.JSON(http.StatusUnauthorized, map[string]interface{}{"message": err.Error()})
-		}
+		ctrl.logger.Error("user.VerifyEmail Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+type userForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ForgotPassword godoc
+// @Summary      Forgot password
+// @Description  Email a link to reset the password, the answer is the same whether the email is registered or not
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userForgotPasswordRequest true "Forgot password request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/password/forgot [post]
+func (ctrl *Controller) ForgotPassword(c echo.Context) error {
+	request := new(userForgotPasswordRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := ctrl.userSvc.ForgotPassword(c.Request().Context(), request.Email); err != nil {
+		ctrl.logger.Error("user.ForgotPassword Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+type userResetPasswordRequest struct {
+	Code     string `json:"code" form:"code" validate:"required"`
+	Password string `json:"password" form:"password" validate:"required"`
+}
+
+// passwordResetForm posts back to the page it's served on, the code in a
+// hidden field.
+var passwordResetForm = template.Must(template.New("password-reset").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Reset password</title></head>
+<body>
+<form method="post">
+<input type="hidden" name="code" value="{{.}}">
+<label>New password <input type="password" name="password" required></label>
+<button type="submit">Reset password</button>
+</form>
+</body>
+</html>
+`))
+
+// PasswordResetForm godoc
+// @Summary      Password reset form
+// @Description  The page emailed reset links open when APP_PASSWORD_RESET_URL isn't set, it posts the new password to /users/password/reset
+// @Tags         Users
+// @Produce      html
+// @Param        code query string true "Reset code"
+// @Success      200 {string} string "HTML form"
+// @Router       /users/password/reset [get]
+func (ctrl *Controller) PasswordResetForm(c echo.Context) error {
+	var page bytes.Buffer
+	if err := passwordResetForm.Execute(&page, c.QueryParam("code")); err != nil {
+		ctrl.logger.Error("user.PasswordResetForm Error", slog.Any("error", err))
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": http.StatusText(http.StatusInternalServerError)})
 	}
 
+	// The code is in the url, keep it out of the Referer of anything loaded
+	c.Response().Header().Set("Referrer-Policy", "no-referrer")
+	return c.HTMLBlob(http.StatusOK, page.Bytes())
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the code of the emailed link, every session of the user is logged out
+// @Tags         Users
+// @Accept       json,x-www-form-urlencoded
+// @Produce      json
+// @Param        request body userResetPasswordRequest true "Reset password request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/password/reset [post]
+func (ctrl *Controller) ResetPassword(c echo.Context) error {
+	request := new(userResetPasswordRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	if err := ctrl.userSvc.ResetPassword(c.Request().Context(), request.Code, request.Password); err != nil {
+		ctrl.logger.Error("user.ResetPassword Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// UnlockAccount godoc
+// @Summary      Unlock an account
+// @Description  Lift the lock of an account with the code of the link emailed when it was locked
+// @Tags         Users
+// @Produce      json
+// @Param        code query string true "Unlock code"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/unlock [get]
+func (ctrl *Controller) UnlockAccount(c echo.Context) error {
+	err := ctrl.userSvc.UnlockAccount(c.Request().Context(), c.QueryParam("code"))
+	if err != nil {
+		ctrl.logger.Error("user.UnlockAccount Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user
+// @Description  Lift the lock of the account of a user after too many failed logins
+// @Tags         Users
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/{id}/unlock [post]
+func (ctrl *Controller) UnlockUser(c echo.Context) error {
+	adminID, _ := c.Get("id").(string)
+	err := ctrl.userSvc.UnlockUser(c.Request().Context(), adminID, c.Param("id"))
+	if err != nil {
+		ctrl.logger.Error("user.UnlockUser Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
+}
+
+// EnrollMFA godoc
+// @Summary      Enrol two-factor authentication
+// @Description  Start two-factor authentication with a new secret, the uri is shown as a QR code to an authenticator app
+// @Tags         Users
+// @Produce      json
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      409 {object} map[string]interface{} "Conflict"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/mfa/enroll [post]
+func (ctrl *Controller) EnrollMFA(c echo.Context) error {
+	userID, _ := c.Get("id").(string)
+	enrolment, err := ctrl.userSvc.EnrollMFA(c.Request().Context(), userID)
+	if err != nil {
+		ctrl.logger.Error("user.EnrollMFA Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": enrolment})
+}
+
+type userMFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// ConfirmMFA godoc
+// @Summary      Verify two-factor authentication
+// @Description  Enable the enrolled two-factor authentication with a first code, answers the recovery codes, they aren't shown again
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userMFACodeRequest true "Two-factor code request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      409 {object} map[string]interface{} "Conflict"
+// @Failure      429 {object} map[string]interface{} "Too Many Requests"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/mfa/verify [post]
+func (ctrl *Controller) ConfirmMFA(c echo.Context) error {
+	request := new(userMFACodeRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	userID, _ := c.Get("id").(string)
+	recoveryCodes, err := ctrl.userSvc.ConfirmMFA(c.Request().Context(), userID, request.Code)
+	if err != nil {
+		ctrl.logger.Error("user.ConfirmMFA Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]interface{}{"recovery_codes": recoveryCodes}})
+}
+
+// DisableMFA godoc
+// @Summary      Disable two-factor authentication
+// @Description  Turn two-factor authentication off with a code of the authenticator app or a recovery code
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request body userMFACodeRequest true "Two-factor code request"
+// @Success      200 {object} map[string]interface{} "Status OK"
+// @Failure      400 {object} map[string]interface{} "Bad Request"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Not Found"
+// @Failure      429 {object} map[string]interface{} "Too Many Requests"
+// @Failure      500 {object} map[string]interface{} "Internal Server Error"
+// @Router       /users/mfa/disable [post]
+func (ctrl *Controller) DisableMFA(c echo.Context) error {
+	request := new(userMFACodeRequest)
+	if err := c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+	if err := validator.New().Struct(request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": http.StatusText(http.StatusBadRequest)})
+	}
+
+	userID, _ := c.Get("id").(string)
+	if err := ctrl.userSvc.DisableMFA(c.Request().Context(), userID, request.Code); err != nil {
+		ctrl.logger.Error("user.DisableMFA Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK"})
 }
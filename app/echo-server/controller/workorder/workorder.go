@@ -0,0 +1,155 @@
+package workorder
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/common"
+	"github.com/pobyzaarif/belajarGo2/service/workorder"
+)
+
+type Controller struct {
+	logger       *slog.Logger
+	workOrderSvc workorder.Service
+}
+
+func NewController(
+	logger *slog.Logger,
+	s workorder.Service,
+) *Controller {
+	return &Controller{
+		logger:       logger,
+		workOrderSvc: s,
+	}
+}
+
+type OpenRequest struct {
+	InventoryCode string `json:"inventory_code" validate:"required"`
+	Technician    string `json:"technician" validate:"max=100"`
+	Cost          int64  `json:"cost" validate:"min=0"`
+	Notes         string `json:"notes"`
+}
+
+func (ctrl *Controller) Open(c echo.Context) error {
+	var req OpenRequest
+	if err := c.Bind(&req); err != nil {
+		ctrl.logger.Error("workorder.Open Bind Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		ctrl.logger.Error("workorder.Open Validation Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	id, err := ctrl.workOrderSvc.Open(c.Request().Context(), workorder.WorkOrder{
+		InventoryCode: req.InventoryCode,
+		Technician:    req.Technician,
+		Cost:          req.Cost,
+		Notes:         req.Notes,
+	})
+	if err != nil {
+		ctrl.logger.Error("workorder.Open Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{"message": "OK", "data": map[string]string{"id": id}})
+}
+
+func (ctrl *Controller) GetAll(c echo.Context) error {
+	pReq := c.QueryParam("page")
+	lReq := c.QueryParam("limit")
+	page, _ := strconv.Atoi(pReq)
+	limit, _ := strconv.Atoi(lReq)
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	status := c.QueryParam("status")
+	if status != "" && status != workorder.StatusOpen && status != workorder.StatusClosed {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	wos, err := ctrl.workOrderSvc.GetAll(c.Request().Context(), status, page, limit)
+	if err != nil {
+		ctrl.logger.Error("workorder.GetAll Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	if len(wos) == 0 {
+		wos = []workorder.WorkOrder{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": wos})
+}
+
+func (ctrl *Controller) GetByID(c echo.Context) error {
+	wo, err := ctrl.workOrderSvc.GetByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		ctrl.logger.Error("workorder.GetByID Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": wo})
+}
+
+type UpdateRequest struct {
+	Technician string `json:"technician" validate:"max=100"`
+	Cost       int64  `json:"cost" validate:"min=0"`
+	Notes      string `json:"notes"`
+}
+
+func (ctrl *Controller) Update(c echo.Context) error {
+	var req UpdateRequest
+	if err := c.Bind(&req); err != nil {
+		ctrl.logger.Error("workorder.Update Bind Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		ctrl.logger.Error("workorder.Update Validation Error", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Validation error"})
+	}
+
+	if err := ctrl.workOrderSvc.Update(c.Request().Context(), workorder.WorkOrder{
+		ID:         c.Param("id"),
+		Technician: req.Technician,
+		Cost:       req.Cost,
+		Notes:      req.Notes,
+	}); err != nil {
+		ctrl.logger.Error("workorder.Update Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
+}
+
+func (ctrl *Controller) Close(c echo.Context) error {
+	if err := ctrl.workOrderSvc.Close(c.Request().Context(), c.Param("id")); err != nil {
+		ctrl.logger.Error("workorder.Close Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": map[string]string{}})
+}
+
+func (ctrl *Controller) GetRepairCost(c echo.Context) error {
+	report, err := ctrl.workOrderSvc.GetRepairCost(c.Request().Context())
+	if err != nil {
+		ctrl.logger.Error("workorder.GetRepairCost Service Error", slog.Any("error", err))
+		return common.ErrorResponse(c, err)
+	}
+
+	if len(report) == 0 {
+		report = []workorder.RepairCost{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "OK", "data": report})
+}
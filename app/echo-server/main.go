@@ -13,16 +13,26 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	invCtrl "github.com/pobyzaarif/belajarGo2/app/echo-server/controller/inventory"
+	loanCtrl "github.com/pobyzaarif/belajarGo2/app/echo-server/controller/loan"
+	rbacCtrl "github.com/pobyzaarif/belajarGo2/app/echo-server/controller/rbac"
 	"github.com/pobyzaarif/belajarGo2/app/echo-server/controller/user"
+	woCtrl "github.com/pobyzaarif/belajarGo2/app/echo-server/controller/workorder"
 	_ "github.com/pobyzaarif/belajarGo2/app/echo-server/docs"
 	"github.com/pobyzaarif/belajarGo2/app/echo-server/router"
-	invRepo "github.com/pobyzaarif/belajarGo2/repository/inventory"
+	"github.com/pobyzaarif/belajarGo2/repository/backend"
 	"github.com/pobyzaarif/belajarGo2/repository/notification/mailjet"
-	userRepo "github.com/pobyzaarif/belajarGo2/repository/user"
 	invSvc "github.com/pobyzaarif/belajarGo2/service/inventory"
+	loanSvc "github.com/pobyzaarif/belajarGo2/service/loan"
+	rbacSvc "github.com/pobyzaarif/belajarGo2/service/rbac"
 	userSvc "github.com/pobyzaarif/belajarGo2/service/user"
+	woSvc "github.com/pobyzaarif/belajarGo2/service/workorder"
 	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/pobyzaarif/belajarGo2/util/health"
+	"github.com/pobyzaarif/belajarGo2/util/rediscache"
+	"github.com/pobyzaarif/belajarGo2/util/ttlcache"
+	cache "github.com/pobyzaarif/go-cache"
 	cfg "github.com/pobyzaarif/go-config"
+	redis "github.com/redis/go-redis/v9"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
@@ -34,9 +44,16 @@ type Config struct {
 	AppHost                 string `env:"APP_PHOST"`
 	AppPort                 string `env:"APP_PORT_ECHO_SERVER"`
 	AppDeploymentUrl        string `env:"APP_DEPLOYMENT_URL"`
+	AppPasswordResetUrl     string `env:"APP_PASSWORD_RESET_URL"`
 	AppEmailVerificationKey string `env:"APP_EMAIL_VERIFICATION_KEY"`
 	AppJWTSecret            string `env:"APP_JWT_SECRET"`
 
+	AppAccessTokenTTL  time.Duration `env:"APP_ACCESS_TOKEN_TTL" envDefault:"15m"`
+	AppRefreshTokenTTL time.Duration `env:"APP_REFRESH_TOKEN_TTL" envDefault:"720h"`
+
+	AppEmailVerificationTTL            time.Duration `env:"APP_EMAIL_VERIFICATION_TTL" envDefault:"5m"`
+	AppEmailVerificationResendInterval time.Duration `env:"APP_EMAIL_VERIFICATION_RESEND_INTERVAL" envDefault:"1m"`
+
 	DBDriver string `env:"DB_DRIVER"`
 
 	DBMySQLHost     string `env:"DB_MYSQL_HOST"`
@@ -52,15 +69,75 @@ type Config struct {
 	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
 	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
 	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
 
 	DBMongoURI  string `env:"DB_MONGO_URI"`
 	DBMongoName string `env:"DB_MONGO_NAME"`
 
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME"`
+	DBStatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES"`
+	DBConnectRetryDelay time.Duration `env:"DB_CONNECT_RETRY_DELAY" envDefault:"2s"`
+
+	DBMySQLReplicaHosts          []string      `env:"DB_MYSQL_REPLICA_HOSTS"`
+	DBPostgreSQLReplicaHosts     []string      `env:"DB_POSTGRESQL_REPLICA_HOSTS"`
+	DBSQLiteReplicaNames         []string      `env:"DB_SQLITE_REPLICA_NAMES"`
+	DBReplicaReadAfterWrite      time.Duration `env:"DB_REPLICA_READ_AFTER_WRITE" envDefault:"2s"`
+	DBReplicaHealthCheckInterval time.Duration `env:"DB_REPLICA_HEALTH_CHECK_INTERVAL" envDefault:"10s"`
+
+	DBMongoMaxPoolSize            uint64        `env:"DB_MONGO_MAX_POOL_SIZE"`
+	DBMongoMinPoolSize            uint64        `env:"DB_MONGO_MIN_POOL_SIZE"`
+	DBMongoMaxConnIdleTime        time.Duration `env:"DB_MONGO_MAX_CONN_IDLE_TIME"`
+	DBMongoConnectTimeout         time.Duration `env:"DB_MONGO_CONNECT_TIMEOUT"`
+	DBMongoServerSelectionTimeout time.Duration `env:"DB_MONGO_SERVER_SELECTION_TIMEOUT"`
+
+	DBRequestTimeout time.Duration `env:"DB_REQUEST_TIMEOUT" envDefault:"10s"`
+
+	InventoryRepoBackend string `env:"INVENTORY_REPO_BACKEND" envDefault:"gorm"`
+	UserRepoBackend      string `env:"USER_REPO_BACKEND" envDefault:"gorm"`
+	LoanRepoBackend      string `env:"LOAN_REPO_BACKEND" envDefault:"gorm"`
+	WorkOrderRepoBackend string `env:"WORK_ORDER_REPO_BACKEND" envDefault:"gorm"`
+	MemorySnapshotFile   string `env:"MEMORY_SNAPSHOT_FILE"`
+
 	MailjetBaseUrl           string `env:"MAILJET_BASE_URL"`
 	MailjetBasicAuthUsername string `env:"MAILJET_BASIC_AUTH_USERNAME"`
 	MailjetBasicAuthPassword string `env:"MAILJET_BASIC_AUTH_PASSWORD"`
 	MailjetSenderEmail       string `env:"MAILJET_SENDER_EMAIL"`
 	MailjetSenderName        string `env:"MAILJET_SENDER_NAME"`
+
+	// Redis and RabbitMQ are only checked when set
+	HealthCheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	RedisHost          string        `env:"REDIS_HOST"`
+	RedisPort          string        `env:"REDIS_PORT"`
+	RedisPassword      string        `env:"REDIS_PASSWORD"`
+	RedisDB            int           `env:"REDIS_DB"`
+	RabbitMQURL        string        `env:"RABBITMQ_URL"`
+
+	// Logged out tokens, failed logins and role permissions are kept in Redis
+	// when REDIS_HOST is set, in memory otherwise, where the least used role
+	// permissions are dropped past the size
+	CacheMemorySize int `env:"CACHE_MEMORY_SIZE" envDefault:"100000"`
+
+	AppMaxLoginFailures     int           `env:"APP_MAX_LOGIN_FAILURES" envDefault:"5"`
+	AppMaxIPLoginFailures   int           `env:"APP_MAX_IP_LOGIN_FAILURES" envDefault:"20"`
+	AppLoginLockDuration    time.Duration `env:"APP_LOGIN_LOCK_DURATION" envDefault:"1m"`
+	AppMaxLoginLockDuration time.Duration `env:"APP_MAX_LOGIN_LOCK_DURATION" envDefault:"1h"`
+
+	// The issuer is the name authenticator apps show, users of the required
+	// roles, a comma separated list, must enable two-factor authentication
+	AppMFAIssuer        string   `env:"APP_MFA_ISSUER" envDefault:"belajarGo2"`
+	AppMFARequiredRoles []string `env:"APP_MFA_REQUIRED_ROLES"`
+
+	// How long the permissions of a role are cached, with the memory cache
+	// other servers see an edit only once it expires
+	AppRBACCacheTTL time.Duration `env:"APP_RBAC_CACHE_TTL" envDefault:"1m"`
+
+	// Client ips are read from X-Forwarded-For only behind a trusted proxy,
+	// anyone could set it otherwise
+	AppTrustProxy bool `env:"APP_TRUST_PROXY"`
 }
 
 func main() {
@@ -74,7 +151,7 @@ func main() {
 	}
 	logger.Info("Config loaded")
 
-	// Init db connection
+	// Init repository backends, databases are connected to on first use
 	databaseConfig := database.Config{
 		DBDriver:             config.DBDriver,
 		DBMySQLHost:          config.DBMySQLHost,
@@ -88,16 +165,47 @@ func main() {
 		DBPostgreSQLUser:     config.DBPostgreSQLUser,
 		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
 		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
 		DBMongoURI:           config.DBMongoURI,
 		DBMongoName:          config.DBMongoName,
+
+		DBMaxOpenConns:      config.DBMaxOpenConns,
+		DBMaxIdleConns:      config.DBMaxIdleConns,
+		DBConnMaxLifetime:   config.DBConnMaxLifetime,
+		DBConnMaxIdleTime:   config.DBConnMaxIdleTime,
+		DBStatementTimeout:  config.DBStatementTimeout,
+		DBConnectRetries:    config.DBConnectRetries,
+		DBConnectRetryDelay: config.DBConnectRetryDelay,
+
+		DBMySQLReplicaHosts:          config.DBMySQLReplicaHosts,
+		DBPostgreSQLReplicaHosts:     config.DBPostgreSQLReplicaHosts,
+		DBSQLiteReplicaNames:         config.DBSQLiteReplicaNames,
+		DBReplicaReadAfterWrite:      config.DBReplicaReadAfterWrite,
+		DBReplicaHealthCheckInterval: config.DBReplicaHealthCheckInterval,
+
+		DBMongoMaxPoolSize:            config.DBMongoMaxPoolSize,
+		DBMongoMinPoolSize:            config.DBMongoMinPoolSize,
+		DBMongoMaxConnIdleTime:        config.DBMongoMaxConnIdleTime,
+		DBMongoConnectTimeout:         config.DBMongoConnectTimeout,
+		DBMongoServerSelectionTimeout: config.DBMongoServerSelectionTimeout,
 	}
-	db := databaseConfig.GetDatabaseConnection()
-	logger.Info("Database client connected!")
+	backends := backend.New(backend.Config{
+		Inventory: config.InventoryRepoBackend,
+		User:      config.UserRepoBackend,
+		Loan:      config.LoanRepoBackend,
+		WorkOrder: config.WorkOrderRepoBackend,
+
+		MemorySnapshotFile: config.MemorySnapshotFile,
+	}, databaseConfig)
 
 	// Setup server
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.IPExtractor = echo.ExtractIPDirect()
+	if config.AppTrustProxy {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	}
 
 	e.Use(middleware.CORS())
 	e.Use(middleware.LoggerWithConfig(
@@ -113,6 +221,10 @@ func main() {
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Pre(middleware.Recover())
 
+	// Requests carry a deadline down to the repositories, queries still running
+	// when it passes are cancelled
+	e.Use(middleware.ContextTimeout(config.DBRequestTimeout))
+
 	// Setup routes
 	e.GET("/", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{
@@ -134,33 +246,136 @@ func main() {
 		},
 	)
 
-	// mongo db repo
-	dbMongo := databaseConfig.GetNoSQLDatabaseConnection()
+	// cache of the logged out access tokens and failed logins, and of the
+	// permissions of roles. In memory they are kept apart, only the roles are
+	// dropped past CACHE_MEMORY_SIZE, logged out tokens and failed logins stay
+	// until they expire so a flood of entries can't evict them.
+	var redisClient *redis.Client
+	var cacheRepo cache.Repository
+	var securityCacheRepo userSvc.SecurityCacheRepository
+	if config.RedisHost != "" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     config.RedisHost + ":" + config.RedisPort,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		})
+		securityCacheRepo = rediscache.New(redisClient)
+		cacheRepo = securityCacheRepo
+	} else {
+		securityCacheRepo = ttlcache.New()
+		cacheRepo, err = cache.NewMemoryARCCacheRepository(config.CacheMemorySize)
+		if err != nil {
+			log.Fatalf("Failed to init cache: %v", err)
+		}
+	}
 
 	// user
-	// userMongoRepo := userRepo.NewMongoRepository(dbMongo)
-	userRepo := userRepo.NewGormRepository(db)
+	userRepo, userTransactor, err := backends.User()
+	if err != nil {
+		log.Fatalf("Failed to init user repository: %v", err)
+	}
+	refreshTokenRepo, err := backends.RefreshToken()
+	if err != nil {
+		log.Fatalf("Failed to init refresh token repository: %v", err)
+	}
+	mfaRepo, err := backends.MFA()
+	if err != nil {
+		log.Fatalf("Failed to init mfa repository: %v", err)
+	}
 	userSvc := userSvc.NewService(
 		logger,
 		userRepo,
+		refreshTokenRepo,
+		mfaRepo,
+		securityCacheRepo,
+		userTransactor,
 		config.AppDeploymentUrl,
 		config.AppJWTSecret,
 		config.AppEmailVerificationKey,
 		mailjetEmail,
+		userSvc.Config{
+			AccessTokenTTL:  config.AppAccessTokenTTL,
+			RefreshTokenTTL: config.AppRefreshTokenTTL,
+
+			VerificationCodeTTL:        config.AppEmailVerificationTTL,
+			VerificationResendInterval: config.AppEmailVerificationResendInterval,
+
+			MaxLoginFailures:     config.AppMaxLoginFailures,
+			MaxIPLoginFailures:   config.AppMaxIPLoginFailures,
+			LoginLockDuration:    config.AppLoginLockDuration,
+			MaxLoginLockDuration: config.AppMaxLoginLockDuration,
+
+			MFAIssuer:        config.AppMFAIssuer,
+			MFARequiredRoles: config.AppMFARequiredRoles,
+
+			PasswordResetURL: config.AppPasswordResetUrl,
+		},
 	)
 	userCtrl := user.NewController(logger, userSvc)
 
+	// permissions of the roles
+	roleRepo, err := backends.Role()
+	if err != nil {
+		log.Fatalf("Failed to init role repository: %v", err)
+	}
+	rbacSvc := rbacSvc.NewService(logger, roleRepo, cacheRepo, rbacSvc.Config{CacheTTL: config.AppRBACCacheTTL})
+	rbacCtrl := rbacCtrl.NewController(logger, rbacSvc)
+
 	// inventory
-	// inventoryRepo := invRepo.NewGormRepository(db)
-	inventoryMongoRepo := invRepo.NewMongoRepository(dbMongo)
-	inventorySvc := invSvc.NewService(inventoryMongoRepo)
-	inventoryCtrl := invCtrl.NewController(logger, inventorySvc)
+	inventoryRepo, inventoryTransactor, err := backends.Inventory()
+	if err != nil {
+		log.Fatalf("Failed to init inventory repository: %v", err)
+	}
+	inventorySvc := invSvc.NewService(inventoryRepo, inventoryTransactor)
+	inventoryCtrl := invCtrl.NewController(logger, inventorySvc, rbacSvc)
+
+	// loan
+	loanRepo, loanTransactor, err := backends.Loan()
+	if err != nil {
+		log.Fatalf("Failed to init loan repository: %v", err)
+	}
+	loanSvc := loanSvc.NewService(
+		logger,
+		loanRepo,
+		inventoryRepo,
+		userRepo,
+		loanTransactor,
+		mailjetEmail,
+	)
+	loanCtrl := loanCtrl.NewController(logger, loanSvc, rbacSvc)
+
+	// work order
+	workOrderRepo, workOrderTransactor, err := backends.WorkOrder()
+	if err != nil {
+		log.Fatalf("Failed to init work order repository: %v", err)
+	}
+	workOrderSvc := woSvc.NewService(logger, workOrderRepo, inventoryRepo, workOrderTransactor)
+	workOrderCtrl := woCtrl.NewController(logger, workOrderSvc)
+
+	// health
+	healthChecks := health.New(config.HealthCheckTimeout)
+	backends.RegisterHealthChecks(healthChecks)
+	if config.MailjetBaseUrl != "" {
+		healthChecks.RegisterOptional("mailjet", health.HTTP(config.MailjetBaseUrl))
+	}
+	if redisClient != nil {
+		healthChecks.RegisterOptional("redis", health.Redis(redisClient))
+	}
+	if config.RabbitMQURL != "" {
+		healthChecks.RegisterOptional("rabbitmq", health.RabbitMQ(config.RabbitMQURL))
+	}
 
 	router.RegisterPath(
 		e,
 		config.AppJWTSecret,
+		userSvc,
+		rbacSvc,
+		healthChecks,
 		inventoryCtrl,
 		userCtrl,
+		loanCtrl,
+		workOrderCtrl,
+		rbacCtrl,
 	)
 
 	// Start server
@@ -186,4 +401,8 @@ func main() {
 	} else {
 		logger.Info("Successfully shutting down echo server")
 	}
+
+	if err := backends.Close(); err != nil {
+		logger.Error("Failed to close repository backends", slog.Any("error", err))
+	}
 }
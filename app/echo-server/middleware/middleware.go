@@ -1,21 +1,37 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"golang.org/x/time/rate"
 )
 
 func forbiddenResponse(c echo.Context) error {
 	return c.JSON(http.StatusForbidden, map[string]interface{}{"message": http.StatusText(http.StatusForbidden)})
 }
 
-func JWTMiddleware(jwtSign string) echo.MiddlewareFunc {
+// Authenticator verifies an access token and returns what it claims.
+type Authenticator interface {
+	Authenticate(ctx context.Context, signedToken string) (token user.AccessToken, err error)
+}
+
+// Authorizer tells whether a role has every one of permissions, it returns a
+// Forbidden error when it doesn't.
+type Authorizer interface {
+	Authorize(ctx context.Context, role string, permissions ...string) (err error)
+}
+
+func JWTMiddleware(authenticator Authenticator) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 
@@ -31,56 +47,50 @@ func JWTMiddleware(jwtSign string) echo.MiddlewareFunc {
 				return forbiddenResponse(c)
 			}
 
-			claim := jwt.MapClaims{}
-			token, err := jwt.ParseWithClaims(signature[1], claim, func(token *jwt.Token) (interface{}, error) {
-				_, ok := token.Method.(*jwt.SigningMethodHMAC)
-				if !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-
-				return []byte(jwtSign), nil
-			})
-			if err != nil {
-				return forbiddenResponse(c)
-			}
-
-			method, ok := token.Method.(*jwt.SigningMethodHMAC)
-			if !ok || method != jwt.SigningMethodHS256 {
+			accessToken, err := authenticator.Authenticate(c.Request().Context(), signature[1])
+			if errors.Is(err, user.ErrInvalidAccessToken) {
 				return forbiddenResponse(c)
 			}
-
-			expAt, err := claim.GetExpirationTime()
 			if err != nil {
-				return forbiddenResponse(c)
+				slog.Error("JWTMiddleware denylist error", slog.Any("error", err))
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": http.StatusText(http.StatusInternalServerError)})
 			}
 
-			if time.Now().After(expAt.Time) {
-				return forbiddenResponse(c)
+			// The role requires two-factor authentication, until it is
+			// enabled the token is only good for that
+			if accessToken.MFAPending && !mfaPendingAllowed(c.Request().URL.Path) {
+				return c.JSON(http.StatusForbidden, map[string]interface{}{"message": "two-factor authentication has to be enabled first"})
 			}
 
-			userID, _ := claim["id"].(string)
-			role, _ := claim["role"].(string)
-			c.Set("id", userID)
-			c.Set("role", role)
+			c.Set("id", accessToken.UserID)
+			c.Set("role", accessToken.Role)
+			c.Set("token", accessToken)
 
 			return next(c)
 		}
 	}
 }
 
-func ACLMiddleware(rolesMap map[string]bool) echo.MiddlewareFunc {
+func mfaPendingAllowed(path string) bool {
+	return strings.HasPrefix(path, "/users/mfa/") || path == "/users/logout" || path == "/users/logout-all"
+}
+
+// PermissionMiddleware lets through the users whose role has every one of
+// permissions, it goes after JWTMiddleware.
+func PermissionMiddleware(authorizer Authorizer, permissions ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			role, _ := c.Get("role").(string)
-			if role == "superadmin" {
-				return next(c)
+			err := authorizer.Authorize(c.Request().Context(), role, permissions...)
+			if errors.Is(err, errs.Forbidden) {
+				return forbiddenResponse(c)
 			}
-
-			if rolesMap[role] {
-				return next(c)
+			if err != nil {
+				slog.Error("PermissionMiddleware authorize error", slog.Any("error", err))
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": http.StatusText(http.StatusInternalServerError)})
 			}
 
-			return forbiddenResponse(c)
+			return next(c)
 		}
 	}
 }
@@ -90,3 +100,18 @@ func JwtEchoMiddleware(jwtSign string) echo.MiddlewareFunc {
 		SigningKey: []byte(jwtSign),
 	})
 }
+
+// RateLimitMiddleware lets a client IP through once every interval, with
+// bursts of up to burst requests.
+func RateLimitMiddleware(every time.Duration, burst int) echo.MiddlewareFunc {
+	return echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
+		Store: echoMiddleware.NewRateLimiterMemoryStoreWithConfig(echoMiddleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Every(every),
+			Burst:     burst,
+			ExpiresIn: every * time.Duration(burst),
+		}),
+		DenyHandler: func(c echo.Context, identifier string, err error) error {
+			return c.JSON(http.StatusTooManyRequests, map[string]interface{}{"message": http.StatusText(http.StatusTooManyRequests)})
+		},
+	})
+}
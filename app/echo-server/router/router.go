@@ -2,18 +2,30 @@ package router
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pobyzaarif/belajarGo2/app/echo-server/controller/inventory"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/controller/loan"
+	rbacCtrl "github.com/pobyzaarif/belajarGo2/app/echo-server/controller/rbac"
 	"github.com/pobyzaarif/belajarGo2/app/echo-server/controller/user"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/controller/workorder"
 	"github.com/pobyzaarif/belajarGo2/app/echo-server/middleware"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	"github.com/pobyzaarif/belajarGo2/util/health"
 )
 
 func RegisterPath(
 	e *echo.Echo,
 	jwtSecret string,
+	authenticator middleware.Authenticator,
+	authorizer middleware.Authorizer,
+	healthChecks *health.Health,
 	ctrlInv *inventory.Controller,
 	ctrlUser *user.Controller,
+	ctrlLoan *loan.Controller,
+	ctrlWorkOrder *workorder.Controller,
+	ctrlRBAC *rbacCtrl.Controller,
 ) {
 	// Setup routes
 	e.GET("/ping", func(c echo.Context) error {
@@ -21,35 +33,90 @@ func RegisterPath(
 			"message": "pong",
 		})
 	})
+	e.GET("/healthz", echo.WrapHandler(healthChecks.LivenessHandler()))
+	e.GET("/readyz", echo.WrapHandler(healthChecks.ReadinessHandler()))
 
 	// Init JWT
-	jwtMiddleware := middleware.JWTMiddleware(jwtSecret)
+	jwtMiddleware := middleware.JWTMiddleware(authenticator)
 
-	// Init ACL
-	userNAdminAccess := middleware.ACLMiddleware(map[string]bool{
-		"admin": true,
-		"user":  true,
-	})
-	adminAccess := middleware.ACLMiddleware(map[string]bool{
-		"admin": true,
-	})
-	superadminAccess := middleware.ACLMiddleware(map[string]bool{
-		"superadmin": true,
-	})
+	// Init permission guards, the permissions of each role are edited under
+	// /admin/roles
+	can := func(permissions ...string) echo.MiddlewareFunc {
+		return middleware.PermissionMiddleware(authorizer, permissions...)
+	}
 
 	// User endpoint
 	userEndpoint := e.Group("/users")
 	userEndpoint.POST("/register", ctrlUser.Register)
 	userEndpoint.POST("/login", ctrlUser.Login)
+	userEndpoint.POST("/login/mfa", ctrlUser.LoginMFA)
+	userEndpoint.POST("/token/refresh", ctrlUser.Refresh)
+	userEndpoint.POST("/logout", ctrlUser.Logout, jwtMiddleware)
+	userEndpoint.POST("/logout-all", ctrlUser.LogoutAll, jwtMiddleware)
 	userEndpoint.GET("/email-verification/:code", ctrlUser.VerifyEmail)
+	userEndpoint.POST("/email-verification/resend", ctrlUser.ResendVerification, middleware.RateLimitMiddleware(12*time.Second, 5))
+	userEndpoint.POST("/password/forgot", ctrlUser.ForgotPassword)
+	userEndpoint.GET("/password/reset", ctrlUser.PasswordResetForm)
+	userEndpoint.POST("/password/reset", ctrlUser.ResetPassword)
+	userEndpoint.GET("/unlock", ctrlUser.UnlockAccount)
+	userEndpoint.POST("/:id/unlock", ctrlUser.UnlockUser, jwtMiddleware, can(rbac.UsersUnlock))
+	userEndpoint.GET("/me", ctrlUser.Me, jwtMiddleware)
+	userEndpoint.PATCH("/me", ctrlUser.UpdateMe, jwtMiddleware)
+	userEndpoint.POST("/me/password", ctrlUser.ChangePassword, jwtMiddleware)
+	userEndpoint.POST("/me/email", ctrlUser.RequestEmailChange, jwtMiddleware)
+	userEndpoint.GET("/email-change", ctrlUser.ConfirmEmailChange)
+	userEndpoint.POST("/mfa/enroll", ctrlUser.EnrollMFA, jwtMiddleware)
+	userEndpoint.POST("/mfa/verify", ctrlUser.ConfirmMFA, jwtMiddleware)
+	userEndpoint.POST("/mfa/disable", ctrlUser.DisableMFA, jwtMiddleware)
+
+	// Admin endpoint
+	adminUserEndpoint := e.Group("/admin/users", jwtMiddleware, can(rbac.UsersManage))
+	adminUserEndpoint.GET("", ctrlUser.GetAll)
+	adminUserEndpoint.GET("/:id", ctrlUser.GetByID)
+	adminUserEndpoint.PUT("/:id/role", ctrlUser.ChangeRole)
+	adminUserEndpoint.POST("/:id/activate", ctrlUser.Activate)
+	adminUserEndpoint.POST("/:id/deactivate", ctrlUser.Deactivate)
+	adminUserEndpoint.POST("/:id/verify-email", ctrlUser.ForceVerifyEmail)
+	adminUserEndpoint.POST("/:id/reset-password", ctrlUser.AdminResetPassword)
+
+	adminRoleEndpoint := e.Group("/admin/roles", jwtMiddleware, can(rbac.RolesManage))
+	adminRoleEndpoint.GET("", ctrlRBAC.GetAll)
+	adminRoleEndpoint.PUT("/:role/permissions", ctrlRBAC.SetPermissions)
 
 	// Inventory endpoint
 	inventoryEndpoint := e.Group("/inventories", jwtMiddleware)
-	inventoryEndpoint.GET("", ctrlInv.GetAll, userNAdminAccess)
-	inventoryEndpoint.GET("/:code", ctrlInv.GetByCode, userNAdminAccess)
-	inventoryEndpoint.POST("", ctrlInv.Create, adminAccess)
-	inventoryEndpoint.PUT("/:code", ctrlInv.Update, adminAccess)
-	inventoryEndpoint.DELETE("/:code", ctrlInv.Delete, superadminAccess)
+	inventoryEndpoint.GET("", ctrlInv.GetAll, can(rbac.InventoryRead))
+	inventoryEndpoint.GET("/:code", ctrlInv.GetByCode, can(rbac.InventoryRead))
+	inventoryEndpoint.POST("", ctrlInv.Create, can(rbac.InventoryWrite))
+	inventoryEndpoint.POST("/bulk", ctrlInv.Bulk, can(rbac.InventoryWrite))
+	inventoryEndpoint.PUT("/:code", ctrlInv.Update, can(rbac.InventoryWrite))
+	inventoryEndpoint.DELETE("/:code", ctrlInv.Delete, can(rbac.InventoryDelete))
+	inventoryEndpoint.POST("/:code/tags", ctrlInv.AddTags, can(rbac.InventoryWrite))
+	inventoryEndpoint.DELETE("/:code/tags/:tag", ctrlInv.RemoveTag, can(rbac.InventoryWrite))
+
+	// Saved search endpoint
+	savedSearchEndpoint := e.Group("/saved-searches", jwtMiddleware, can(rbac.InventoryRead))
+	savedSearchEndpoint.GET("", ctrlInv.GetSavedSearches)
+	savedSearchEndpoint.POST("", ctrlInv.CreateSavedSearch)
+	savedSearchEndpoint.GET("/:id/inventories", ctrlInv.RunSavedSearch)
+	savedSearchEndpoint.DELETE("/:id", ctrlInv.DeleteSavedSearch)
+
+	// Loan endpoint
+	loanEndpoint := e.Group("/loans", jwtMiddleware)
+	loanEndpoint.GET("", ctrlLoan.GetAll, can(rbac.LoansRead))
+	loanEndpoint.GET("/overdue", ctrlLoan.GetOverdue, can(rbac.LoansRead))
+	loanEndpoint.GET("/:id", ctrlLoan.GetByID, can(rbac.LoansRead))
+	loanEndpoint.POST("", ctrlLoan.Checkout, can(rbac.LoansWrite))
+	loanEndpoint.POST("/:id/checkin", ctrlLoan.Checkin, can(rbac.LoansWrite))
+
+	// Work order endpoint
+	workOrderEndpoint := e.Group("/work-orders", jwtMiddleware, can(rbac.WorkOrdersManage))
+	workOrderEndpoint.GET("", ctrlWorkOrder.GetAll)
+	workOrderEndpoint.GET("/report/repair-cost", ctrlWorkOrder.GetRepairCost)
+	workOrderEndpoint.GET("/:id", ctrlWorkOrder.GetByID)
+	workOrderEndpoint.POST("", ctrlWorkOrder.Open)
+	workOrderEndpoint.PUT("/:id", ctrlWorkOrder.Update)
+	workOrderEndpoint.POST("/:id/close", ctrlWorkOrder.Close)
 
 	// Explore endpoint
 	echoJWT := middleware.JwtEchoMiddleware(jwtSecret) // poc: rafly
@@ -0,0 +1,86 @@
+package router_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/pobyzaarif/belajarGo2/app/echo-server/router"
+	mock_notification "github.com/pobyzaarif/belajarGo2/service/notification/mock"
+	mock_transaction "github.com/pobyzaarif/belajarGo2/service/transaction/mock"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	mock_user "github.com/pobyzaarif/belajarGo2/service/user/mock"
+	"github.com/pobyzaarif/belajarGo2/util/health"
+	"github.com/pobyzaarif/belajarGo2/util/ttlcache"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// resetLink returns the link ForgotPassword emails with config.
+func resetLink(t *testing.T, config user.Config) *url.URL {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_notif := mock_notification.NewMockRepository(ctrl)
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_user.NewMockRefreshTokenRepository(ctrl),
+		mock_user.NewMockMFARepository(ctrl),
+		ttlcache.New(),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://localhost:8000",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notif,
+		config,
+	)
+
+	var link string
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(user.User{ID: "1", Email: "user@example.com", Password: "hash"}, nil)
+	mock_notif.EXPECT().SendEmail(gomock.Any(), gomock.Any(), "user@example.com", user.SubjectResetPassword, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, name, email, subject, body string) error {
+			_, link, _ = strings.Cut(body, "<br/><br/>")
+			link, _, _ = strings.Cut(link, "<br/>")
+			return nil
+		},
+	)
+	assert.Nil(t, userService.ForgotPassword(context.Background(), "user@example.com"))
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestPasswordResetLinkIsRouted(t *testing.T) {
+	e := echo.New()
+	router.RegisterPath(e, "secret", nil, nil, health.New(time.Second), nil, nil, nil, nil, nil)
+
+	// Without a frontend page the link opens the form of the API
+	link := resetLink(t, user.Config{})
+	assert.Equal(t, "localhost:8000", link.Host)
+	assert.NotEmpty(t, link.Query().Get("code"))
+
+	var methods []string
+	for _, route := range e.Routes() {
+		if route.Path == link.Path {
+			methods = append(methods, route.Method)
+		}
+	}
+	assert.ElementsMatch(t, []string{http.MethodGet, http.MethodPost}, methods)
+
+	link = resetLink(t, user.Config{PasswordResetURL: "https://app.example.com/reset-password"})
+	assert.Equal(t, "app.example.com", link.Host)
+	assert.Equal(t, "/reset-password", link.Path)
+	assert.NotEmpty(t, link.Query().Get("code"))
+}
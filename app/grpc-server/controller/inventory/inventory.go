@@ -3,45 +3,131 @@ package inventory
 import (
 	"context"
 
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type inventoryServiceServer struct {
 	UnimplementedInventoryServiceServer
+	inventorySvc inventory.Service
 }
 
-func NewInventoryService() InventoryServiceServer {
-	return &inventoryServiceServer{}
+func NewInventoryService(inventorySvc inventory.Service) InventoryServiceServer {
+	return &inventoryServiceServer{
+		inventorySvc: inventorySvc,
+	}
 }
 
-func (s *inventoryServiceServer) Create(ctx context.Context, req *InventoryRequest) (*InventoryResponse, error) {
+var statusToDomain = map[InventoryStatus]string{
+	InventoryStatus_ACTIVE: "active",
+	InventoryStatus_BROKEN: "broken",
+}
+
+func toDomain(req *InventoryRequest) inventory.Inventory {
+	return inventory.Inventory{
+		Code:        req.GetCode(),
+		Name:        req.GetName(),
+		Stock:       int(req.GetStock()),
+		Description: req.GetDescription(),
+		Status:      statusToDomain[req.GetStatus()],
+	}
+}
+
+func fromDomain(inv inventory.Inventory) *InventoryRequest {
+	resp := &InventoryRequest{
+		Code:        inv.Code,
+		Name:        inv.Name,
+		Stock:       int32(inv.Stock),
+		Description: inv.Description,
+	}
+	for pbStatus, domainStatus := range statusToDomain {
+		if domainStatus == inv.Status {
+			resp.Status = pbStatus
+		}
+	}
+	return resp
+}
+
+func validate(req *InventoryRequest) error {
 	if req == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "request is nil")
+		return status.Errorf(codes.InvalidArgument, "request is nil")
 	}
 	if req.GetCode() == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "code is required")
+		return status.Errorf(codes.InvalidArgument, "code is required")
 	}
 	if req.GetName() == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "name is required")
+		return status.Errorf(codes.InvalidArgument, "name is required")
 	}
 	if req.GetStatus() == InventoryStatus_INVENTORY_STATUS_UNSPECIFIED {
-		return nil, status.Errorf(codes.InvalidArgument, "status is required")
+		return status.Errorf(codes.InvalidArgument, "status is required")
+	}
+	return nil
+}
+
+func (s *inventoryServiceServer) Create(ctx context.Context, req *InventoryRequest) (*InventoryResponse, error) {
+	if err := validate(req); err != nil {
+		return nil, err
 	}
 
-	resp := &InventoryResponse{Inventory: req}
+	if err := s.inventorySvc.Create(ctx, toDomain(req)); err != nil {
+		return nil, err
+	}
+	return &InventoryResponse{Inventory: req}, nil
+}
+
+func (s *inventoryServiceServer) Get(ctx context.Context, req *InventoryRequest) (*InventoryResponse, error) {
+	if req.GetCode() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "code is required")
+	}
+
+	inv, err := s.inventorySvc.GetByCode(ctx, req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	return &InventoryResponse{Inventory: fromDomain(inv)}, nil
+}
+
+func (s *inventoryServiceServer) List(ctx context.Context, req *InventoryListRequest) (*InventoryListResponse, error) {
+	page := int(req.GetPage())
+	limit := int(req.GetLimit())
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	invs, err := s.inventorySvc.GetAll(ctx, inventory.Filter{}, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &InventoryListResponse{Inventories: make([]*InventoryRequest, 0, len(invs))}
+	for _, inv := range invs {
+		resp.Inventories = append(resp.Inventories, fromDomain(inv))
+	}
 	return resp, nil
 }
 
-// func (s *inventoryServiceServer) Get(ctx context.Context, req *InventoryRequest) (*InventoryResponse, error) {
-// 	return nil, nil
-// }
-// func (s *inventoryServiceServer) List(ctx context.Context, req *InventoryListRequest) (*InventoryListResponse, error) {
-// 	return nil, nil
-// }
-// func (s *inventoryServiceServer) Update(ctx context.Context, req *InventoryRequest) (*InventoryResponse, error) {
-// 	return nil, nil
-// }
-// func (s *inventoryServiceServer) Delete(ctx context.Context, req *InventoryRequest) (*Empty, error) {
-// 	return nil, nil
-// }
+func (s *inventoryServiceServer) Update(ctx context.Context, req *InventoryRequest) (*InventoryResponse, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.inventorySvc.Update(ctx, toDomain(req)); err != nil {
+		return nil, err
+	}
+	return &InventoryResponse{Inventory: req}, nil
+}
+
+func (s *inventoryServiceServer) Delete(ctx context.Context, req *InventoryRequest) (*Empty, error) {
+	if req.GetCode() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "code is required")
+	}
+
+	if err := s.inventorySvc.Delete(ctx, req.GetCode()); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
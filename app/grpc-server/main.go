@@ -1,17 +1,30 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
 	"net"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	pb "github.com/pobyzaarif/belajarGo2/app/grpc-server/controller/inventory"
 	"github.com/pobyzaarif/belajarGo2/app/grpc-server/middleware"
+	"github.com/pobyzaarif/belajarGo2/repository/backend"
+	invSvc "github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	userSvc "github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/pobyzaarif/belajarGo2/util/health"
+	"github.com/pobyzaarif/belajarGo2/util/ttlcache"
+	cache "github.com/pobyzaarif/go-cache"
 	cfg "github.com/pobyzaarif/go-config"
+	redis "github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var loggerOption = slog.HandlerOptions{AddSource: true}
@@ -20,6 +33,68 @@ var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
 type Config struct {
 	AppPort      string `env:"APP_PORT_GRPC_SERVER"`
 	AppBasicAuth string `env:"APP_BASIC_AUTH"`
+
+	// Besides basic auth, access tokens of the echo server are accepted, the
+	// secret has to be the same. The permissions of their role live on the
+	// user backend
+	AppJWTSecret    string        `env:"APP_JWT_SECRET"`
+	AppRBACCacheTTL time.Duration `env:"APP_RBAC_CACHE_TTL" envDefault:"1m"`
+
+	DBDriver string `env:"DB_DRIVER"`
+
+	DBMySQLHost     string `env:"DB_MYSQL_HOST"`
+	DBMySQLPort     string `env:"DB_MYSQL_PORT"`
+	DBMySQLUser     string `env:"DB_MYSQL_USER"`
+	DBMySQLPassword string `env:"DB_MYSQL_PASSWORD"`
+	DBMySQLName     string `env:"DB_MYSQL_NAME"`
+
+	DBSQLiteName string `env:"DB_SQLITE_NAME"`
+
+	DBPostgreSQLHost     string `env:"DB_POSTGRESQL_HOST"`
+	DBPostgreSQLPort     string `env:"DB_POSTGRESQL_PORT"`
+	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
+	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
+	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
+
+	DBMongoURI  string `env:"DB_MONGO_URI"`
+	DBMongoName string `env:"DB_MONGO_NAME"`
+
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME"`
+	DBStatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES"`
+	DBConnectRetryDelay time.Duration `env:"DB_CONNECT_RETRY_DELAY" envDefault:"2s"`
+
+	DBMySQLReplicaHosts          []string      `env:"DB_MYSQL_REPLICA_HOSTS"`
+	DBPostgreSQLReplicaHosts     []string      `env:"DB_POSTGRESQL_REPLICA_HOSTS"`
+	DBSQLiteReplicaNames         []string      `env:"DB_SQLITE_REPLICA_NAMES"`
+	DBReplicaReadAfterWrite      time.Duration `env:"DB_REPLICA_READ_AFTER_WRITE" envDefault:"2s"`
+	DBReplicaHealthCheckInterval time.Duration `env:"DB_REPLICA_HEALTH_CHECK_INTERVAL" envDefault:"10s"`
+
+	DBMongoMaxPoolSize            uint64        `env:"DB_MONGO_MAX_POOL_SIZE"`
+	DBMongoMinPoolSize            uint64        `env:"DB_MONGO_MIN_POOL_SIZE"`
+	DBMongoMaxConnIdleTime        time.Duration `env:"DB_MONGO_MAX_CONN_IDLE_TIME"`
+	DBMongoConnectTimeout         time.Duration `env:"DB_MONGO_CONNECT_TIMEOUT"`
+	DBMongoServerSelectionTimeout time.Duration `env:"DB_MONGO_SERVER_SELECTION_TIMEOUT"`
+
+	DBRequestTimeout time.Duration `env:"DB_REQUEST_TIMEOUT" envDefault:"10s"`
+
+	InventoryRepoBackend string `env:"INVENTORY_REPO_BACKEND" envDefault:"gorm"`
+	UserRepoBackend      string `env:"USER_REPO_BACKEND" envDefault:"gorm"`
+
+	HealthCheckTimeout  time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" envDefault:"10s"`
+
+	// Logged out tokens are read from Redis when REDIS_HOST is set, it has
+	// to be the one of the echo server, the memory cache doesn't see them
+	RedisHost       string `env:"REDIS_HOST"`
+	RedisPort       string `env:"REDIS_PORT"`
+	RedisPassword   string `env:"REDIS_PASSWORD"`
+	RedisDB         int    `env:"REDIS_DB"`
+	CacheMemorySize int    `env:"CACHE_MEMORY_SIZE" envDefault:"100000"`
 }
 
 func main() {
@@ -33,12 +108,105 @@ func main() {
 	}
 	logger.Info("Config loaded")
 
-	// Extract basic auth credentials from config
+	// Extract basic auth credentials from config, there may be none now
+	// that access tokens are accepted
 	basicAuthMap := make(map[string]string)
 	basicAuthConfig := strings.Split(config.AppBasicAuth, ",")
 	for _, v := range basicAuthConfig {
-		basicAuthPair := strings.Split(v, ":")
-		basicAuthMap[basicAuthPair[0]] = basicAuthPair[1]
+		username, password, ok := strings.Cut(v, ":")
+		if !ok {
+			continue
+		}
+		basicAuthMap[username] = password
+	}
+
+	// Init repository backends, databases are connected to on first use
+	databaseConfig := database.Config{
+		DBDriver:             config.DBDriver,
+		DBMySQLHost:          config.DBMySQLHost,
+		DBMySQLPort:          config.DBMySQLPort,
+		DBMySQLUser:          config.DBMySQLUser,
+		DBMySQLPassword:      config.DBMySQLPassword,
+		DBMySQLName:          config.DBMySQLName,
+		DBSQLiteName:         config.DBSQLiteName,
+		DBPostgreSQLHost:     config.DBPostgreSQLHost,
+		DBPostgreSQLPort:     config.DBPostgreSQLPort,
+		DBPostgreSQLUser:     config.DBPostgreSQLUser,
+		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
+		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
+		DBMongoURI:           config.DBMongoURI,
+		DBMongoName:          config.DBMongoName,
+
+		DBMaxOpenConns:      config.DBMaxOpenConns,
+		DBMaxIdleConns:      config.DBMaxIdleConns,
+		DBConnMaxLifetime:   config.DBConnMaxLifetime,
+		DBConnMaxIdleTime:   config.DBConnMaxIdleTime,
+		DBStatementTimeout:  config.DBStatementTimeout,
+		DBConnectRetries:    config.DBConnectRetries,
+		DBConnectRetryDelay: config.DBConnectRetryDelay,
+
+		DBMySQLReplicaHosts:          config.DBMySQLReplicaHosts,
+		DBPostgreSQLReplicaHosts:     config.DBPostgreSQLReplicaHosts,
+		DBSQLiteReplicaNames:         config.DBSQLiteReplicaNames,
+		DBReplicaReadAfterWrite:      config.DBReplicaReadAfterWrite,
+		DBReplicaHealthCheckInterval: config.DBReplicaHealthCheckInterval,
+
+		DBMongoMaxPoolSize:            config.DBMongoMaxPoolSize,
+		DBMongoMinPoolSize:            config.DBMongoMinPoolSize,
+		DBMongoMaxConnIdleTime:        config.DBMongoMaxConnIdleTime,
+		DBMongoConnectTimeout:         config.DBMongoConnectTimeout,
+		DBMongoServerSelectionTimeout: config.DBMongoServerSelectionTimeout,
+	}
+	backends := backend.New(backend.Config{
+		Inventory: config.InventoryRepoBackend,
+		User:      config.UserRepoBackend,
+	}, databaseConfig)
+
+	inventoryRepo, inventoryTransactor, err := backends.Inventory()
+	if err != nil {
+		log.Fatalf("Failed to init inventory repository: %v", err)
+	}
+	inventorySvc := invSvc.NewService(inventoryRepo, inventoryTransactor)
+
+	// cache of the logged out access tokens and failed logins, and of the
+	// permissions of roles. In memory they are kept apart, only the roles are
+	// dropped past CACHE_MEMORY_SIZE, logged out tokens and failed logins stay
+	// until they expire so a flood of entries can't evict them.
+	var redisClient *redis.Client
+	var cacheRepo, securityCacheRepo cache.Repository
+	if config.RedisHost != "" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     config.RedisHost + ":" + config.RedisPort,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		})
+		cacheRepo = cache.NewRedisCacheRepository(redisClient)
+		securityCacheRepo = cacheRepo
+	} else {
+		securityCacheRepo = ttlcache.New()
+		cacheRepo, err = cache.NewMemoryARCCacheRepository(config.CacheMemorySize)
+		if err != nil {
+			log.Fatalf("Failed to init cache: %v", err)
+		}
+	}
+
+	roleRepo, err := backends.Role()
+	if err != nil {
+		log.Fatalf("Failed to init role repository: %v", err)
+	}
+	auth := middleware.Auth{
+		BasicAuth:     basicAuthMap,
+		Authenticator: userSvc.NewAuthenticator(config.AppJWTSecret, securityCacheRepo),
+		Authorizer:    rbac.NewService(logger, roleRepo, cacheRepo, rbac.Config{CacheTTL: config.AppRBACCacheTTL}),
+		// What the role of an access token needs for each method
+		MethodPermissions: map[string][]string{
+			pb.InventoryService_Create_FullMethodName: {rbac.InventoryWrite},
+			pb.InventoryService_Get_FullMethodName:    {rbac.InventoryRead},
+			pb.InventoryService_List_FullMethodName:   {rbac.InventoryRead},
+			pb.InventoryService_Update_FullMethodName: {rbac.InventoryWrite},
+			pb.InventoryService_Delete_FullMethodName: {rbac.InventoryDelete},
+		},
 	}
 
 	// Listen grpc with port from config
@@ -47,14 +215,31 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	// Create a new gRPC server with Basic Auth interceptors.
+	// Create a new gRPC server with auth, timeout and domain error interceptors.
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(middleware.BasicAuthUnaryInterceptor(basicAuthMap)),
-		grpc.StreamInterceptor(middleware.BasicAuthStreamInterceptor(basicAuthMap)),
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryInterceptor(),
+			middleware.TimeoutUnaryInterceptor(config.DBRequestTimeout),
+			middleware.DomainErrorUnaryInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			auth.StreamInterceptor(),
+			middleware.DomainErrorStreamInterceptor(),
+		),
 	)
 
 	// Register the service implementation
-	pb.RegisterInventoryServiceServer(grpcServer, pb.NewInventoryService())
+	pb.RegisterInventoryServiceServer(grpcServer, pb.NewInventoryService(inventorySvc))
+
+	// Standard health service, kept up to date with the database checks
+	healthChecks := health.New(config.HealthCheckTimeout)
+	backends.RegisterHealthChecks(healthChecks)
+	if redisClient != nil {
+		healthChecks.RegisterOptional("redis", health.Redis(redisClient))
+	}
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go healthChecks.WatchGRPC(context.Background(), healthServer, config.HealthCheckInterval, "", pb.InventoryService_ServiceDesc.ServiceName)
 
 	logger.Info("gRPC service running on port " + config.AppPort)
 
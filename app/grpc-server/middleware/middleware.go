@@ -3,41 +3,108 @@ package middleware
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"strings"
+	"time"
 
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/user"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
-// BasicAuthUnaryInterceptor returns a UnaryServerInterceptor that validates
-// an incoming Basic Authorization header against a map of allowed credentials.
-func BasicAuthUnaryInterceptor(allowed map[string]string) grpc.UnaryServerInterceptor {
+// Authenticator verifies an access token and returns what it claims.
+type Authenticator interface {
+	Authenticate(ctx context.Context, signedToken string) (token user.AccessToken, err error)
+}
+
+// Authorizer tells whether a role has every one of permissions, it returns a
+// Forbidden error when it doesn't.
+type Authorizer interface {
+	Authorize(ctx context.Context, role string, permissions ...string) (err error)
+}
+
+// Auth checks the authorization metadata of calls. Basic credentials of
+// BasicAuth, service accounts, may call every method. A bearer access token
+// of the echo server may call the methods of MethodPermissions its role has
+// every permission of, the methods missing from it are refused.
+type Auth struct {
+	BasicAuth         map[string]string
+	Authenticator     Authenticator
+	Authorizer        Authorizer
+	MethodPermissions map[string][]string
+}
+
+// UnaryInterceptor returns a UnaryServerInterceptor checking every call but
+// the health checks.
+func (a Auth) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if len(allowed) == 0 {
-			return nil, status.Errorf(codes.Unauthenticated, "unauthenticated")
+		if isHealthCheck(info.FullMethod) {
+			return handler(ctx, req)
 		}
-		if ok := checkBasicAuthAgainstMap(ctx, allowed); !ok {
-			return nil, status.Errorf(codes.Unauthenticated, "unauthenticated")
+		if err := a.check(ctx, info.FullMethod); err != nil {
+			return nil, err
 		}
 		return handler(ctx, req)
 	}
 }
 
-// BasicAuthStreamInterceptor validates Basic Authorization for streaming RPCs using a map.
-func BasicAuthStreamInterceptor(allowed map[string]string) grpc.StreamServerInterceptor {
+// StreamInterceptor is the streaming counterpart of UnaryInterceptor.
+func (a Auth) StreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		if len(allowed) == 0 {
-			return status.Errorf(codes.Unauthenticated, "unauthenticated")
+		if isHealthCheck(info.FullMethod) {
+			return handler(srv, ss)
 		}
-		if ok := checkBasicAuthAgainstMap(ss.Context(), allowed); !ok {
-			return status.Errorf(codes.Unauthenticated, "unauthenticated")
+		if err := a.check(ss.Context(), info.FullMethod); err != nil {
+			return err
 		}
 		return handler(srv, ss)
 	}
 }
 
+// check returns the status error a call of method is refused with, nil when
+// it is allowed.
+func (a Auth) check(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return status.Errorf(codes.Unauthenticated, "unauthenticated")
+	}
+	scheme, credentials, _ := strings.Cut(vals[0], " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if len(a.BasicAuth) == 0 || !checkBasicAuthAgainstMap(ctx, a.BasicAuth) {
+			return status.Errorf(codes.Unauthenticated, "unauthenticated")
+		}
+		return nil
+	case "bearer":
+		token, err := a.Authenticator.Authenticate(ctx, strings.TrimSpace(credentials))
+		if err != nil {
+			return domainErrorToStatus(err)
+		}
+		// Until two-factor authentication is enabled the token is only good
+		// for that, on the echo server
+		if token.MFAPending {
+			return domainErrorToStatus(user.ErrMFARequired)
+		}
+		permissions, ok := a.MethodPermissions[method]
+		if !ok {
+			return status.Errorf(codes.PermissionDenied, "permission denied")
+		}
+		return domainErrorToStatus(a.Authorizer.Authorize(ctx, token.Role, permissions...))
+	}
+	return status.Errorf(codes.Unauthenticated, "unauthenticated")
+}
+
+// isHealthCheck reports whether method belongs to the gRPC health service,
+// probes call it without credentials.
+func isHealthCheck(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
 // checkBasicAuthAgainstMap validates credentials from context against the allowed map.
 func checkBasicAuthAgainstMap(ctx context.Context, allowed map[string]string) bool {
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -74,3 +141,61 @@ func checkBasicAuthAgainstMap(ctx context.Context, allowed map[string]string) bo
 	}
 	return false
 }
+
+// TimeoutUnaryInterceptor bounds every call by timeout, a shorter deadline
+// set by the client still wins.
+func TimeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// DomainErrorUnaryInterceptor converts domain errors returned by handlers into
+// gRPC status errors so every service maps them the same way.
+func DomainErrorUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, domainErrorToStatus(err)
+	}
+}
+
+// DomainErrorStreamInterceptor is the streaming counterpart of DomainErrorUnaryInterceptor.
+func DomainErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return domainErrorToStatus(handler(srv, ss))
+	}
+}
+
+// domainErrorToStatus maps a domain error to its gRPC code, errors that are
+// already a status pass through untouched.
+func domainErrorToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var code codes.Code
+	switch {
+	case errors.Is(err, errs.Validation):
+		code = codes.InvalidArgument
+	case errors.Is(err, errs.Unauthorized):
+		code = codes.Unauthenticated
+	case errors.Is(err, errs.Forbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, errs.NotFound):
+		code = codes.NotFound
+	case errors.Is(err, errs.Conflict):
+		code = codes.AlreadyExists
+	case errors.Is(err, errs.TooManyRequests):
+		code = codes.ResourceExhausted
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+	return status.Error(code, errs.Message(err))
+}
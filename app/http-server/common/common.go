@@ -3,6 +3,9 @@ package common
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/util/httperror"
 )
 
 func ErrorInvalidJSON(w http.ResponseWriter) {
@@ -35,6 +38,19 @@ func ErrorDataNotFound(w http.ResponseWriter) {
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"message": http.StatusText(http.StatusNotFound), "data": map[string]interface{}{}})
 }
 
+// ErrorResponse writes the response for an error returned by a service.
+func ErrorResponse(w http.ResponseWriter, err error) {
+	status := httperror.Status(err)
+	message := errs.Message(err)
+	if status >= http.StatusInternalServerError {
+		message = http.StatusText(status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"message": message, "data": map[string]interface{}{}})
+}
+
 func ValidResponse(w http.ResponseWriter, httpStatus int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus)
@@ -51,7 +51,7 @@ func (c *Controller) Create(w http.ResponseWriter, r *http.Request, _ httprouter
 		return
 	}
 
-	if err := c.inventorySvc.Create(inventory.Inventory{
+	if err := c.inventorySvc.Create(r.Context(), inventory.Inventory{
 		Code:        req.Code,
 		Name:        req.Name,
 		Stock:       req.Stock,
@@ -60,12 +60,7 @@ func (c *Controller) Create(w http.ResponseWriter, r *http.Request, _ httprouter
 	}); err != nil {
 		c.logger.Error("inventory.Create Error", slog.Any("error", err))
 
-		if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
-			common.ErrorDataConflict(w)
-			return
-		}
-
-		common.ErrorInternal(w)
+		common.ErrorResponse(w, err)
 		return
 	}
 
@@ -85,9 +80,16 @@ func (c *Controller) GetAll(w http.ResponseWriter, r *http.Request, _ httprouter
 		limit = 10
 	}
 
-	invs, err := c.inventorySvc.GetAll(page, limit)
+	var tags []string
+	if tReq := r.FormValue("tags"); tReq != "" {
+		tags = strings.Split(tReq, ",")
+	}
+
+	invs, err := c.inventorySvc.GetAll(r.Context(), inventory.Filter{Tags: tags}, page, limit)
 	if err != nil {
-		common.ErrorInternal(w)
+		c.logger.Error("inventory.GetAll Error", slog.Any("error", err))
+
+		common.ErrorResponse(w, err)
 		return
 	}
 
@@ -106,14 +108,11 @@ func (c *Controller) GetByCode(w http.ResponseWriter, r *http.Request, p httprou
 		return
 	}
 
-	inv, err := c.inventorySvc.GetByCode(code)
+	inv, err := c.inventorySvc.GetByCode(r.Context(), code)
 	if err != nil {
-		common.ErrorInternal(w)
-		return
-	}
+		c.logger.Error("inventory.GetByCode Error", slog.Any("error", err))
 
-	if inv.Code == "" {
-		common.ErrorDataNotFound(w)
+		common.ErrorResponse(w, err)
 		return
 	}
 
@@ -135,7 +134,7 @@ func (c *Controller) Update(w http.ResponseWriter, r *http.Request, p httprouter
 		return
 	}
 
-	if err := c.inventorySvc.Update(inventory.Inventory{
+	if err := c.inventorySvc.Update(r.Context(), inventory.Inventory{
 		Code:        req.Code,
 		Name:        req.Name,
 		Stock:       req.Stock,
@@ -144,12 +143,7 @@ func (c *Controller) Update(w http.ResponseWriter, r *http.Request, p httprouter
 	}); err != nil {
 		c.logger.Error("inventory.Update Error", slog.Any("error", err))
 
-		if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
-			common.ErrorDataConflict(w)
-			return
-		}
-
-		common.ErrorInternal(w)
+		common.ErrorResponse(w, err)
 		return
 	}
 
@@ -163,10 +157,10 @@ func (c *Controller) Delete(w http.ResponseWriter, r *http.Request, p httprouter
 		return
 	}
 
-	if err := c.inventorySvc.Delete(code); err != nil {
+	if err := c.inventorySvc.Delete(r.Context(), code); err != nil {
 		c.logger.Error("inventory.Delete Error", slog.Any("error", err))
 
-		common.ErrorInternal(w)
+		common.ErrorResponse(w, err)
 		return
 	}
 
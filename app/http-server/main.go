@@ -6,14 +6,22 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/julienschmidt/httprouter"
 	invCtrl "github.com/pobyzaarif/belajarGo2/app/http-server/controller/inventory"
-	invRepo "github.com/pobyzaarif/belajarGo2/repository/inventory"
+	"github.com/pobyzaarif/belajarGo2/app/http-server/middleware"
+	"github.com/pobyzaarif/belajarGo2/repository/backend"
 	invSvc "github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	userSvc "github.com/pobyzaarif/belajarGo2/service/user"
 	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/pobyzaarif/belajarGo2/util/health"
+	"github.com/pobyzaarif/belajarGo2/util/ttlcache"
+	cache "github.com/pobyzaarif/go-cache"
 	cfg "github.com/pobyzaarif/go-config"
+	redis "github.com/redis/go-redis/v9"
 )
 
 var loggerOption = slog.HandlerOptions{AddSource: true}
@@ -24,6 +32,11 @@ type Config struct {
 	AppHost    string `env:"APP_HOST"`
 	AppPort    string `env:"APP_PORT_HTTP_SERVER"`
 
+	// Access tokens of the echo server are accepted, the secret has to be
+	// the same. The permissions of their role live on the user backend
+	AppJWTSecret    string        `env:"APP_JWT_SECRET"`
+	AppRBACCacheTTL time.Duration `env:"APP_RBAC_CACHE_TTL" envDefault:"1m"`
+
 	DBDriver string `env:"DB_DRIVER"`
 
 	DBMySQLHost     string `env:"DB_MYSQL_HOST"`
@@ -39,6 +52,45 @@ type Config struct {
 	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
 	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
 	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
+
+	DBMongoURI  string `env:"DB_MONGO_URI"`
+	DBMongoName string `env:"DB_MONGO_NAME"`
+
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME"`
+	DBStatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES"`
+	DBConnectRetryDelay time.Duration `env:"DB_CONNECT_RETRY_DELAY" envDefault:"2s"`
+
+	DBMySQLReplicaHosts          []string      `env:"DB_MYSQL_REPLICA_HOSTS"`
+	DBPostgreSQLReplicaHosts     []string      `env:"DB_POSTGRESQL_REPLICA_HOSTS"`
+	DBSQLiteReplicaNames         []string      `env:"DB_SQLITE_REPLICA_NAMES"`
+	DBReplicaReadAfterWrite      time.Duration `env:"DB_REPLICA_READ_AFTER_WRITE" envDefault:"2s"`
+	DBReplicaHealthCheckInterval time.Duration `env:"DB_REPLICA_HEALTH_CHECK_INTERVAL" envDefault:"10s"`
+
+	DBMongoMaxPoolSize            uint64        `env:"DB_MONGO_MAX_POOL_SIZE"`
+	DBMongoMinPoolSize            uint64        `env:"DB_MONGO_MIN_POOL_SIZE"`
+	DBMongoMaxConnIdleTime        time.Duration `env:"DB_MONGO_MAX_CONN_IDLE_TIME"`
+	DBMongoConnectTimeout         time.Duration `env:"DB_MONGO_CONNECT_TIMEOUT"`
+	DBMongoServerSelectionTimeout time.Duration `env:"DB_MONGO_SERVER_SELECTION_TIMEOUT"`
+
+	DBRequestTimeout time.Duration `env:"DB_REQUEST_TIMEOUT" envDefault:"10s"`
+
+	InventoryRepoBackend string `env:"INVENTORY_REPO_BACKEND" envDefault:"gorm"`
+	UserRepoBackend      string `env:"USER_REPO_BACKEND" envDefault:"gorm"`
+
+	HealthCheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+
+	// Logged out tokens are read from Redis when REDIS_HOST is set, it has
+	// to be the one of the echo server, the memory cache doesn't see them
+	RedisHost       string `env:"REDIS_HOST"`
+	RedisPort       string `env:"REDIS_PORT"`
+	RedisPassword   string `env:"REDIS_PASSWORD"`
+	RedisDB         int    `env:"REDIS_DB"`
+	CacheMemorySize int    `env:"CACHE_MEMORY_SIZE" envDefault:"100000"`
 }
 
 func main() {
@@ -52,7 +104,7 @@ func main() {
 	}
 	logger.Info("Config loaded")
 
-	// Init db connection
+	// Init repository backends, databases are connected to on first use
 	databaseConfig := database.Config{
 		DBDriver:             config.DBDriver,
 		DBMySQLHost:          config.DBMySQLHost,
@@ -66,15 +118,81 @@ func main() {
 		DBPostgreSQLUser:     config.DBPostgreSQLUser,
 		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
 		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
+		DBMongoURI:           config.DBMongoURI,
+		DBMongoName:          config.DBMongoName,
+
+		DBMaxOpenConns:      config.DBMaxOpenConns,
+		DBMaxIdleConns:      config.DBMaxIdleConns,
+		DBConnMaxLifetime:   config.DBConnMaxLifetime,
+		DBConnMaxIdleTime:   config.DBConnMaxIdleTime,
+		DBStatementTimeout:  config.DBStatementTimeout,
+		DBConnectRetries:    config.DBConnectRetries,
+		DBConnectRetryDelay: config.DBConnectRetryDelay,
+
+		DBMySQLReplicaHosts:          config.DBMySQLReplicaHosts,
+		DBPostgreSQLReplicaHosts:     config.DBPostgreSQLReplicaHosts,
+		DBSQLiteReplicaNames:         config.DBSQLiteReplicaNames,
+		DBReplicaReadAfterWrite:      config.DBReplicaReadAfterWrite,
+		DBReplicaHealthCheckInterval: config.DBReplicaHealthCheckInterval,
+
+		DBMongoMaxPoolSize:            config.DBMongoMaxPoolSize,
+		DBMongoMinPoolSize:            config.DBMongoMinPoolSize,
+		DBMongoMaxConnIdleTime:        config.DBMongoMaxConnIdleTime,
+		DBMongoConnectTimeout:         config.DBMongoConnectTimeout,
+		DBMongoServerSelectionTimeout: config.DBMongoServerSelectionTimeout,
 	}
-	db := databaseConfig.GetDatabaseConnection()
-	logger.Info("Database client connected!")
+	backends := backend.New(backend.Config{
+		Inventory: config.InventoryRepoBackend,
+		User:      config.UserRepoBackend,
+	}, databaseConfig)
 
 	// Dependency Injection
-	inventoryRepo := invRepo.NewGormRepository(db)
-	inventorySvc := invSvc.NewService(inventoryRepo)
+	inventoryRepo, inventoryTransactor, err := backends.Inventory()
+	if err != nil {
+		log.Fatalf("Failed to init inventory repository: %v", err)
+	}
+	inventorySvc := invSvc.NewService(inventoryRepo, inventoryTransactor)
 	inventoryCtrl := invCtrl.NewController(logger, inventorySvc)
 
+	// cache of the logged out access tokens and failed logins, and of the
+	// permissions of roles. In memory they are kept apart, only the roles are
+	// dropped past CACHE_MEMORY_SIZE, logged out tokens and failed logins stay
+	// until they expire so a flood of entries can't evict them.
+	var redisClient *redis.Client
+	var cacheRepo, securityCacheRepo cache.Repository
+	if config.RedisHost != "" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     config.RedisHost + ":" + config.RedisPort,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		})
+		cacheRepo = cache.NewRedisCacheRepository(redisClient)
+		securityCacheRepo = cacheRepo
+	} else {
+		securityCacheRepo = ttlcache.New()
+		cacheRepo, err = cache.NewMemoryARCCacheRepository(config.CacheMemorySize)
+		if err != nil {
+			log.Fatalf("Failed to init cache: %v", err)
+		}
+	}
+
+	roleRepo, err := backends.Role()
+	if err != nil {
+		log.Fatalf("Failed to init role repository: %v", err)
+	}
+	authenticator := userSvc.NewAuthenticator(config.AppJWTSecret, securityCacheRepo)
+	authorizer := rbac.NewService(logger, roleRepo, cacheRepo, rbac.Config{CacheTTL: config.AppRBACCacheTTL})
+	can := func(next httprouter.Handle, permissions ...string) httprouter.Handle {
+		return middleware.Permission(authenticator, authorizer, next, permissions...)
+	}
+
+	healthChecks := health.New(config.HealthCheckTimeout)
+	backends.RegisterHealthChecks(healthChecks)
+	if redisClient != nil {
+		healthChecks.RegisterOptional("redis", health.Redis(redisClient))
+	}
+
 	// Setup router
 	router := httprouter.New()
 
@@ -89,13 +207,15 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(map[string]string{"message": "pong"})
 	})
+	router.Handler(http.MethodGet, "/healthz", healthChecks.LivenessHandler())
+	router.Handler(http.MethodGet, "/readyz", healthChecks.ReadinessHandler())
 
 	// Inventories routes
-	router.GET("/inventories", inventoryCtrl.GetAll)
-	router.GET("/inventories/:code", inventoryCtrl.GetByCode)
-	router.POST("/inventories", inventoryCtrl.Create)
-	router.PUT("/inventories/:code", inventoryCtrl.Update)
-	router.DELETE("/inventories/:code", inventoryCtrl.Delete)
+	router.GET("/inventories", can(inventoryCtrl.GetAll, rbac.InventoryRead))
+	router.GET("/inventories/:code", can(inventoryCtrl.GetByCode, rbac.InventoryRead))
+	router.POST("/inventories", can(inventoryCtrl.Create, rbac.InventoryWrite))
+	router.PUT("/inventories/:code", can(inventoryCtrl.Update, rbac.InventoryWrite))
+	router.DELETE("/inventories/:code", can(inventoryCtrl.Delete, rbac.InventoryDelete))
 
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -120,7 +240,7 @@ func main() {
 	logger.Info("Api service running in " + config.AppHost + ":" + config.AppPort)
 	server := &http.Server{
 		Addr:    config.AppHost + ":" + config.AppPort,
-		Handler: router,
+		Handler: middleware.ContextTimeout(config.DBRequestTimeout, router),
 	}
 
 	err = server.ListenAndServe()
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pobyzaarif/belajarGo2/app/http-server/common"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+)
+
+// ContextTimeout bounds the request context by timeout, the repositories get
+// it from the controllers so queries still running when it passes are
+// cancelled.
+func ContextTimeout(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Authenticator verifies an access token and returns what it claims.
+type Authenticator interface {
+	Authenticate(ctx context.Context, signedToken string) (token user.AccessToken, err error)
+}
+
+// Authorizer tells whether a role has every one of permissions, it returns a
+// Forbidden error when it doesn't.
+type Authorizer interface {
+	Authorize(ctx context.Context, role string, permissions ...string) (err error)
+}
+
+// Permission lets a request through to next when it carries a valid access
+// token, "Authorization: Bearer <token>" as handed out by the echo server, of
+// a role with every one of permissions.
+func Permission(authenticator Authenticator, authorizer Authorizer, next httprouter.Handle, permissions ...string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		scheme, signedToken, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if scheme != "Bearer" || signedToken == "" {
+			common.ErrorResponse(w, user.ErrInvalidAccessToken)
+			return
+		}
+
+		token, err := authenticator.Authenticate(r.Context(), signedToken)
+		if err != nil {
+			if !errors.Is(err, user.ErrInvalidAccessToken) {
+				slog.Error("Permission denylist error", slog.Any("error", err))
+			}
+			common.ErrorResponse(w, err)
+			return
+		}
+		// Until two-factor authentication is enabled the token is only good
+		// for that, on the echo server
+		if token.MFAPending {
+			common.ErrorResponse(w, user.ErrMFARequired)
+			return
+		}
+
+		if err := authorizer.Authorize(r.Context(), token.Role, permissions...); err != nil {
+			if !errors.Is(err, errs.Forbidden) {
+				slog.Error("Permission authorize error", slog.Any("error", err))
+			}
+			common.ErrorResponse(w, err)
+			return
+		}
+
+		next(w, r, ps)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/repository/backend"
+	"github.com/pobyzaarif/belajarGo2/repository/notification/mailjet"
+	loanSvc "github.com/pobyzaarif/belajarGo2/service/loan"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	cfg "github.com/pobyzaarif/go-config"
+	"github.com/robfig/cron/v3"
+)
+
+var loggerOption = slog.HandlerOptions{AddSource: true}
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
+
+type Config struct {
+	LoanReminderSchedule string `env:"LOAN_REMINDER_SCHEDULE" envDefault:"0 * * * *"`
+
+	DBDriver string `env:"DB_DRIVER"`
+
+	DBMySQLHost     string `env:"DB_MYSQL_HOST"`
+	DBMySQLPort     string `env:"DB_MYSQL_PORT"`
+	DBMySQLUser     string `env:"DB_MYSQL_USER"`
+	DBMySQLPassword string `env:"DB_MYSQL_PASSWORD"`
+	DBMySQLName     string `env:"DB_MYSQL_NAME"`
+
+	DBSQLiteName string `env:"DB_SQLITE_NAME"`
+
+	DBPostgreSQLHost     string `env:"DB_POSTGRESQL_HOST"`
+	DBPostgreSQLPort     string `env:"DB_POSTGRESQL_PORT"`
+	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
+	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
+	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
+
+	DBMongoURI  string `env:"DB_MONGO_URI"`
+	DBMongoName string `env:"DB_MONGO_NAME"`
+
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME"`
+	DBStatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES"`
+	DBConnectRetryDelay time.Duration `env:"DB_CONNECT_RETRY_DELAY" envDefault:"2s"`
+
+	DBMongoMaxPoolSize            uint64        `env:"DB_MONGO_MAX_POOL_SIZE"`
+	DBMongoMinPoolSize            uint64        `env:"DB_MONGO_MIN_POOL_SIZE"`
+	DBMongoMaxConnIdleTime        time.Duration `env:"DB_MONGO_MAX_CONN_IDLE_TIME"`
+	DBMongoConnectTimeout         time.Duration `env:"DB_MONGO_CONNECT_TIMEOUT"`
+	DBMongoServerSelectionTimeout time.Duration `env:"DB_MONGO_SERVER_SELECTION_TIMEOUT"`
+
+	InventoryRepoBackend string `env:"INVENTORY_REPO_BACKEND" envDefault:"gorm"`
+	UserRepoBackend      string `env:"USER_REPO_BACKEND" envDefault:"gorm"`
+	LoanRepoBackend      string `env:"LOAN_REPO_BACKEND" envDefault:"gorm"`
+
+	MailjetBaseUrl           string `env:"MAILJET_BASE_URL"`
+	MailjetBasicAuthUsername string `env:"MAILJET_BASIC_AUTH_USERNAME"`
+	MailjetBasicAuthPassword string `env:"MAILJET_BASIC_AUTH_PASSWORD"`
+	MailjetSenderEmail       string `env:"MAILJET_SENDER_EMAIL"`
+	MailjetSenderName        string `env:"MAILJET_SENDER_NAME"`
+}
+
+func main() {
+	// Init config
+	config := Config{}
+	err := cfg.LoadConfig(&config)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	logger.Info("Config loaded")
+
+	// Init repository backends, databases are connected to on first use
+	databaseConfig := database.Config{
+		DBDriver:             config.DBDriver,
+		DBMySQLHost:          config.DBMySQLHost,
+		DBMySQLPort:          config.DBMySQLPort,
+		DBMySQLUser:          config.DBMySQLUser,
+		DBMySQLPassword:      config.DBMySQLPassword,
+		DBMySQLName:          config.DBMySQLName,
+		DBSQLiteName:         config.DBSQLiteName,
+		DBPostgreSQLHost:     config.DBPostgreSQLHost,
+		DBPostgreSQLPort:     config.DBPostgreSQLPort,
+		DBPostgreSQLUser:     config.DBPostgreSQLUser,
+		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
+		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
+		DBMongoURI:           config.DBMongoURI,
+		DBMongoName:          config.DBMongoName,
+
+		DBMaxOpenConns:      config.DBMaxOpenConns,
+		DBMaxIdleConns:      config.DBMaxIdleConns,
+		DBConnMaxLifetime:   config.DBConnMaxLifetime,
+		DBConnMaxIdleTime:   config.DBConnMaxIdleTime,
+		DBStatementTimeout:  config.DBStatementTimeout,
+		DBConnectRetries:    config.DBConnectRetries,
+		DBConnectRetryDelay: config.DBConnectRetryDelay,
+
+		DBMongoMaxPoolSize:            config.DBMongoMaxPoolSize,
+		DBMongoMinPoolSize:            config.DBMongoMinPoolSize,
+		DBMongoMaxConnIdleTime:        config.DBMongoMaxConnIdleTime,
+		DBMongoConnectTimeout:         config.DBMongoConnectTimeout,
+		DBMongoServerSelectionTimeout: config.DBMongoServerSelectionTimeout,
+	}
+	backends := backend.New(backend.Config{
+		Inventory: config.InventoryRepoBackend,
+		User:      config.UserRepoBackend,
+		Loan:      config.LoanRepoBackend,
+	}, databaseConfig)
+
+	// notification
+	mailjetEmail := mailjet.NewMailjetRepository(
+		logger,
+		mailjet.MailjetConfig{
+			MailjetBaseURL:           config.MailjetBaseUrl,
+			MailjetBasicAuthUsername: config.MailjetBasicAuthUsername,
+			MailjetBasicAuthPassword: config.MailjetBasicAuthPassword,
+			MailjetSenderEmail:       config.MailjetSenderEmail,
+			MailjetSenderName:        config.MailjetSenderName,
+		},
+	)
+
+	// Dependency Injection, the same backends as the echo server
+	loanRepo, loanTransactor, err := backends.Loan()
+	if err != nil {
+		log.Fatalf("Failed to init loan repository: %v", err)
+	}
+	inventoryRepo, _, err := backends.Inventory()
+	if err != nil {
+		log.Fatalf("Failed to init inventory repository: %v", err)
+	}
+	userRepo, _, err := backends.User()
+	if err != nil {
+		log.Fatalf("Failed to init user repository: %v", err)
+	}
+	loanSvc := loanSvc.NewService(
+		logger,
+		loanRepo,
+		inventoryRepo,
+		userRepo,
+		loanTransactor,
+		mailjetEmail,
+	)
+
+	c := cron.New()
+	_, err = c.AddFunc(config.LoanReminderSchedule, func() {
+		sent, err := loanSvc.SendOverdueReminders(context.Background())
+		if err != nil {
+			logger.Error("Failed to send overdue loan reminders", slog.Any("error", err))
+			return
+		}
+		logger.Info("Overdue loan reminders sent", slog.Int("sent", sent))
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule loan reminder: %v", err)
+	}
+
+	c.Start()
+	logger.Info("Loan reminder running with schedule " + config.LoanReminderSchedule)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+
+	// Wait for a running job to finish before exiting
+	<-c.Stop().Done()
+	logger.Info("Successfully shutting down loan reminder")
+
+	if err := backends.Close(); err != nil {
+		logger.Error("Failed to close repository backends", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	cfg "github.com/pobyzaarif/go-config"
+)
+
+var loggerOption = slog.HandlerOptions{AddSource: true}
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
+
+type Config struct {
+	DBDriver string `env:"DB_DRIVER"`
+
+	DBMySQLHost     string `env:"DB_MYSQL_HOST"`
+	DBMySQLPort     string `env:"DB_MYSQL_PORT"`
+	DBMySQLUser     string `env:"DB_MYSQL_USER"`
+	DBMySQLPassword string `env:"DB_MYSQL_PASSWORD"`
+	DBMySQLName     string `env:"DB_MYSQL_NAME"`
+
+	DBSQLiteName string `env:"DB_SQLITE_NAME"`
+
+	DBPostgreSQLHost     string `env:"DB_POSTGRESQL_HOST"`
+	DBPostgreSQLPort     string `env:"DB_POSTGRESQL_PORT"`
+	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
+	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
+	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
+
+	DBMongoURI  string `env:"DB_MONGO_URI"`
+	DBMongoName string `env:"DB_MONGO_NAME"`
+
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME"`
+	DBStatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES"`
+	DBConnectRetryDelay time.Duration `env:"DB_CONNECT_RETRY_DELAY" envDefault:"2s"`
+
+	DBMongoMaxPoolSize            uint64        `env:"DB_MONGO_MAX_POOL_SIZE"`
+	DBMongoMinPoolSize            uint64        `env:"DB_MONGO_MIN_POOL_SIZE"`
+	DBMongoMaxConnIdleTime        time.Duration `env:"DB_MONGO_MAX_CONN_IDLE_TIME"`
+	DBMongoConnectTimeout         time.Duration `env:"DB_MONGO_CONNECT_TIMEOUT"`
+	DBMongoServerSelectionTimeout time.Duration `env:"DB_MONGO_SERVER_SELECTION_TIMEOUT"`
+
+	MigrationLockTimeout time.Duration `env:"MIGRATION_LOCK_TIMEOUT" envDefault:"1m"`
+}
+
+// migrator is what the sql and Mongo migrators have in common.
+type migrator interface {
+	Up(ctx context.Context) (applied []database.MigrationStatus, err error)
+	Down(ctx context.Context, steps int) (reverted []database.MigrationStatus, err error)
+	Status(ctx context.Context) (statuses []database.MigrationStatus, err error)
+	ForceUnlock(ctx context.Context) (err error)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: migrate [-mongo] <command>
+
+Commands:
+  up             apply every pending migration
+  down [steps]   revert the last steps migrations (default 1)
+  status         list migrations and when they were applied
+  force-unlock   release a lock left behind by a crashed run
+
+Flags:`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	useMongo := flag.Bool("mongo", false, "migrate the Mongo database (DB_MONGO_*) instead of DB_DRIVER")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	// Init config
+	config := Config{}
+	err := cfg.LoadConfig(&config)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Init db connection
+	databaseConfig := database.Config{
+		DBDriver:             config.DBDriver,
+		DBMySQLHost:          config.DBMySQLHost,
+		DBMySQLPort:          config.DBMySQLPort,
+		DBMySQLUser:          config.DBMySQLUser,
+		DBMySQLPassword:      config.DBMySQLPassword,
+		DBMySQLName:          config.DBMySQLName,
+		DBSQLiteName:         config.DBSQLiteName,
+		DBPostgreSQLHost:     config.DBPostgreSQLHost,
+		DBPostgreSQLPort:     config.DBPostgreSQLPort,
+		DBPostgreSQLUser:     config.DBPostgreSQLUser,
+		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
+		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
+		DBMongoURI:           config.DBMongoURI,
+		DBMongoName:          config.DBMongoName,
+
+		DBMaxOpenConns:      config.DBMaxOpenConns,
+		DBMaxIdleConns:      config.DBMaxIdleConns,
+		DBConnMaxLifetime:   config.DBConnMaxLifetime,
+		DBConnMaxIdleTime:   config.DBConnMaxIdleTime,
+		DBStatementTimeout:  config.DBStatementTimeout,
+		DBConnectRetries:    config.DBConnectRetries,
+		DBConnectRetryDelay: config.DBConnectRetryDelay,
+
+		DBMongoMaxPoolSize:            config.DBMongoMaxPoolSize,
+		DBMongoMinPoolSize:            config.DBMongoMinPoolSize,
+		DBMongoMaxConnIdleTime:        config.DBMongoMaxConnIdleTime,
+		DBMongoConnectTimeout:         config.DBMongoConnectTimeout,
+		DBMongoServerSelectionTimeout: config.DBMongoServerSelectionTimeout,
+	}
+
+	var m migrator
+	if *useMongo {
+		db, err := databaseConfig.GetNoSQLDatabaseConnection()
+		if err != nil {
+			log.Fatalf("Failed to connect to mongo: %v", err)
+		}
+		mongoMigrator := database.NewMongoMigrator(db, database.MongoMigrations())
+		mongoMigrator.LockTimeout = config.MigrationLockTimeout
+		m = mongoMigrator
+	} else {
+		migrations, err := database.Migrations(config.DBDriver)
+		if err != nil {
+			log.Fatalf("Failed to load migrations: %v", err)
+		}
+		db, err := databaseConfig.GetDatabaseConnection()
+		if err != nil {
+			log.Fatalf("Failed to connect to the database: %v", err)
+		}
+		sqlMigrator := database.NewMigrator(db, migrations)
+		sqlMigrator.LockTimeout = config.MigrationLockTimeout
+		m = sqlMigrator
+	}
+
+	ctx := context.Background()
+	switch command := flag.Arg(0); command {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			logger.Info("Migration applied", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate up: %v", err)
+		}
+		logger.Info("Database is up to date", slog.Int("applied", len(applied)))
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid steps %q", flag.Arg(1))
+			}
+		}
+
+		reverted, err := m.Down(ctx, steps)
+		for _, migration := range reverted {
+			logger.Info("Migration reverted", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate down: %v", err)
+		}
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+	case "force-unlock":
+		if err := m.ForceUnlock(ctx); err != nil {
+			log.Fatalf("Failed to release migration lock: %v", err)
+		}
+		logger.Info("Migration lock released")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/repository/backend"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/pobyzaarif/belajarGo2/util/fixture"
+	cfg "github.com/pobyzaarif/go-config"
+)
+
+var loggerOption = slog.HandlerOptions{AddSource: true}
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
+
+type Config struct {
+	DBDriver string `env:"DB_DRIVER"`
+
+	DBMySQLHost     string `env:"DB_MYSQL_HOST"`
+	DBMySQLPort     string `env:"DB_MYSQL_PORT"`
+	DBMySQLUser     string `env:"DB_MYSQL_USER"`
+	DBMySQLPassword string `env:"DB_MYSQL_PASSWORD"`
+	DBMySQLName     string `env:"DB_MYSQL_NAME"`
+
+	DBSQLiteName string `env:"DB_SQLITE_NAME"`
+
+	DBPostgreSQLHost     string `env:"DB_POSTGRESQL_HOST"`
+	DBPostgreSQLPort     string `env:"DB_POSTGRESQL_PORT"`
+	DBPostgreSQLUser     string `env:"DB_POSTGRESQL_USER"`
+	DBPostgreSQLPassword string `env:"DB_POSTGRESQL_PASSWORD"`
+	DBPostgreSQLName     string `env:"DB_POSTGRESQL_NAME"`
+	DBPostgreSQLSSLMode  string `env:"DB_POSTGRESQL_SSLMODE" envDefault:"require"`
+
+	DBMongoURI  string `env:"DB_MONGO_URI"`
+	DBMongoName string `env:"DB_MONGO_NAME"`
+
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME"`
+	DBStatementTimeout  time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES"`
+	DBConnectRetryDelay time.Duration `env:"DB_CONNECT_RETRY_DELAY" envDefault:"2s"`
+
+	DBMongoMaxPoolSize            uint64        `env:"DB_MONGO_MAX_POOL_SIZE"`
+	DBMongoMinPoolSize            uint64        `env:"DB_MONGO_MIN_POOL_SIZE"`
+	DBMongoMaxConnIdleTime        time.Duration `env:"DB_MONGO_MAX_CONN_IDLE_TIME"`
+	DBMongoConnectTimeout         time.Duration `env:"DB_MONGO_CONNECT_TIMEOUT"`
+	DBMongoServerSelectionTimeout time.Duration `env:"DB_MONGO_SERVER_SELECTION_TIMEOUT"`
+
+	InventoryRepoBackend string `env:"INVENTORY_REPO_BACKEND" envDefault:"gorm"`
+	UserRepoBackend      string `env:"USER_REPO_BACKEND" envDefault:"gorm"`
+	MemorySnapshotFile   string `env:"MEMORY_SNAPSHOT_FILE"`
+}
+
+func usage() {
+	sets, _ := fixture.Sets(fixture.Builtin())
+	fmt.Fprintf(os.Stderr, `Usage: seed [-dir path] [-set name]
+
+Loads a fixture set (inventories and users) into the backends selected by
+INVENTORY_REPO_BACKEND and USER_REPO_BACKEND, records that exist already are
+skipped so seeding again is safe.
+
+Builtin sets: %s
+
+Flags:
+`, strings.Join(sets, ", "))
+	flag.PrintDefaults()
+}
+
+func main() {
+	dir := flag.String("dir", "", "read fixture sets from this directory instead of the builtin ones")
+	setName := flag.String("set", "demo", "name of the fixture set to load")
+	flag.Usage = usage
+	flag.Parse()
+
+	var fsys fs.FS = fixture.Builtin()
+	if *dir != "" {
+		fsys = os.DirFS(*dir)
+	}
+	set, err := fixture.LoadSet(fsys, *setName)
+	if err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	// Init config
+	config := Config{}
+	err = cfg.LoadConfig(&config)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Init repository backends, the same ones the servers use
+	databaseConfig := database.Config{
+		DBDriver:             config.DBDriver,
+		DBMySQLHost:          config.DBMySQLHost,
+		DBMySQLPort:          config.DBMySQLPort,
+		DBMySQLUser:          config.DBMySQLUser,
+		DBMySQLPassword:      config.DBMySQLPassword,
+		DBMySQLName:          config.DBMySQLName,
+		DBSQLiteName:         config.DBSQLiteName,
+		DBPostgreSQLHost:     config.DBPostgreSQLHost,
+		DBPostgreSQLPort:     config.DBPostgreSQLPort,
+		DBPostgreSQLUser:     config.DBPostgreSQLUser,
+		DBPostgreSQLPassword: config.DBPostgreSQLPassword,
+		DBPostgreSQLName:     config.DBPostgreSQLName,
+		DBPostgreSQLSSLMode:  config.DBPostgreSQLSSLMode,
+		DBMongoURI:           config.DBMongoURI,
+		DBMongoName:          config.DBMongoName,
+
+		DBMaxOpenConns:      config.DBMaxOpenConns,
+		DBMaxIdleConns:      config.DBMaxIdleConns,
+		DBConnMaxLifetime:   config.DBConnMaxLifetime,
+		DBConnMaxIdleTime:   config.DBConnMaxIdleTime,
+		DBStatementTimeout:  config.DBStatementTimeout,
+		DBConnectRetries:    config.DBConnectRetries,
+		DBConnectRetryDelay: config.DBConnectRetryDelay,
+
+		DBMongoMaxPoolSize:            config.DBMongoMaxPoolSize,
+		DBMongoMinPoolSize:            config.DBMongoMinPoolSize,
+		DBMongoMaxConnIdleTime:        config.DBMongoMaxConnIdleTime,
+		DBMongoConnectTimeout:         config.DBMongoConnectTimeout,
+		DBMongoServerSelectionTimeout: config.DBMongoServerSelectionTimeout,
+	}
+
+	backends := backend.New(backend.Config{
+		Inventory: config.InventoryRepoBackend,
+		User:      config.UserRepoBackend,
+
+		MemorySnapshotFile: config.MemorySnapshotFile,
+	}, databaseConfig)
+
+	inventoryRepo, _, err := backends.Inventory()
+	if err != nil {
+		log.Fatalf("Failed to init inventory repository: %v", err)
+	}
+	userRepo, _, err := backends.User()
+	if err != nil {
+		log.Fatalf("Failed to init user repository: %v", err)
+	}
+
+	result, err := fixture.NewSeeder(inventoryRepo, userRepo).Seed(context.Background(), set)
+	logger.Info("Fixtures seeded",
+		slog.String("set", *setName),
+		slog.Int("inventories_created", result.InventoriesCreated),
+		slog.Int("inventories_skipped", result.InventoriesSkipped),
+		slog.Int("users_created", result.UsersCreated),
+		slog.Int("users_skipped", result.UsersSkipped),
+	)
+	if err != nil {
+		log.Fatalf("Failed to seed fixtures: %v", err)
+	}
+
+	if err := backends.Close(); err != nil {
+		log.Fatalf("Failed to close repository backends: %v", err)
+	}
+}
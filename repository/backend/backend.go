@@ -0,0 +1,353 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	invRepo "github.com/pobyzaarif/belajarGo2/repository/inventory"
+	loanRepo "github.com/pobyzaarif/belajarGo2/repository/loan"
+	rbacRepo "github.com/pobyzaarif/belajarGo2/repository/rbac"
+	"github.com/pobyzaarif/belajarGo2/repository/transaction"
+	userRepo "github.com/pobyzaarif/belajarGo2/repository/user"
+	woRepo "github.com/pobyzaarif/belajarGo2/repository/workorder"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/loan"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	svcTransaction "github.com/pobyzaarif/belajarGo2/service/transaction"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/service/workorder"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/pobyzaarif/belajarGo2/util/health"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+const (
+	Gorm   = "gorm"
+	Mongo  = "mongo"
+	Memory = "memory"
+)
+
+var (
+	ErrUnknownBackend = errors.New("unknown repository backend")
+
+	// ErrMixedBackends is returned for loans or work orders not on the
+	// inventory backend, their writes to the inventory would be outside
+	// their unit of work with nothing to undo them when it fails.
+	ErrMixedBackends = errors.New("repository backend differs from the inventory one")
+)
+
+// Config selects the backend (gorm, mongo or memory) of each repository.
+// MemorySnapshotFile, when set, is where the memory repositories are loaded
+// from at startup and saved to by Close.
+type Config struct {
+	Inventory string
+	User      string
+	Loan      string
+	WorkOrder string
+
+	MemorySnapshotFile string
+}
+
+// Backends builds repositories on the selected backends. A database is only
+// connected to the first time a repository needs it, so an app whose
+// repositories all live in Mongo never opens DB_DRIVER and the other way
+// round.
+type Backends struct {
+	config   Config
+	database database.Config
+
+	gormDB  *gorm.DB
+	mongoDB *mongo.Database
+	memory  *memoryStores
+}
+
+// memoryStores are the memory repositories, all of them share one transactor
+// and one snapshot file.
+type memoryStores struct {
+	Inventory    *invRepo.MemoryRepository              `json:"inventory"`
+	User         *userRepo.MemoryRepository             `json:"user"`
+	RefreshToken *userRepo.MemoryRefreshTokenRepository `json:"refresh_token"`
+	MFA          *userRepo.MemoryMFARepository          `json:"mfa"`
+	Role         *rbacRepo.MemoryRepository             `json:"role"`
+	Loan         *loanRepo.MemoryRepository             `json:"loan"`
+	WorkOrder    *woRepo.MemoryRepository               `json:"work_order"`
+	transactor   *transaction.MemoryTransactor
+}
+
+func New(config Config, databaseConfig database.Config) *Backends {
+	return &Backends{
+		config:   config,
+		database: databaseConfig,
+	}
+}
+
+func (b *Backends) gorm() (db *gorm.DB, err error) {
+	if b.gormDB == nil {
+		b.gormDB, err = b.database.GetDatabaseConnection()
+	}
+	return b.gormDB, err
+}
+
+func (b *Backends) mongo() (db *mongo.Database, err error) {
+	if b.mongoDB == nil {
+		b.mongoDB, err = b.database.GetNoSQLDatabaseConnection()
+	}
+	return b.mongoDB, err
+}
+
+func (b *Backends) memoryStores() (stores *memoryStores, err error) {
+	if b.memory != nil {
+		return b.memory, nil
+	}
+
+	stores = &memoryStores{
+		Inventory:    invRepo.NewMemoryRepository(),
+		User:         userRepo.NewMemoryRepository(),
+		RefreshToken: userRepo.NewMemoryRefreshTokenRepository(),
+		MFA:          userRepo.NewMemoryMFARepository(),
+		Role:         rbacRepo.NewMemoryRepository(),
+		Loan:         loanRepo.NewMemoryRepository(),
+		WorkOrder:    woRepo.NewMemoryRepository(),
+	}
+	stores.transactor = transaction.NewMemoryTransactor(stores.Inventory, stores.User, stores.RefreshToken, stores.MFA, stores.Role, stores.Loan, stores.WorkOrder)
+
+	if b.config.MemorySnapshotFile != "" {
+		data, err := os.ReadFile(b.config.MemorySnapshotFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, stores); err != nil {
+				return nil, fmt.Errorf("memory snapshot %s: %w", b.config.MemorySnapshotFile, err)
+			}
+		}
+	}
+
+	b.memory = stores
+	return stores, nil
+}
+
+// Close closes the gorm database with its read replicas, then saves the
+// memory repositories to MemorySnapshotFile if they are in use and a file is
+// set.
+func (b *Backends) Close() (err error) {
+	if b.gormDB != nil {
+		err = database.Close(b.gormDB)
+	}
+	if b.memory == nil || b.config.MemorySnapshotFile == "" {
+		return
+	}
+
+	data, errJSON := json.Marshal(b.memory)
+	if errJSON != nil {
+		return errors.Join(err, errJSON)
+	}
+	// Write then rename, a crash halfway never leaves a truncated snapshot
+	tmp := b.config.MemorySnapshotFile + ".tmp"
+	if errWrite := os.WriteFile(tmp, data, 0o600); errWrite != nil {
+		return errors.Join(err, errWrite)
+	}
+	return errors.Join(err, os.Rename(tmp, b.config.MemorySnapshotFile))
+}
+
+// RegisterHealthChecks registers the databases connected to so far as
+// required dependencies of checks, call it once the repositories are built.
+func (b *Backends) RegisterHealthChecks(checks *health.Health) {
+	if b.gormDB != nil {
+		checks.Register(b.database.DBDriver, health.Gorm(b.gormDB))
+	}
+	if b.mongoDB != nil {
+		checks.Register("mongo", health.Mongo(b.mongoDB))
+	}
+}
+
+// Inventory returns the inventory repository and the transactor of its backend.
+func (b *Backends) Inventory() (repo inventory.Repository, transactor svcTransaction.Transactor, err error) {
+	switch b.config.Inventory {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, nil, err
+		}
+		return invRepo.NewGormRepository(db), transaction.NewGormTransactor(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, nil, err
+		}
+		return invRepo.NewMongoRepository(db), transaction.NewMongoTransactor(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, nil, err
+		}
+		return stores.Inventory, stores.transactor, nil
+	}
+	return nil, nil, fmt.Errorf("inventory %q: %w", b.config.Inventory, ErrUnknownBackend)
+}
+
+// User returns the user repository and the transactor of its backend.
+func (b *Backends) User() (repo user.Repository, transactor svcTransaction.Transactor, err error) {
+	switch b.config.User {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, nil, err
+		}
+		return userRepo.NewGormRepository(db), transaction.NewGormTransactor(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, nil, err
+		}
+		return userRepo.NewMongoRepository(db), transaction.NewMongoTransactor(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, nil, err
+		}
+		return stores.User, stores.transactor, nil
+	}
+	return nil, nil, fmt.Errorf("user %q: %w", b.config.User, ErrUnknownBackend)
+}
+
+// RefreshToken returns the refresh token repository, it lives on the user
+// backend so the user transactor covers it.
+func (b *Backends) RefreshToken() (repo user.RefreshTokenRepository, err error) {
+	switch b.config.User {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, err
+		}
+		return userRepo.NewGormRefreshTokenRepository(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, err
+		}
+		return userRepo.NewMongoRefreshTokenRepository(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, err
+		}
+		return stores.RefreshToken, nil
+	}
+	return nil, fmt.Errorf("refresh token %q: %w", b.config.User, ErrUnknownBackend)
+}
+
+// MFA returns the two-factor settings repository, on the user backend as well.
+func (b *Backends) MFA() (repo user.MFARepository, err error) {
+	switch b.config.User {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, err
+		}
+		return userRepo.NewGormMFARepository(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, err
+		}
+		return userRepo.NewMongoMFARepository(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, err
+		}
+		return stores.MFA, nil
+	}
+	return nil, fmt.Errorf("mfa %q: %w", b.config.User, ErrUnknownBackend)
+}
+
+// Role returns the repository of the permissions of roles, on the user backend
+// too.
+func (b *Backends) Role() (repo rbac.Repository, err error) {
+	switch b.config.User {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, err
+		}
+		return rbacRepo.NewGormRepository(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, err
+		}
+		return rbacRepo.NewMongoRepository(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, err
+		}
+		return stores.Role, nil
+	}
+	return nil, fmt.Errorf("role %q: %w", b.config.User, ErrUnknownBackend)
+}
+
+// Loan returns the loan repository and the transactor of its backend, which
+// has to be the inventory one so a loan and the stock it takes are written in
+// one unit of work.
+func (b *Backends) Loan() (repo loan.Repository, transactor svcTransaction.Transactor, err error) {
+	if b.config.Loan != b.config.Inventory {
+		return nil, nil, fmt.Errorf("loan %q, inventory %q: %w", b.config.Loan, b.config.Inventory, ErrMixedBackends)
+	}
+
+	switch b.config.Loan {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, nil, err
+		}
+		return loanRepo.NewGormRepository(db), transaction.NewGormTransactor(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, nil, err
+		}
+		return loanRepo.NewMongoRepository(db), transaction.NewMongoTransactor(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, nil, err
+		}
+		return stores.Loan, stores.transactor, nil
+	}
+	return nil, nil, fmt.Errorf("loan %q: %w", b.config.Loan, ErrUnknownBackend)
+}
+
+// WorkOrder returns the work order repository and the transactor of its
+// backend, the inventory one as well like Loan, for the inventory status.
+func (b *Backends) WorkOrder() (repo workorder.Repository, transactor svcTransaction.Transactor, err error) {
+	if b.config.WorkOrder != b.config.Inventory {
+		return nil, nil, fmt.Errorf("work order %q, inventory %q: %w", b.config.WorkOrder, b.config.Inventory, ErrMixedBackends)
+	}
+
+	switch b.config.WorkOrder {
+	case Gorm:
+		db, err := b.gorm()
+		if err != nil {
+			return nil, nil, err
+		}
+		return woRepo.NewGormRepository(db), transaction.NewGormTransactor(db), nil
+	case Mongo:
+		db, err := b.mongo()
+		if err != nil {
+			return nil, nil, err
+		}
+		return woRepo.NewMongoRepository(db), transaction.NewMongoTransactor(db), nil
+	case Memory:
+		stores, err := b.memoryStores()
+		if err != nil {
+			return nil, nil, err
+		}
+		return stores.WorkOrder, stores.transactor, nil
+	}
+	return nil, nil, fmt.Errorf("work order %q: %w", b.config.WorkOrder, ErrUnknownBackend)
+}
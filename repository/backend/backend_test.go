@@ -0,0 +1,105 @@
+package backend_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pobyzaarif/belajarGo2/repository/backend"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackends(t *testing.T) {
+	databaseConfig := database.Config{
+		DBDriver:     "sqlite",
+		DBSQLiteName: filepath.Join(t.TempDir(), "test.db"),
+	}
+
+	backends := backend.New(backend.Config{
+		Inventory: backend.Gorm,
+		User:      backend.Gorm,
+		Loan:      "redis",
+	}, databaseConfig)
+
+	inventoryRepo, inventoryTransactor, err := backends.Inventory()
+	assert.Nil(t, err)
+	assert.NotNil(t, inventoryRepo)
+	assert.NotNil(t, inventoryTransactor)
+
+	userRepo, userTransactor, err := backends.User()
+	assert.Nil(t, err)
+	assert.NotNil(t, userRepo)
+	assert.NotNil(t, userTransactor)
+
+	// Loans and work orders write the inventory in their transactions
+	_, _, err = backends.Loan()
+	assert.ErrorIs(t, err, backend.ErrMixedBackends)
+
+	_, _, err = backends.WorkOrder()
+	assert.ErrorIs(t, err, backend.ErrMixedBackends)
+
+	backends = backend.New(backend.Config{Inventory: "redis", Loan: "redis", WorkOrder: "redis"}, databaseConfig)
+	_, _, err = backends.Loan()
+	assert.ErrorIs(t, err, backend.ErrUnknownBackend)
+
+	_, _, err = backends.WorkOrder()
+	assert.ErrorIs(t, err, backend.ErrUnknownBackend)
+
+	// A database that can't be connected to is an error, not an exit
+	backends = backend.New(backend.Config{Inventory: backend.Mongo}, databaseConfig)
+	_, _, err = backends.Inventory()
+	assert.NotNil(t, err)
+}
+
+func TestBackendsMemorySnapshot(t *testing.T) {
+	ctx := context.Background()
+	config := backend.Config{
+		Inventory:          backend.Memory,
+		User:               backend.Memory,
+		MemorySnapshotFile: filepath.Join(t.TempDir(), "snapshot.json"),
+	}
+
+	backends := backend.New(config, database.Config{})
+	inventoryRepo, _, err := backends.Inventory()
+	assert.Nil(t, err)
+	userRepo, _, err := backends.User()
+	assert.Nil(t, err)
+
+	assert.Nil(t, inventoryRepo.Create(ctx, inventory.Inventory{Code: "INV001", Name: "Laptop", Stock: 25, Status: "active"}))
+	assert.Nil(t, inventoryRepo.AddTags(ctx, "INV001", []string{"it"}))
+	assert.Nil(t, userRepo.Create(ctx, user.User{ID: "u1", Email: "test@example.com", Role: "admin"}))
+	assert.Nil(t, backends.Close())
+
+	// A new process picks up where the last one stopped
+	backends = backend.New(config, database.Config{})
+	inventoryRepo, _, err = backends.Inventory()
+	assert.Nil(t, err)
+	userRepo, _, err = backends.User()
+	assert.Nil(t, err)
+
+	inv, err := inventoryRepo.ReadByCode(ctx, "INV001")
+	assert.Nil(t, err)
+	assert.Equal(t, inventory.Inventory{Code: "INV001", Name: "Laptop", Stock: 25, Status: "active", Tags: []string{"it"}}, inv)
+
+	u, err := userRepo.GetByEmail(ctx, "test@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", u.Role)
+
+	// Stores that were empty are loaded empty, not nil
+	refreshTokenRepo, err := backends.RefreshToken()
+	assert.Nil(t, err)
+	_, err = refreshTokenRepo.GetByHash(ctx, "unknown")
+	assert.ErrorIs(t, err, user.ErrRefreshTokenNotFound)
+	mfaRepo, err := backends.MFA()
+	assert.Nil(t, err)
+	_, err = mfaRepo.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, user.ErrMFANotFound)
+	roleRepo, err := backends.Role()
+	assert.Nil(t, err)
+	_, err = roleRepo.Get(ctx, "admin")
+	assert.ErrorIs(t, err, rbac.ErrRoleNotFound)
+}
@@ -2,15 +2,37 @@ package inventory
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/util/database"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type (
 	GormRepository struct {
 		*gorm.DB
 	}
+
+	inventoryTag struct {
+		Code string
+		Tag  string
+	}
+
+	savedSearch struct {
+		ID     string
+		UserID string
+		Name   string
+		Filter string
+	}
+)
+
+const (
+	tableInventoryTags = "bg_inventory_tags"
+	tableSavedSearches = "bg_saved_searches"
 )
 
 func NewGormRepository(db *gorm.DB) *GormRepository {
@@ -19,29 +41,186 @@ func NewGormRepository(db *gorm.DB) *GormRepository {
 	}
 }
 
-func (r *GormRepository) Create(inv inventory.Inventory) (err error) {
-	ctx := context.Background()
-	return r.DB.WithContext(ctx).Create(&inv).Error
+func (r *GormRepository) Create(ctx context.Context, inv inventory.Inventory) (err error) {
+	err = database.Conn(ctx, r.DB).Create(&inv).Error
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", inventory.ErrConflict, err)
+	}
+	return
 }
 
-func (r *GormRepository) ReadAll(page int, limit int) (invs []inventory.Inventory, err error) {
-	ctx := context.Background()
-	r.DB.WithContext(ctx).Order("code DESC").Offset((page - 1) * limit).Limit(limit).Find(&invs)
+func (r *GormRepository) ReadAll(ctx context.Context, filter inventory.Filter, page int, limit int) (invs []inventory.Inventory, err error) {
+	query := database.Read(ctx, r.DB)
+	if len(filter.Tags) > 0 {
+		query = query.Where(
+			"code IN (?)",
+			database.Conn(ctx, r.DB).Table(tableInventoryTags).
+				Select("code").
+				Where("tag IN ?", filter.Tags).
+				Group("code").
+				Having("COUNT(DISTINCT tag) = ?", len(filter.Tags)),
+		)
+	}
+	err = query.Order("code DESC").Offset((page - 1) * limit).Limit(limit).Find(&invs).Error
+	if err != nil {
+		return
+	}
+
+	err = r.loadTags(ctx, invs)
 	return
 }
 
-func (r *GormRepository) ReadByCode(code string) (inv inventory.Inventory, err error) {
-	ctx := context.Background()
-	r.DB.WithContext(ctx).First(&inv, "code = ?", code)
+func (r *GormRepository) ReadByCode(ctx context.Context, code string) (inv inventory.Inventory, err error) {
+	err = database.Read(ctx, r.DB).First(&inv, "code = ?", code).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return inv, inventory.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+
+	invs := []inventory.Inventory{inv}
+	err = r.loadTags(ctx, invs)
+	return invs[0], err
+}
+
+// Update overwrites the fields of an existing inventory, zero values included.
+// Tags are left alone and a missing code is not created.
+func (r *GormRepository) Update(ctx context.Context, inv inventory.Inventory) (err error) {
+	return database.Conn(ctx, r.DB).Where("code = ?", inv.Code).Select("name", "stock", "description", "status").Updates(&inv).Error
+}
+
+func (r *GormRepository) Delete(ctx context.Context, code string) (err error) {
+	return database.Conn(ctx, r.DB).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(tableInventoryTags).Where("code = ?", code).Delete(&inventoryTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Table("bg_inventories").Where("code = ?", code).Delete(inventory.Inventory{}).Error
+	})
+}
+
+// AdjustStock changes the stock in the database rather than writing back a
+// value read earlier, two loans can't both take the last unit.
+func (r *GormRepository) AdjustStock(ctx context.Context, code string, delta int) (err error) {
+	result := database.Conn(ctx, r.DB).Where("code = ? AND stock + ? >= 0", code, delta).Update("stock", gorm.Expr("stock + ?", delta))
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	// Nothing changed: a missing code, too little stock, or a zero delta
+	// MySQL doesn't count as an affected row
+	var inv inventory.Inventory
+	err = database.Conn(ctx, r.DB).Select("stock").First(&inv, "code = ?", code).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return inventory.ErrNotFound
+	case err != nil:
+		return err
+	case inv.Stock+delta < 0:
+		return inventory.ErrInsufficientStock
+	}
+	return nil
+}
+
+func (r *GormRepository) SetStatus(ctx context.Context, code string, status string) (err error) {
+	return database.Conn(ctx, r.DB).Where("code = ?", code).Update("status", status).Error
+}
+
+func (r *GormRepository) AddTags(ctx context.Context, code string, tags []string) (err error) {
+	rows := make([]inventoryTag, 0, len(tags))
+	for _, tag := range tags {
+		rows = append(rows, inventoryTag{Code: code, Tag: tag})
+	}
+	return database.Conn(ctx, r.DB).Table(tableInventoryTags).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func (r *GormRepository) RemoveTags(ctx context.Context, code string, tags []string) (err error) {
+	return database.Conn(ctx, r.DB).Table(tableInventoryTags).Where("code = ? AND tag IN ?", code, tags).Delete(&inventoryTag{}).Error
+}
+
+func (r *GormRepository) loadTags(ctx context.Context, invs []inventory.Inventory) (err error) {
+	if len(invs) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(invs))
+	for _, inv := range invs {
+		codes = append(codes, inv.Code)
+	}
+
+	var rows []inventoryTag
+	err = database.Read(ctx, r.DB).Table(tableInventoryTags).Where("code IN ?", codes).Order("tag").Find(&rows).Error
+	if err != nil {
+		return
+	}
+
+	tagsByCode := make(map[string][]string)
+	for _, row := range rows {
+		tagsByCode[row.Code] = append(tagsByCode[row.Code], row.Tag)
+	}
+	for i := range invs {
+		invs[i].Tags = tagsByCode[invs[i].Code]
+	}
+	return nil
+}
+
+func (r *GormRepository) CreateSavedSearch(ctx context.Context, search inventory.SavedSearch) (err error) {
+	filter, err := json.Marshal(search.Filter)
+	if err != nil {
+		return
+	}
+
+	err = database.Conn(ctx, r.DB).Table(tableSavedSearches).Create(&savedSearch{
+		ID:     search.ID,
+		UserID: search.UserID,
+		Name:   search.Name,
+		Filter: string(filter),
+	}).Error
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", inventory.ErrSavedSearchConflict, err)
+	}
+	return
+}
+
+func (r *GormRepository) ReadSavedSearches(ctx context.Context, userID string) (searches []inventory.SavedSearch, err error) {
+	var rows []savedSearch
+	err = database.Conn(ctx, r.DB).Table(tableSavedSearches).Where("user_id = ?", userID).Order("name").Find(&rows).Error
+	if err != nil {
+		return
+	}
+
+	for _, row := range rows {
+		search, err := row.toSavedSearch()
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
 	return
 }
 
-func (r *GormRepository) Update(inv inventory.Inventory) (err error) {
-	ctx := context.Background()
-	return r.DB.WithContext(ctx).Where("code = ?", inv.Code).Save(inv).Error
+func (r *GormRepository) ReadSavedSearchByID(ctx context.Context, userID string, id string) (search inventory.SavedSearch, err error) {
+	var row savedSearch
+	err = database.Conn(ctx, r.DB).Table(tableSavedSearches).First(&row, "user_id = ? AND id = ?", userID, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return search, inventory.ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return
+	}
+	return row.toSavedSearch()
 }
 
-func (r *GormRepository) Delete(code string) (err error) {
-	ctx := context.Background()
-	return r.DB.WithContext(ctx).Where("code = ?", code).Delete(inventory.Inventory{}).Error
+func (r *GormRepository) DeleteSavedSearch(ctx context.Context, userID string, id string) (err error) {
+	return database.Conn(ctx, r.DB).Table(tableSavedSearches).Where("user_id = ? AND id = ?", userID, id).Delete(&savedSearch{}).Error
+}
+
+func (row savedSearch) toSavedSearch() (search inventory.SavedSearch, err error) {
+	search = inventory.SavedSearch{
+		ID:     row.ID,
+		UserID: row.UserID,
+		Name:   row.Name,
+	}
+	err = json.Unmarshal([]byte(row.Filter), &search.Filter)
+	return
 }
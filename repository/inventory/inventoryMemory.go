@@ -0,0 +1,273 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+)
+
+// MemoryRepository keeps inventories in process memory, for demos and tests.
+// It follows the gorm repository: tags are only changed by AddTags and
+// RemoveTags, and listings are sorted by code descending.
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	inventories   map[string]inventory.Inventory
+	savedSearches map[string]inventory.SavedSearch
+}
+
+type memoryState struct {
+	Inventories   []inventory.Inventory   `json:"inventories"`
+	SavedSearches []inventory.SavedSearch `json:"saved_searches"`
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		inventories:   map[string]inventory.Inventory{},
+		savedSearches: map[string]inventory.SavedSearch{},
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, inv inventory.Inventory) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.inventories[inv.Code]; ok {
+		return inventory.ErrConflict
+	}
+	inv.Tags = nil
+	r.inventories[inv.Code] = inv
+	return nil
+}
+
+func (r *MemoryRepository) ReadAll(ctx context.Context, filter inventory.Filter, page int, limit int) (invs []inventory.Inventory, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, inv := range r.inventories {
+		if hasAllTags(inv.Tags, filter.Tags) {
+			invs = append(invs, copyInventory(inv))
+		}
+	}
+	sort.Slice(invs, func(i, j int) bool { return invs[i].Code > invs[j].Code })
+	return database.Page(invs, page, limit), nil
+}
+
+func (r *MemoryRepository) ReadByCode(ctx context.Context, code string) (inv inventory.Inventory, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inv, ok := r.inventories[code]
+	if !ok {
+		return inv, inventory.ErrNotFound
+	}
+	return copyInventory(inv), nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, inv inventory.Inventory) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.inventories[inv.Code]
+	if !ok {
+		return nil
+	}
+	inv.Tags = stored.Tags
+	r.inventories[inv.Code] = inv
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, code string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.inventories, code)
+	return nil
+}
+
+func (r *MemoryRepository) AdjustStock(ctx context.Context, code string, delta int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.inventories[code]
+	if !ok {
+		return inventory.ErrNotFound
+	}
+	if inv.Stock+delta < 0 {
+		return inventory.ErrInsufficientStock
+	}
+	inv.Stock += delta
+	r.inventories[code] = inv
+	return nil
+}
+
+func (r *MemoryRepository) SetStatus(ctx context.Context, code string, status string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if inv, ok := r.inventories[code]; ok {
+		inv.Status = status
+		r.inventories[code] = inv
+	}
+	return nil
+}
+
+func (r *MemoryRepository) AddTags(ctx context.Context, code string, tags []string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.inventories[code]
+	if !ok {
+		return nil
+	}
+	merged := slices.Clone(inv.Tags)
+	for _, tag := range tags {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	sort.Strings(merged)
+	inv.Tags = merged
+	r.inventories[code] = inv
+	return nil
+}
+
+func (r *MemoryRepository) RemoveTags(ctx context.Context, code string, tags []string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.inventories[code]
+	if !ok {
+		return nil
+	}
+	var kept []string
+	for _, tag := range inv.Tags {
+		if !slices.Contains(tags, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	inv.Tags = kept
+	r.inventories[code] = inv
+	return nil
+}
+
+func (r *MemoryRepository) CreateSavedSearch(ctx context.Context, search inventory.SavedSearch) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.savedSearches[search.ID]; ok {
+		return inventory.ErrSavedSearchConflict
+	}
+	for _, stored := range r.savedSearches {
+		if stored.UserID == search.UserID && stored.Name == search.Name {
+			return inventory.ErrSavedSearchConflict
+		}
+	}
+	search.Filter.Tags = slices.Clone(search.Filter.Tags)
+	r.savedSearches[search.ID] = search
+	return nil
+}
+
+func (r *MemoryRepository) ReadSavedSearches(ctx context.Context, userID string) (searches []inventory.SavedSearch, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, search := range r.savedSearches {
+		if search.UserID == userID {
+			search.Filter.Tags = slices.Clone(search.Filter.Tags)
+			searches = append(searches, search)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].Name < searches[j].Name })
+	return searches, nil
+}
+
+func (r *MemoryRepository) ReadSavedSearchByID(ctx context.Context, userID string, id string) (search inventory.SavedSearch, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search, ok := r.savedSearches[id]
+	if !ok || search.UserID != userID {
+		return inventory.SavedSearch{}, inventory.ErrSavedSearchNotFound
+	}
+	search.Filter.Tags = slices.Clone(search.Filter.Tags)
+	return search, nil
+}
+
+func (r *MemoryRepository) DeleteSavedSearch(ctx context.Context, userID string, id string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if search, ok := r.savedSearches[id]; ok && search.UserID == userID {
+		delete(r.savedSearches, id)
+	}
+	return nil
+}
+
+// Snapshot copies the current state, calling restore puts it back. The
+// memory transactor uses it to roll a unit of work back.
+func (r *MemoryRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	inventories := maps.Clone(r.inventories)
+	savedSearches := maps.Clone(r.savedSearches)
+	r.mu.RUnlock()
+
+	// Stored values are never modified in place, a shallow copy is enough
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.inventories = inventories
+		r.savedSearches = savedSearches
+	}
+}
+
+func (r *MemoryRepository) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	state := memoryState{
+		Inventories:   slices.Collect(maps.Values(r.inventories)),
+		SavedSearches: slices.Collect(maps.Values(r.savedSearches)),
+	}
+	sort.Slice(state.Inventories, func(i, j int) bool { return state.Inventories[i].Code < state.Inventories[j].Code })
+	sort.Slice(state.SavedSearches, func(i, j int) bool { return state.SavedSearches[i].ID < state.SavedSearches[j].ID })
+	return json.Marshal(state)
+}
+
+func (r *MemoryRepository) UnmarshalJSON(data []byte) error {
+	var state memoryState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inventories = make(map[string]inventory.Inventory, len(state.Inventories))
+	for _, inv := range state.Inventories {
+		r.inventories[inv.Code] = inv
+	}
+	r.savedSearches = make(map[string]inventory.SavedSearch, len(state.SavedSearches))
+	for _, search := range state.SavedSearches {
+		r.savedSearches[search.ID] = search
+	}
+	return nil
+}
+
+func copyInventory(inv inventory.Inventory) inventory.Inventory {
+	inv.Tags = slices.Clone(inv.Tags)
+	return inv
+}
+
+func hasAllTags(tags []string, wanted []string) bool {
+	for _, tag := range wanted {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,107 +2,158 @@ package inventory
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"sort"
 
 	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/util/database"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func createInventoryIndex(col *mongo.Collection) error {
-	ctx := context.TODO()
+type MongoRepository struct {
+	col            *mongo.Collection
+	savedSearchCol *mongo.Collection
+}
 
-	// Check existing indexes
-	cur, err := col.Indexes().List(ctx)
-	if err != nil {
-		return err
+func NewMongoRepository(db *mongo.Database) *MongoRepository {
+	// Indexes are created by the Mongo migrations (util/database), not here
+	return &MongoRepository{
+		col:            db.Collection("inventories"),
+		savedSearchCol: db.Collection("saved_searches"),
 	}
-	defer cur.Close(ctx)
+}
 
-	indexExists := false
-	for cur.Next(ctx) {
-		var idx bson.M
-		if err := cur.Decode(&idx); err != nil {
-			return err
-		}
+// Create stores inv without its tags, like the gorm repository they are added
+// with AddTags.
+func (r *MongoRepository) Create(ctx context.Context, inv inventory.Inventory) (err error) {
+	inv.Tags = nil
+	_, err = r.col.InsertOne(ctx, inv)
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", inventory.ErrConflict, err)
+	}
+	return err
+}
 
-		if name, ok := idx["name"].(string); ok && name == "code_1" {
-			indexExists = true
-			break
-		}
+func (r *MongoRepository) ReadAll(ctx context.Context, filter inventory.Filter, page int, limit int) (invs []inventory.Inventory, err error) {
+	query := bson.M{}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
 	}
 
-	if indexExists {
-		return nil // already exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "code", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := r.col.Find(ctx, query, opts)
+	if err != nil {
+		return
 	}
+	defer cursor.Close(ctx)
 
-	// Create unique index on "code"
-	model := mongo.IndexModel{
-		Keys:    bson.D{{Key: "code", Value: 1}},
-		Options: options.Index().SetUnique(true),
+	for cursor.Next(ctx) {
+		var inv inventory.Inventory
+		if err = cursor.Decode(&inv); err != nil {
+			return
+		}
+		// $addToSet keeps insertion order, the other backends sort tags
+		sort.Strings(inv.Tags)
+		invs = append(invs, inv)
 	}
+	return
+}
 
-	_, err = col.Indexes().CreateOne(ctx, model)
-	return err
+func (r *MongoRepository) ReadByCode(ctx context.Context, code string) (inv inventory.Inventory, err error) {
+	err = r.col.FindOne(ctx, bson.M{"code": code}).Decode(&inv)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return inv, inventory.ErrNotFound
+	}
+	sort.Strings(inv.Tags)
+	return
 }
 
-type MongoRepository struct {
-	col *mongo.Collection
+// Update overwrites the fields of an existing inventory, tags are left alone.
+func (r *MongoRepository) Update(ctx context.Context, inv inventory.Inventory) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"code": inv.Code}, bson.M{"$set": bson.M{
+		"name":        inv.Name,
+		"stock":       inv.Stock,
+		"description": inv.Description,
+		"status":      inv.Status,
+	}})
+	return
 }
 
-func NewMongoRepository(db *mongo.Database) *MongoRepository {
-	col := db.Collection("inventories")
+func (r *MongoRepository) Delete(ctx context.Context, code string) (err error) {
+	_, err = r.col.DeleteOne(ctx, bson.M{"code": code})
+	return
+}
 
-	if err := createInventoryIndex(col); err != nil {
-		fmt.Println("Error ensuring unique index:", err)
+// AdjustStock changes the stock with $inc, guarded so it can't go below zero.
+func (r *MongoRepository) AdjustStock(ctx context.Context, code string, delta int) (err error) {
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"code": code, "stock": bson.M{"$gte": -delta}},
+		bson.M{"$inc": bson.M{"stock": delta}},
+	)
+	if err != nil || result.MatchedCount > 0 {
+		return
 	}
 
-	return &MongoRepository{
-		col: col,
+	count, err := r.col.CountDocuments(ctx, bson.M{"code": code})
+	if err != nil {
+		return
+	}
+	if count == 0 {
+		return inventory.ErrNotFound
 	}
+	return inventory.ErrInsufficientStock
 }
 
-func (r *MongoRepository) Create(inv inventory.Inventory) (err error) {
-	_, err = r.col.InsertOne(context.Background(), inv)
-	return err
+func (r *MongoRepository) SetStatus(ctx context.Context, code string, status string) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"code": code}, bson.M{"$set": bson.M{"status": status}})
+	return
 }
 
-func (r *MongoRepository) ReadAll(page int, limit int) (invs []inventory.Inventory, err error) {
-	cursor, err := r.col.Find(context.Background(), bson.M{}, nil)
-	if err != nil {
-		return
-	}
-	defer cursor.Close(context.Background())
+func (r *MongoRepository) AddTags(ctx context.Context, code string, tags []string) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"code": code}, bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": tags}}})
+	return
+}
 
-	for cursor.Next(context.Background()) {
-		var inv inventory.Inventory
-		if err = cursor.Decode(&inv); err != nil {
-			return
-		}
-		invs = append(invs, inv)
+func (r *MongoRepository) RemoveTags(ctx context.Context, code string, tags []string) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"code": code}, bson.M{"$pull": bson.M{"tags": bson.M{"$in": tags}}})
+	return
+}
+
+func (r *MongoRepository) CreateSavedSearch(ctx context.Context, search inventory.SavedSearch) (err error) {
+	_, err = r.savedSearchCol.InsertOne(ctx, search)
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", inventory.ErrSavedSearchConflict, err)
 	}
 	return
 }
 
-func (r *MongoRepository) ReadByCode(code string) (inv inventory.Inventory, err error) {
-	err = r.col.FindOne(context.Background(), bson.M{"code": code}).Decode(&inv)
+func (r *MongoRepository) ReadSavedSearches(ctx context.Context, userID string) (searches []inventory.SavedSearch, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.savedSearchCol.Find(ctx, bson.M{"user_id": userID}, opts)
 	if err != nil {
-		if strings.Contains(err.Error(), "no documents") {
-			err = nil
-			return
-		}
+		return
 	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &searches)
 	return
 }
 
-func (r *MongoRepository) Update(inv inventory.Inventory) (err error) {
-	_, err = r.col.UpdateOne(context.Background(), bson.M{"code": inv.Code}, bson.M{"$set": inv})
+func (r *MongoRepository) ReadSavedSearchByID(ctx context.Context, userID string, id string) (search inventory.SavedSearch, err error) {
+	err = r.savedSearchCol.FindOne(ctx, bson.M{"user_id": userID, "search_id": id}).Decode(&search)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return search, inventory.ErrSavedSearchNotFound
+	}
 	return
 }
 
-func (r *MongoRepository) Delete(code string) (err error) {
-	_, err = r.col.DeleteOne(context.Background(), bson.M{"code": code})
+func (r *MongoRepository) DeleteSavedSearch(ctx context.Context, userID string, id string) (err error) {
+	_, err = r.savedSearchCol.DeleteOne(ctx, bson.M{"user_id": userID, "search_id": id})
 	return
 }
@@ -0,0 +1,28 @@
+package inventory_test
+
+import (
+	"testing"
+
+	invRepo "github.com/pobyzaarif/belajarGo2/repository/inventory"
+	"github.com/pobyzaarif/belajarGo2/repository/repotest"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	backends := []struct {
+		name    string
+		newRepo func(t *testing.T) inventory.Repository
+	}{
+		{"sqlite", func(t *testing.T) inventory.Repository { return invRepo.NewGormRepository(repotest.SQLite(t)) }},
+		{"mysql", func(t *testing.T) inventory.Repository { return invRepo.NewGormRepository(repotest.MySQL(t)) }},
+		{"postgres", func(t *testing.T) inventory.Repository { return invRepo.NewGormRepository(repotest.Postgres(t)) }},
+		{"mongo", func(t *testing.T) inventory.Repository { return invRepo.NewMongoRepository(repotest.Mongo(t)) }},
+		{"memory", func(t *testing.T) inventory.Repository { return invRepo.NewMemoryRepository() }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repotest.InventoryRepository(t, backend.newRepo)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/loan"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"gorm.io/gorm"
+)
+
+type (
+	GormRepository struct {
+		*gorm.DB
+	}
+)
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{
+		db.Table("bg_loans"),
+	}
+}
+
+func (r *GormRepository) Create(ctx context.Context, loan loan.Loan) (err error) {
+	return database.Conn(ctx, r.DB).Create(&loan).Error
+}
+
+func (r *GormRepository) ReadAll(ctx context.Context, userID string, page int, limit int) (loans []loan.Loan, err error) {
+	query := database.Conn(ctx, r.DB)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err = query.Order("checked_out_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&loans).Error
+	return
+}
+
+func (r *GormRepository) ReadByID(ctx context.Context, id string) (l loan.Loan, err error) {
+	err = database.Conn(ctx, r.DB).First(&l, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return l, loan.ErrNotFound
+	}
+	return
+}
+
+func (r *GormRepository) ReadOverdue(ctx context.Context, userID string, now time.Time) (loans []loan.Loan, err error) {
+	query := database.Conn(ctx, r.DB).Where("returned_at IS NULL AND due_at < ?", now)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err = query.Order("due_at").Find(&loans).Error
+	return
+}
+
+func (r *GormRepository) MarkReminded(ctx context.Context, id string, at time.Time) (err error) {
+	return database.Conn(ctx, r.DB).Where("id = ? AND returned_at IS NULL", id).Update("reminder_sent_at", at).Error
+}
+
+func (r *GormRepository) Return(ctx context.Context, id string, returnedAt time.Time) (err error) {
+	result := database.Conn(ctx, r.DB).Where("id = ? AND returned_at IS NULL", id).Update("returned_at", returnedAt)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	if _, err = r.ReadByID(ctx, id); err != nil {
+		return
+	}
+	return loan.ErrReturnedAlready
+}
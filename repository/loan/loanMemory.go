@@ -0,0 +1,135 @@
+package loan
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/loan"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+)
+
+// MemoryRepository keeps loans in process memory, for demos and tests.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	loans map[string]loan.Loan
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		loans: map[string]loan.Loan{},
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, l loan.Loan) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.loans[l.ID] = l
+	return nil
+}
+
+func (r *MemoryRepository) ReadAll(ctx context.Context, userID string, page int, limit int) (loans []loan.Loan, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, l := range r.loans {
+		if userID == "" || l.UserID == userID {
+			loans = append(loans, l)
+		}
+	}
+	sort.Slice(loans, func(i, j int) bool { return loans[i].CheckedOutAt.After(loans[j].CheckedOutAt) })
+	return database.Page(loans, page, limit), nil
+}
+
+func (r *MemoryRepository) ReadByID(ctx context.Context, id string) (l loan.Loan, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, ok := r.loans[id]
+	if !ok {
+		return l, loan.ErrNotFound
+	}
+	return l, nil
+}
+
+func (r *MemoryRepository) ReadOverdue(ctx context.Context, userID string, now time.Time) (loans []loan.Loan, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, l := range r.loans {
+		if l.IsOverdue(now) && (userID == "" || l.UserID == userID) {
+			loans = append(loans, l)
+		}
+	}
+	sort.Slice(loans, func(i, j int) bool { return loans[i].DueAt.Before(loans[j].DueAt) })
+	return loans, nil
+}
+
+func (r *MemoryRepository) MarkReminded(ctx context.Context, id string, at time.Time) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, ok := r.loans[id]; ok && l.ReturnedAt == nil {
+		l.ReminderSentAt = &at
+		r.loans[id] = l
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Return(ctx context.Context, id string, returnedAt time.Time) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.loans[id]
+	if !ok {
+		return loan.ErrNotFound
+	}
+	if l.ReturnedAt != nil {
+		return loan.ErrReturnedAlready
+	}
+	l.ReturnedAt = &returnedAt
+	r.loans[id] = l
+	return nil
+}
+
+// Snapshot copies the current state, calling restore puts it back.
+func (r *MemoryRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	loans := maps.Clone(r.loans)
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.loans = loans
+	}
+}
+
+func (r *MemoryRepository) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	loans := slices.AppendSeq(make([]loan.Loan, 0, len(r.loans)), maps.Values(r.loans))
+	sort.Slice(loans, func(i, j int) bool { return loans[i].ID < loans[j].ID })
+	return json.Marshal(loans)
+}
+
+func (r *MemoryRepository) UnmarshalJSON(data []byte) error {
+	var loans []loan.Loan
+	if err := json.Unmarshal(data, &loans); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loans = make(map[string]loan.Loan, len(loans))
+	for _, l := range loans {
+		r.loans[l.ID] = l
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/loan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	col *mongo.Collection
+}
+
+func NewMongoRepository(db *mongo.Database) *MongoRepository {
+	return &MongoRepository{
+		col: db.Collection("loans"),
+	}
+}
+
+func (r *MongoRepository) Create(ctx context.Context, loan loan.Loan) (err error) {
+	_, err = r.col.InsertOne(ctx, loan)
+	return
+}
+
+func (r *MongoRepository) ReadAll(ctx context.Context, userID string, page int, limit int) (loans []loan.Loan, err error) {
+	query := bson.M{}
+	if userID != "" {
+		query["user_id"] = userID
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "checked_out_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := r.col.Find(ctx, query, opts)
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &loans)
+	return
+}
+
+func (r *MongoRepository) ReadByID(ctx context.Context, id string) (l loan.Loan, err error) {
+	err = r.col.FindOne(ctx, bson.M{"loan_id": id}).Decode(&l)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return l, loan.ErrNotFound
+	}
+	return
+}
+
+func (r *MongoRepository) ReadOverdue(ctx context.Context, userID string, now time.Time) (loans []loan.Loan, err error) {
+	query := bson.M{"returned_at": nil, "due_at": bson.M{"$lt": now}}
+	if userID != "" {
+		query["user_id"] = userID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}})
+	cursor, err := r.col.Find(ctx, query, opts)
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &loans)
+	return
+}
+
+func (r *MongoRepository) MarkReminded(ctx context.Context, id string, at time.Time) (err error) {
+	_, err = r.col.UpdateOne(ctx,
+		bson.M{"loan_id": id, "returned_at": nil},
+		bson.M{"$set": bson.M{"reminder_sent_at": at}},
+	)
+	return
+}
+
+func (r *MongoRepository) Return(ctx context.Context, id string, returnedAt time.Time) (err error) {
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"loan_id": id, "returned_at": nil},
+		bson.M{"$set": bson.M{"returned_at": returnedAt}},
+	)
+	if err != nil || result.MatchedCount > 0 {
+		return
+	}
+
+	if _, err = r.ReadByID(ctx, id); err != nil {
+		return
+	}
+	return loan.ErrReturnedAlready
+}
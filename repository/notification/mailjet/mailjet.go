@@ -1,6 +1,7 @@
 package mailjet
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -49,7 +50,7 @@ type Messages struct {
 	HTMLPart string `json:"HTMLPart"`
 }
 
-func (r *MailjetRepository) SendEmail(toName, toEmail, subject, message string) (err error) {
+func (r *MailjetRepository) SendEmail(ctx context.Context, toName, toEmail, subject, message string) (err error) {
 	url := r.mailjetConfig.MailjetBaseURL + "/v3.1/send"
 	method := http.MethodPost
 
@@ -79,7 +80,7 @@ func (r *MailjetRepository) SendEmail(toName, toEmail, subject, message string)
 	payloadByte, _ := json.Marshal(payload)
 
 	client := &http.Client{}
-	req, err := http.NewRequest(method, url, strings.NewReader(string(payloadByte)))
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(string(payloadByte)))
 	if err != nil {
 		// TODO Add log
 		return
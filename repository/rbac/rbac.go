@@ -0,0 +1,39 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"gorm.io/gorm"
+)
+
+type (
+	GormRepository struct {
+		*gorm.DB
+	}
+)
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{
+		db.Table("bg_roles"),
+	}
+}
+
+func (r *GormRepository) Get(ctx context.Context, name string) (role rbac.Role, err error) {
+	err = database.Conn(ctx, r.DB).First(&role, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return role, rbac.ErrRoleNotFound
+	}
+	return
+}
+
+func (r *GormRepository) GetAll(ctx context.Context) (roles []rbac.Role, err error) {
+	err = database.Conn(ctx, r.DB).Order("name ASC").Find(&roles).Error
+	return
+}
+
+func (r *GormRepository) Save(ctx context.Context, role rbac.Role) (err error) {
+	return database.Conn(ctx, r.DB).Save(&role).Error
+}
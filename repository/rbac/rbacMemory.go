@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+)
+
+// MemoryRepository keeps the roles in process memory, for demos and tests.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	roles map[string]rbac.Role
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		roles: map[string]rbac.Role{},
+	}
+}
+
+func (r *MemoryRepository) Get(ctx context.Context, name string) (role rbac.Role, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, ok := r.roles[name]
+	if !ok {
+		return role, rbac.ErrRoleNotFound
+	}
+	role.Permissions = slices.Clone(role.Permissions)
+	return role, nil
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) (roles []rbac.Role, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles = make([]rbac.Role, 0, len(r.roles))
+	for _, role := range r.roles {
+		role.Permissions = slices.Clone(role.Permissions)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (r *MemoryRepository) Save(ctx context.Context, role rbac.Role) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	role.Permissions = slices.Clone(role.Permissions)
+	r.roles[role.Name] = role
+	return nil
+}
+
+// Snapshot copies the current state, calling restore puts it back. The
+// memory transactor uses it to roll a unit of work back.
+func (r *MemoryRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	roles := maps.Clone(r.roles)
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.roles = roles
+	}
+}
+
+func (r *MemoryRepository) MarshalJSON() ([]byte, error) {
+	roles, _ := r.GetAll(context.Background())
+	return json.Marshal(roles)
+}
+
+func (r *MemoryRepository) UnmarshalJSON(data []byte) error {
+	var roles []rbac.Role
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles = make(map[string]rbac.Role, len(roles))
+	for _, role := range roles {
+		r.roles[role.Name] = role
+	}
+	return nil
+}
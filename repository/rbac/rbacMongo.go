@@ -0,0 +1,46 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	col *mongo.Collection
+}
+
+func NewMongoRepository(db *mongo.Database) *MongoRepository {
+	// Indexes are created by the Mongo migrations (util/database), not here
+	return &MongoRepository{
+		col: db.Collection("roles"),
+	}
+}
+
+func (r *MongoRepository) Get(ctx context.Context, name string) (role rbac.Role, err error) {
+	err = r.col.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return role, rbac.ErrRoleNotFound
+	}
+	return
+}
+
+func (r *MongoRepository) GetAll(ctx context.Context) (roles []rbac.Role, err error) {
+	cursor, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &roles)
+	return
+}
+
+func (r *MongoRepository) Save(ctx context.Context, role rbac.Role) (err error) {
+	_, err = r.col.ReplaceOne(ctx, bson.M{"name": role.Name}, role, options.Replace().SetUpsert(true))
+	return
+}
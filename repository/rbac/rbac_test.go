@@ -0,0 +1,28 @@
+package rbac_test
+
+import (
+	"testing"
+
+	rbacRepo "github.com/pobyzaarif/belajarGo2/repository/rbac"
+	"github.com/pobyzaarif/belajarGo2/repository/repotest"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	backends := []struct {
+		name    string
+		newRepo func(t *testing.T) rbac.Repository
+	}{
+		{"sqlite", func(t *testing.T) rbac.Repository { return rbacRepo.NewGormRepository(repotest.SQLite(t)) }},
+		{"mysql", func(t *testing.T) rbac.Repository { return rbacRepo.NewGormRepository(repotest.MySQL(t)) }},
+		{"postgres", func(t *testing.T) rbac.Repository { return rbacRepo.NewGormRepository(repotest.Postgres(t)) }},
+		{"mongo", func(t *testing.T) rbac.Repository { return rbacRepo.NewMongoRepository(repotest.Mongo(t)) }},
+		{"memory", func(t *testing.T) rbac.Repository { return rbacRepo.NewMemoryRepository() }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repotest.RoleRepository(t, backend.newRepo)
+		})
+	}
+}
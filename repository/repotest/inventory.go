@@ -0,0 +1,247 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/stretchr/testify/assert"
+)
+
+// InventoryRepository runs the inventory.Repository scenarios, newRepo must
+// return an empty repository on every call.
+func InventoryRepository(t *testing.T, newRepo func(t *testing.T) inventory.Repository) {
+	ctx := context.Background()
+	laptop := inventory.Inventory{Code: "INV001", Name: "Laptop", Stock: 25, Description: "Dell Latitude 5420", Status: "active"}
+	mouse := inventory.Inventory{Code: "INV002", Name: "Mouse", Stock: 100, Description: "Logitech wireless mouse", Status: "active"}
+	monitor := inventory.Inventory{Code: "INV003", Name: "Monitor", Stock: 30, Description: "27-inch 4K UHD monitor", Status: "broken"}
+
+	create := func(t *testing.T, repo inventory.Repository, invs ...inventory.Inventory) {
+		t.Helper()
+		for _, inv := range invs {
+			if err := repo.Create(ctx, inv); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("create and read by code", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Equal(t, laptop, normalize(got))
+
+		_, err = repo.ReadByCode(ctx, "INV999")
+		assert.ErrorIs(t, err, inventory.ErrNotFound)
+	})
+
+	t.Run("create a duplicate code conflicts", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+
+		err := repo.Create(ctx, inventory.Inventory{Code: laptop.Code, Name: "Other", Status: "active"})
+		assert.ErrorIs(t, err, inventory.ErrConflict)
+	})
+
+	t.Run("create leaves tags to AddTags", func(t *testing.T) {
+		repo := newRepo(t)
+		withTags := laptop
+		withTags.Tags = []string{"it"}
+		create(t, repo, withTags)
+
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Empty(t, got.Tags)
+	})
+
+	t.Run("read all sorts by code descending and pages", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, mouse, laptop, monitor)
+
+		got, err := repo.ReadAll(ctx, inventory.Filter{}, 1, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{monitor.Code, mouse.Code}, codes(got))
+
+		got, err = repo.ReadAll(ctx, inventory.Filter{}, 2, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{laptop.Code}, codes(got))
+
+		got, err = repo.ReadAll(ctx, inventory.Filter{}, 3, 2)
+		assert.Nil(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("read all filters on every tag", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop, mouse, monitor)
+		assert.Nil(t, repo.AddTags(ctx, laptop.Code, []string{"it", "loaner"}))
+		assert.Nil(t, repo.AddTags(ctx, mouse.Code, []string{"it"}))
+
+		got, err := repo.ReadAll(ctx, inventory.Filter{Tags: []string{"it"}}, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{mouse.Code, laptop.Code}, codes(got))
+
+		got, err = repo.ReadAll(ctx, inventory.Filter{Tags: []string{"loaner", "it"}}, 1, 10)
+		assert.Nil(t, err)
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, []string{"it", "loaner"}, got[0].Tags)
+		}
+
+		got, err = repo.ReadAll(ctx, inventory.Filter{Tags: []string{"it", "floor-3"}}, 1, 10)
+		assert.Nil(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("update replaces the fields and keeps the tags", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+		assert.Nil(t, repo.AddTags(ctx, laptop.Code, []string{"it"}))
+
+		updated := inventory.Inventory{Code: laptop.Code, Name: "Laptop Pro", Stock: 0, Description: "", Status: "broken"}
+		assert.Nil(t, repo.Update(ctx, updated))
+
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		updated.Tags = []string{"it"}
+		assert.Equal(t, updated, got)
+	})
+
+	t.Run("update of a missing code creates nothing", func(t *testing.T) {
+		repo := newRepo(t)
+
+		assert.Nil(t, repo.Update(ctx, laptop))
+
+		_, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.ErrorIs(t, err, inventory.ErrNotFound)
+	})
+
+	t.Run("adjust stock never goes below zero", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+
+		assert.Nil(t, repo.AdjustStock(ctx, laptop.Code, -20))
+		assert.ErrorIs(t, repo.AdjustStock(ctx, laptop.Code, -6), inventory.ErrInsufficientStock)
+		assert.Nil(t, repo.AdjustStock(ctx, laptop.Code, 0))
+		assert.Nil(t, repo.AdjustStock(ctx, laptop.Code, 3))
+		assert.ErrorIs(t, repo.AdjustStock(ctx, "INV999", 1), inventory.ErrNotFound)
+
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Equal(t, 8, got.Stock)
+	})
+
+	t.Run("set status changes nothing else", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+		assert.Nil(t, repo.AddTags(ctx, laptop.Code, []string{"it"}))
+
+		assert.Nil(t, repo.SetStatus(ctx, laptop.Code, "broken"))
+		assert.Nil(t, repo.SetStatus(ctx, "INV999", "broken"))
+
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		want := laptop
+		want.Status = "broken"
+		want.Tags = []string{"it"}
+		assert.Equal(t, want, got)
+
+		_, err = repo.ReadByCode(ctx, "INV999")
+		assert.ErrorIs(t, err, inventory.ErrNotFound)
+	})
+
+	t.Run("delete removes the inventory and its tags", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+		assert.Nil(t, repo.AddTags(ctx, laptop.Code, []string{"it"}))
+
+		assert.Nil(t, repo.Delete(ctx, laptop.Code))
+		_, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.ErrorIs(t, err, inventory.ErrNotFound)
+
+		// The code can be used again, without the old tags
+		create(t, repo, laptop)
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Empty(t, got.Tags)
+
+		assert.Nil(t, repo.Delete(ctx, "INV999"))
+	})
+
+	t.Run("tags are a sorted set", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, laptop)
+
+		assert.Nil(t, repo.AddTags(ctx, laptop.Code, []string{"loaner", "it"}))
+		assert.Nil(t, repo.AddTags(ctx, laptop.Code, []string{"it", "floor-3"}))
+		got, err := repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"floor-3", "it", "loaner"}, got.Tags)
+
+		assert.Nil(t, repo.RemoveTags(ctx, laptop.Code, []string{"it", "unknown"}))
+		got, err = repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"floor-3", "loaner"}, got.Tags)
+
+		assert.Nil(t, repo.RemoveTags(ctx, laptop.Code, []string{"floor-3", "loaner"}))
+		got, err = repo.ReadByCode(ctx, laptop.Code)
+		assert.Nil(t, err)
+		assert.Empty(t, got.Tags)
+	})
+
+	t.Run("saved searches belong to their user", func(t *testing.T) {
+		repo := newRepo(t)
+		broken := inventory.SavedSearch{ID: "s1", UserID: "u1", Name: "loaners", Filter: inventory.Filter{Tags: []string{"loaner"}}}
+		all := inventory.SavedSearch{ID: "s2", UserID: "u1", Name: "all"}
+		other := inventory.SavedSearch{ID: "s3", UserID: "u2", Name: "loaners"}
+
+		assert.Nil(t, repo.CreateSavedSearch(ctx, broken))
+		assert.Nil(t, repo.CreateSavedSearch(ctx, all))
+		assert.Nil(t, repo.CreateSavedSearch(ctx, other))
+
+		err := repo.CreateSavedSearch(ctx, inventory.SavedSearch{ID: "s4", UserID: "u1", Name: "loaners"})
+		assert.ErrorIs(t, err, inventory.ErrSavedSearchConflict)
+		err = repo.CreateSavedSearch(ctx, inventory.SavedSearch{ID: "s1", UserID: "u3", Name: "other"})
+		assert.ErrorIs(t, err, inventory.ErrSavedSearchConflict)
+
+		searches, err := repo.ReadSavedSearches(ctx, "u1")
+		assert.Nil(t, err)
+		if assert.Len(t, searches, 2) {
+			assert.Equal(t, "all", searches[0].Name)
+			assert.Empty(t, searches[0].Filter.Tags)
+			assert.Equal(t, broken, searches[1])
+		}
+
+		got, err := repo.ReadSavedSearchByID(ctx, "u1", "s1")
+		assert.Nil(t, err)
+		assert.Equal(t, broken, got)
+		_, err = repo.ReadSavedSearchByID(ctx, "u2", "s1")
+		assert.ErrorIs(t, err, inventory.ErrSavedSearchNotFound)
+
+		// Deleting someone else's search does nothing
+		assert.Nil(t, repo.DeleteSavedSearch(ctx, "u2", "s1"))
+		_, err = repo.ReadSavedSearchByID(ctx, "u1", "s1")
+		assert.Nil(t, err)
+
+		assert.Nil(t, repo.DeleteSavedSearch(ctx, "u1", "s1"))
+		_, err = repo.ReadSavedSearchByID(ctx, "u1", "s1")
+		assert.ErrorIs(t, err, inventory.ErrSavedSearchNotFound)
+	})
+}
+
+// normalize turns empty tags into nil, backends don't agree on which one
+// "no tags" reads back as.
+func normalize(inv inventory.Inventory) inventory.Inventory {
+	if len(inv.Tags) == 0 {
+		inv.Tags = nil
+	}
+	return inv
+}
+
+func codes(invs []inventory.Inventory) (codes []string) {
+	for _, inv := range invs {
+		codes = append(codes, inv.Code)
+	}
+	return codes
+}
@@ -0,0 +1,63 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/stretchr/testify/assert"
+)
+
+// MFARepository runs the user.MFARepository scenarios, newRepo must return an
+// empty repository on every call.
+func MFARepository(t *testing.T, newRepo func(t *testing.T) user.MFARepository) {
+	ctx := context.Background()
+	// Rounded, not every store keeps nanoseconds or the location
+	now := time.Now().UTC().Truncate(time.Second)
+	pending := user.MFA{UserID: "u1", Secret: "secret1", CreatedAt: now}
+	other := user.MFA{UserID: "u2", Secret: "secret2", Enabled: true, RecoveryCodes: []string{"hash1", "hash2"}, LastStep: 7, CreatedAt: now}
+
+	get := func(t *testing.T, repo user.MFARepository, userID string) user.MFA {
+		t.Helper()
+		mfa, err := repo.Get(ctx, userID)
+		assert.Nil(t, err)
+		mfa.CreatedAt = mfa.CreatedAt.UTC()
+		return mfa
+	}
+
+	t.Run("save and read", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Save(ctx, other))
+		assert.Equal(t, other, get(t, repo, other.UserID))
+
+		_, err := repo.Get(ctx, "unknown")
+		assert.ErrorIs(t, err, user.ErrMFANotFound)
+	})
+
+	t.Run("save replaces", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Save(ctx, pending))
+
+		enabled := pending
+		enabled.Enabled = true
+		enabled.RecoveryCodes = []string{"hash3"}
+		enabled.LastStep = 9
+		assert.Nil(t, repo.Save(ctx, enabled))
+		assert.Equal(t, enabled, get(t, repo, pending.UserID))
+	})
+
+	t.Run("delete leaves the others", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Save(ctx, pending))
+		assert.Nil(t, repo.Save(ctx, other))
+
+		assert.Nil(t, repo.Delete(ctx, pending.UserID))
+		_, err := repo.Get(ctx, pending.UserID)
+		assert.ErrorIs(t, err, user.ErrMFANotFound)
+		assert.Equal(t, other, get(t, repo, other.UserID))
+
+		// Nothing to delete isn't an error
+		assert.Nil(t, repo.Delete(ctx, pending.UserID))
+	})
+}
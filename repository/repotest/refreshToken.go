@@ -0,0 +1,86 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/stretchr/testify/assert"
+)
+
+// RefreshTokenRepository runs the user.RefreshTokenRepository scenarios,
+// newRepo must return an empty repository on every call.
+func RefreshTokenRepository(t *testing.T, newRepo func(t *testing.T) user.RefreshTokenRepository) {
+	ctx := context.Background()
+	// Rounded, not every store keeps nanoseconds or the location
+	now := time.Now().UTC().Truncate(time.Second)
+	first := user.RefreshToken{ID: "t1", FamilyID: "f1", UserID: "u1", TokenHash: "hash1", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	second := user.RefreshToken{ID: "t2", FamilyID: "f1", UserID: "u1", TokenHash: "hash2", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	other := user.RefreshToken{ID: "t3", FamilyID: "f2", UserID: "u1", TokenHash: "hash3", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	otherUser := user.RefreshToken{ID: "t4", FamilyID: "f3", UserID: "u2", TokenHash: "hash4", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+
+	create := func(t *testing.T, repo user.RefreshTokenRepository, tokens ...user.RefreshToken) {
+		t.Helper()
+		for _, token := range tokens {
+			if err := repo.Create(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	get := func(t *testing.T, repo user.RefreshTokenRepository, hash string) user.RefreshToken {
+		t.Helper()
+		token, err := repo.GetByHash(ctx, hash)
+		assert.Nil(t, err)
+		token.ExpiresAt = token.ExpiresAt.UTC()
+		token.CreatedAt = token.CreatedAt.UTC()
+		return token
+	}
+
+	t.Run("create and read by hash", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, first)
+
+		assert.Equal(t, first, get(t, repo, first.TokenHash))
+
+		_, err := repo.GetByHash(ctx, "unknown")
+		assert.ErrorIs(t, err, user.ErrRefreshTokenNotFound)
+	})
+
+	t.Run("a token is marked used once", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, first)
+
+		ok, err := repo.MarkUsed(ctx, first.ID)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.True(t, get(t, repo, first.TokenHash).Used)
+
+		ok, err = repo.MarkUsed(ctx, first.ID)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+
+		ok, err = repo.MarkUsed(ctx, "unknown")
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("revoking a family leaves the others", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, first, second, other)
+
+		assert.Nil(t, repo.RevokeFamily(ctx, first.FamilyID))
+		assert.True(t, get(t, repo, first.TokenHash).Revoked)
+		assert.True(t, get(t, repo, second.TokenHash).Revoked)
+		assert.False(t, get(t, repo, other.TokenHash).Revoked)
+	})
+	t.Run("revoking a user leaves the other users", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, first, other, otherUser)
+
+		assert.Nil(t, repo.RevokeUser(ctx, first.UserID))
+		assert.True(t, get(t, repo, first.TokenHash).Revoked)
+		assert.True(t, get(t, repo, other.TokenHash).Revoked)
+		assert.False(t, get(t, repo, otherUser.TokenHash).Revoked)
+	})
+}
@@ -0,0 +1,126 @@
+// Package repotest holds the repository conformance suites, the scenarios
+// every backend of a repository has to pass, and the stores to run them on.
+//
+// SQLite runs in-process, MySQL, Postgres and Mongo only when their env var
+// points at a server, e.g. a local container:
+//
+//	TEST_MYSQL_DSN="root:secret@tcp(localhost:3306)/belajargo_test?parseTime=True"
+//	TEST_POSTGRES_DSN="host=localhost user=postgres password=secret dbname=belajargo_test sslmode=disable"
+//	TEST_MONGO_URI="mongodb://localhost:27017"
+//
+// The MySQL and Postgres databases are migrated and emptied, use throwaway ones.
+package repotest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var tables = []string{
+	"bg_inventories",
+	"bg_inventory_tags",
+	"bg_saved_searches",
+	"bg_users",
+	"bg_refresh_tokens",
+	"bg_user_mfa",
+	"bg_roles",
+	"bg_loans",
+	"bg_work_orders",
+}
+
+// SQLite returns a migrated SQLite database in a temporary file.
+func SQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	databaseConfig := database.Config{
+		DBDriver:     "sqlite",
+		DBSQLiteName: filepath.Join(t.TempDir(), "test.db"),
+	}
+	db, err := databaseConfig.GetDatabaseConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrate(t, db, "sqlite")
+	return db
+}
+
+// MySQL returns the migrated and emptied database of TEST_MYSQL_DSN, the test
+// is skipped when it isn't set.
+func MySQL(t *testing.T) *gorm.DB {
+	t.Helper()
+	return openGorm(t, "mysql", "TEST_MYSQL_DSN", mysql.Open)
+}
+
+// Postgres returns the migrated and emptied database of TEST_POSTGRES_DSN,
+// the test is skipped when it isn't set.
+func Postgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	return openGorm(t, "postgres", "TEST_POSTGRES_DSN", postgres.Open)
+}
+
+// Mongo returns a new migrated database on the server of TEST_MONGO_URI,
+// dropped when the test ends. The test is skipped when it isn't set.
+func Mongo(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI not set")
+	}
+
+	databaseConfig := database.Config{
+		DBMongoURI:  uri,
+		DBMongoName: "belajargo_test_" + uuid.NewString()[:8],
+	}
+	db, err := databaseConfig.GetNoSQLDatabaseConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Drop(context.Background())
+		_ = db.Client().Disconnect(context.Background())
+	})
+
+	if _, err := database.NewMongoMigrator(db, database.MongoMigrations()).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func openGorm(t *testing.T, driver string, envName string, open func(dsn string) gorm.Dialector) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(envName)
+	if dsn == "" {
+		t.Skip(envName + " not set")
+	}
+
+	db, err := gorm.Open(open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrate(t, db, driver)
+	for _, table := range tables {
+		if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func migrate(t *testing.T, db *gorm.DB, driver string) {
+	t.Helper()
+	migrations, err := database.Migrations(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.NewMigrator(db, migrations).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	"github.com/stretchr/testify/assert"
+)
+
+// RoleRepository runs the rbac.Repository scenarios, newRepo must return an
+// empty repository on every call.
+func RoleRepository(t *testing.T, newRepo func(t *testing.T) rbac.Repository) {
+	ctx := context.Background()
+	// Rounded, not every store keeps nanoseconds or the location
+	now := time.Now().UTC().Truncate(time.Second)
+	admin := rbac.Role{Name: "admin", Permissions: []string{rbac.InventoryRead, rbac.InventoryWrite}, UpdatedAt: now}
+	user := rbac.Role{Name: "user", Permissions: []string{}, UpdatedAt: now}
+
+	utc := func(roles ...rbac.Role) []rbac.Role {
+		for i := range roles {
+			roles[i].UpdatedAt = roles[i].UpdatedAt.UTC()
+		}
+		return roles
+	}
+
+	t.Run("save and read", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Save(ctx, admin))
+
+		role, err := repo.Get(ctx, admin.Name)
+		assert.Nil(t, err)
+		assert.Equal(t, utc(admin), utc(role))
+
+		_, err = repo.Get(ctx, "unknown")
+		assert.ErrorIs(t, err, rbac.ErrRoleNotFound)
+	})
+
+	t.Run("save replaces", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Save(ctx, admin))
+
+		edited := admin
+		edited.Permissions = []string{rbac.InventoryRead}
+		edited.UpdatedAt = now.Add(time.Minute)
+		assert.Nil(t, repo.Save(ctx, edited))
+
+		role, err := repo.Get(ctx, admin.Name)
+		assert.Nil(t, err)
+		assert.Equal(t, utc(edited), utc(role))
+	})
+
+	t.Run("get all by name", func(t *testing.T) {
+		repo := newRepo(t)
+		roles, err := repo.GetAll(ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, roles)
+
+		assert.Nil(t, repo.Save(ctx, user))
+		assert.Nil(t, repo.Save(ctx, admin))
+		roles, err = repo.GetAll(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, utc(admin, user), utc(roles...))
+	})
+}
@@ -0,0 +1,201 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/stretchr/testify/assert"
+)
+
+// UserRepository runs the user.Repository scenarios, newRepo must return an
+// empty repository on every call.
+func UserRepository(t *testing.T, newRepo func(t *testing.T) user.Repository) {
+	ctx := context.Background()
+	admin := user.User{ID: "u1", Email: "admin@example.com", Password: "hash1", Fullname: "Admin", Role: "admin"}
+	member := user.User{ID: "u2", Email: "user@example.com", Password: "hash2", Fullname: "User", Role: "user", IsEmailVerified: true}
+
+	t.Run("create and read by id or email", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, admin))
+		assert.Nil(t, repo.Create(ctx, member))
+
+		got, err := repo.GetByID(ctx, admin.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, admin, got)
+
+		got, err = repo.GetByEmail(ctx, member.Email)
+		assert.Nil(t, err)
+		assert.Equal(t, member, got)
+
+		_, err = repo.GetByID(ctx, "u9")
+		assert.ErrorIs(t, err, user.ErrNotFound)
+		_, err = repo.GetByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("emails and ids are unique", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, admin))
+
+		sameEmail := member
+		sameEmail.Email = admin.Email
+		assert.ErrorIs(t, repo.Create(ctx, sameEmail), user.ErrEmailRegistered)
+
+		sameID := member
+		sameID.ID = admin.ID
+		assert.NotNil(t, repo.Create(ctx, sameID))
+	})
+
+	t.Run("update email verification only changes the flag", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, admin))
+
+		changed := admin
+		changed.IsEmailVerified = true
+		changed.Password = "other"
+		changed.Role = "superadmin"
+		assert.Nil(t, repo.UpdateEmailVerification(ctx, changed))
+
+		got, err := repo.GetByID(ctx, admin.ID)
+		assert.Nil(t, err)
+		want := admin
+		want.IsEmailVerified = true
+		assert.Equal(t, want, got)
+
+		changed.IsEmailVerified = false
+		assert.Nil(t, repo.UpdateEmailVerification(ctx, changed))
+		got, err = repo.GetByID(ctx, admin.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, admin, got)
+	})
+
+	t.Run("update password only changes the password", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, admin))
+
+		changed := admin
+		changed.Password = "other"
+		changed.Role = "superadmin"
+		changed.IsEmailVerified = true
+		assert.Nil(t, repo.UpdatePassword(ctx, changed))
+
+		got, err := repo.GetByID(ctx, admin.ID)
+		assert.Nil(t, err)
+		want := admin
+		want.Password = "other"
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("update verification email only changes its status", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, admin))
+
+		// Rounded, not every store keeps nanoseconds or the location
+		sentAt := time.Now().UTC().Truncate(time.Second)
+		changed := admin
+		changed.Password = "other"
+		changed.VerificationEmailStatus = user.VerificationEmailFailed
+		changed.VerificationEmailSentAt = &sentAt
+		assert.Nil(t, repo.UpdateVerificationEmail(ctx, changed))
+
+		got, err := repo.GetByID(ctx, admin.ID)
+		assert.Nil(t, err)
+		if assert.NotNil(t, got.VerificationEmailSentAt) {
+			assert.True(t, sentAt.Equal(*got.VerificationEmailSentAt))
+		}
+		got.VerificationEmailSentAt = nil
+		want := admin
+		want.VerificationEmailStatus = user.VerificationEmailFailed
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("read all filters, sorts and pages", func(t *testing.T) {
+		repo := newRepo(t)
+		superadmin := user.User{ID: "u3", Email: "boss@example.com", Password: "hash3", Fullname: "Big Boss", Role: "superadmin"}
+		for _, u := range []user.User{admin, member, superadmin} {
+			assert.Nil(t, repo.Create(ctx, u))
+		}
+
+		got, err := repo.ReadAll(ctx, user.Filter{}, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []user.User{admin, superadmin, member}, got)
+
+		got, err = repo.ReadAll(ctx, user.Filter{}, 2, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []user.User{member}, got)
+
+		// Any part of the email or the fullname, whatever the case
+		got, err = repo.ReadAll(ctx, user.Filter{Search: "BOSS"}, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []user.User{superadmin}, got)
+		got, err = repo.ReadAll(ctx, user.Filter{Search: "user@"}, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []user.User{member}, got)
+
+		got, err = repo.ReadAll(ctx, user.Filter{Search: "example", Role: "admin"}, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []user.User{admin}, got)
+
+		got, err = repo.ReadAll(ctx, user.Filter{Role: "nobody"}, 1, 10)
+		assert.Nil(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("update role and deactivation only change them", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, member))
+
+		deactivatedAt := time.Now().UTC().Truncate(time.Second)
+		changed := member
+		changed.Password = "other"
+		changed.Role = "admin"
+		changed.DeactivatedAt = &deactivatedAt
+		assert.Nil(t, repo.UpdateRole(ctx, changed))
+		assert.Nil(t, repo.UpdateDeactivation(ctx, changed))
+
+		got, err := repo.GetByID(ctx, member.ID)
+		assert.Nil(t, err)
+		if assert.NotNil(t, got.DeactivatedAt) {
+			assert.True(t, deactivatedAt.Equal(*got.DeactivatedAt))
+		}
+		got.DeactivatedAt = nil
+		want := member
+		want.Role = "admin"
+		assert.Equal(t, want, got)
+
+		// Activated again
+		changed.DeactivatedAt = nil
+		assert.Nil(t, repo.UpdateDeactivation(ctx, changed))
+		got, err = repo.GetByID(ctx, member.ID)
+		assert.Nil(t, err)
+		assert.Nil(t, got.DeactivatedAt)
+	})
+
+	t.Run("update fullname and email only change them", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.Nil(t, repo.Create(ctx, admin))
+		assert.Nil(t, repo.Create(ctx, member))
+
+		changed := member
+		changed.Fullname = "Renamed"
+		changed.Email = "renamed@example.com"
+		changed.Role = "admin"
+		assert.Nil(t, repo.UpdateFullname(ctx, changed))
+		assert.Nil(t, repo.UpdateEmail(ctx, changed))
+
+		got, err := repo.GetByEmail(ctx, changed.Email)
+		assert.Nil(t, err)
+		want := member
+		want.Fullname = "Renamed"
+		want.Email = "renamed@example.com"
+		assert.Equal(t, want, got)
+		_, err = repo.GetByEmail(ctx, member.Email)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		// Emails stay unique
+		changed.Email = admin.Email
+		assert.ErrorIs(t, repo.UpdateEmail(ctx, changed), user.ErrEmailRegistered)
+	})
+}
@@ -0,0 +1,26 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"gorm.io/gorm"
+)
+
+type GormTransactor struct {
+	*gorm.DB
+}
+
+func NewGormTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{db}
+}
+
+func (t *GormTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := database.TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	return t.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(database.ContextWithTx(ctx, tx))
+	})
+}
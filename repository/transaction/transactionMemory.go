@@ -0,0 +1,50 @@
+package transaction
+
+import (
+	"context"
+	"sync"
+)
+
+// Snapshotter is a store that can copy its state and put it back later, the
+// memory repositories are.
+type Snapshotter interface {
+	Snapshot() (restore func())
+}
+
+// MemoryTransactor is the unit of work of the memory repositories. Units of
+// work run one at a time and a failed one restores the stores to how they were
+// before it started. There is no isolation: a write made outside any unit of
+// work while one is rolled back is lost with it, fine for demos and tests.
+type MemoryTransactor struct {
+	mu     sync.Mutex
+	stores []Snapshotter
+}
+
+type memoryTxKey struct{}
+
+func NewMemoryTransactor(stores ...Snapshotter) *MemoryTransactor {
+	return &MemoryTransactor{
+		stores: stores,
+	}
+}
+
+func (t *MemoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if owner, ok := ctx.Value(memoryTxKey{}).(*MemoryTransactor); ok && owner == t {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	restores := make([]func(), 0, len(t.stores))
+	for _, store := range t.stores {
+		restores = append(restores, store.Snapshot())
+	}
+
+	if err = fn(context.WithValue(ctx, memoryTxKey{}, t)); err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+	return
+}
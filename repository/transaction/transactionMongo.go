@@ -0,0 +1,37 @@
+package transaction
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoTransactor struct {
+	client *mongo.Client
+}
+
+func NewMongoTransactor(db *mongo.Database) *MongoTransactor {
+	return &MongoTransactor{
+		client: db.Client(),
+	}
+}
+
+// WithinTransaction needs the server to run as a replica set, standalone
+// servers don't support multi-document transactions. The session travels in
+// the context handed to fn, so the Mongo repositories join it as they are.
+func (t *MongoTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return
+}
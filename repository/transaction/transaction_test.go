@@ -0,0 +1,183 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	invRepo "github.com/pobyzaarif/belajarGo2/repository/inventory"
+	"github.com/pobyzaarif/belajarGo2/repository/repotest"
+	"github.com/pobyzaarif/belajarGo2/repository/transaction"
+	userRepo "github.com/pobyzaarif/belajarGo2/repository/user"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGormTransactor(t *testing.T) {
+	errSomething := errors.New("something went wrong")
+	newUser := user.User{ID: "u1", Email: "test@example.com", Password: "x", Fullname: "Test", Role: "user"}
+	newInv := inventory.Inventory{Code: "TX001", Name: "Cable", Stock: 3, Status: "active"}
+
+	tests := []struct {
+		name       string
+		fn         func(ctx context.Context, tr *transaction.GormTransactor, users *userRepo.GormRepository, invs *invRepo.GormRepository) error
+		wantErr    error
+		wantCommit bool
+	}{
+		{
+			name: "commit writes of several repositories",
+			fn: func(ctx context.Context, tr *transaction.GormTransactor, users *userRepo.GormRepository, invs *invRepo.GormRepository) error {
+				if err := users.Create(ctx, newUser); err != nil {
+					return err
+				}
+				return invs.Create(ctx, newInv)
+			},
+			wantErr:    nil,
+			wantCommit: true,
+		},
+		{
+			name: "rollback writes of several repositories when fn fails",
+			fn: func(ctx context.Context, tr *transaction.GormTransactor, users *userRepo.GormRepository, invs *invRepo.GormRepository) error {
+				if err := users.Create(ctx, newUser); err != nil {
+					return err
+				}
+				if err := invs.Create(ctx, newInv); err != nil {
+					return err
+				}
+				return errSomething
+			},
+			wantErr:    errSomething,
+			wantCommit: false,
+		},
+		{
+			name: "rollback when a repository fails",
+			fn: func(ctx context.Context, tr *transaction.GormTransactor, users *userRepo.GormRepository, invs *invRepo.GormRepository) error {
+				if err := users.Create(ctx, newUser); err != nil {
+					return err
+				}
+				if err := invs.Create(ctx, newInv); err != nil {
+					return err
+				}
+				return invs.Create(ctx, newInv)
+			},
+			wantErr:    inventory.ErrConflict,
+			wantCommit: false,
+		},
+		{
+			name: "nested unit of work joins the outer one",
+			fn: func(ctx context.Context, tr *transaction.GormTransactor, users *userRepo.GormRepository, invs *invRepo.GormRepository) error {
+				if err := users.Create(ctx, newUser); err != nil {
+					return err
+				}
+				if err := tr.WithinTransaction(ctx, func(ctx context.Context) error {
+					return invs.Create(ctx, newInv)
+				}); err != nil {
+					return err
+				}
+				return errSomething
+			},
+			wantErr:    errSomething,
+			wantCommit: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repotest.SQLite(t)
+			tr := transaction.NewGormTransactor(db)
+			users := userRepo.NewGormRepository(db)
+			invs := invRepo.NewGormRepository(db)
+
+			ctx := context.Background()
+			err := tr.WithinTransaction(ctx, func(ctx context.Context) error {
+				return tt.fn(ctx, tr, users, invs)
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			_, errUser := users.GetByID(ctx, newUser.ID)
+			_, errInv := invs.ReadByCode(ctx, newInv.Code)
+			if tt.wantCommit {
+				assert.Nil(t, errUser)
+				assert.Nil(t, errInv)
+			} else {
+				assert.ErrorIs(t, errUser, user.ErrNotFound)
+				assert.ErrorIs(t, errInv, inventory.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestGormTransactorRollbackDelete(t *testing.T) {
+	db := repotest.SQLite(t)
+	tr := transaction.NewGormTransactor(db)
+	invs := invRepo.NewGormRepository(db)
+
+	ctx := context.Background()
+	assert.Nil(t, invs.Create(ctx, inventory.Inventory{Code: "INV001", Name: "Laptop", Stock: 25, Status: "active"}))
+	assert.Nil(t, invs.AddTags(ctx, "INV001", []string{"it"}))
+
+	errSomething := errors.New("something went wrong")
+	err := tr.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := invs.Delete(ctx, "INV001"); err != nil {
+			return err
+		}
+		return errSomething
+	})
+	assert.ErrorIs(t, err, errSomething)
+
+	inv, err := invs.ReadByCode(ctx, "INV001")
+	assert.Nil(t, err)
+
+	got, err := invs.ReadAll(ctx, inventory.Filter{Tags: []string{"it"}}, 1, 10)
+	assert.Nil(t, err)
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, inv.Code, got[0].Code)
+		assert.Equal(t, []string{"it"}, got[0].Tags)
+	}
+}
+
+func TestMemoryTransactor(t *testing.T) {
+	users := userRepo.NewMemoryRepository()
+	invs := invRepo.NewMemoryRepository()
+	tr := transaction.NewMemoryTransactor(users, invs)
+
+	ctx := context.Background()
+	assert.Nil(t, invs.Create(ctx, inventory.Inventory{Code: "INV001", Name: "Laptop", Stock: 25, Status: "active"}))
+	assert.Nil(t, invs.AddTags(ctx, "INV001", []string{"it"}))
+
+	errSomething := errors.New("something went wrong")
+	err := tr.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := users.Create(ctx, user.User{ID: "u1", Email: "test@example.com", Role: "user"}); err != nil {
+			return err
+		}
+		if err := invs.RemoveTags(ctx, "INV001", []string{"it"}); err != nil {
+			return err
+		}
+		// Nested units of work join the outer one instead of deadlocking
+		return tr.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := invs.Delete(ctx, "INV001"); err != nil {
+				return err
+			}
+			return errSomething
+		})
+	})
+	assert.ErrorIs(t, err, errSomething)
+
+	_, err = users.GetByID(ctx, "u1")
+	assert.ErrorIs(t, err, user.ErrNotFound)
+	inv, err := invs.ReadByCode(ctx, "INV001")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"it"}, inv.Tags)
+
+	err = tr.WithinTransaction(ctx, func(ctx context.Context) error {
+		return users.Create(ctx, user.User{ID: "u1", Email: "test@example.com", Role: "user"})
+	})
+	assert.Nil(t, err)
+	_, err = users.GetByID(ctx, "u1")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,38 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"gorm.io/gorm"
+)
+
+type (
+	GormMFARepository struct {
+		*gorm.DB
+	}
+)
+
+func NewGormMFARepository(db *gorm.DB) *GormMFARepository {
+	return &GormMFARepository{
+		db.Table("bg_user_mfa"),
+	}
+}
+
+func (r *GormMFARepository) Get(ctx context.Context, userID string) (mfa user.MFA, err error) {
+	err = database.Conn(ctx, r.DB).First(&mfa, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return mfa, user.ErrMFANotFound
+	}
+	return
+}
+
+func (r *GormMFARepository) Save(ctx context.Context, mfa user.MFA) (err error) {
+	return database.Conn(ctx, r.DB).Save(&mfa).Error
+}
+
+func (r *GormMFARepository) Delete(ctx context.Context, userID string) (err error) {
+	return database.Conn(ctx, r.DB).Where("user_id = ?", userID).Delete(&user.MFA{}).Error
+}
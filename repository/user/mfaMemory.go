@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+)
+
+// MemoryMFARepository keeps the two-factor authentication of users in process
+// memory, for demos and tests.
+type MemoryMFARepository struct {
+	mu  sync.RWMutex
+	mfa map[string]user.MFA
+}
+
+func NewMemoryMFARepository() *MemoryMFARepository {
+	return &MemoryMFARepository{
+		mfa: map[string]user.MFA{},
+	}
+}
+
+func (r *MemoryMFARepository) Get(ctx context.Context, userID string) (mfa user.MFA, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mfa, ok := r.mfa[userID]
+	if !ok {
+		return mfa, user.ErrMFANotFound
+	}
+	mfa.RecoveryCodes = slices.Clone(mfa.RecoveryCodes)
+	return mfa, nil
+}
+
+func (r *MemoryMFARepository) Save(ctx context.Context, mfa user.MFA) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mfa.RecoveryCodes = slices.Clone(mfa.RecoveryCodes)
+	r.mfa[mfa.UserID] = mfa
+	return nil
+}
+
+func (r *MemoryMFARepository) Delete(ctx context.Context, userID string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.mfa, userID)
+	return nil
+}
+
+// Snapshot copies the current state, calling restore puts it back. The
+// memory transactor uses it to roll a unit of work back.
+func (r *MemoryMFARepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	mfa := maps.Clone(r.mfa)
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.mfa = mfa
+	}
+}
+
+func (r *MemoryMFARepository) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mfa := slices.AppendSeq(make([]user.MFA, 0, len(r.mfa)), maps.Values(r.mfa))
+	sort.Slice(mfa, func(i, j int) bool { return mfa[i].UserID < mfa[j].UserID })
+	return json.Marshal(mfa)
+}
+
+func (r *MemoryMFARepository) UnmarshalJSON(data []byte) error {
+	var mfa []user.MFA
+	if err := json.Unmarshal(data, &mfa); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mfa = make(map[string]user.MFA, len(mfa))
+	for _, m := range mfa {
+		r.mfa[m.UserID] = m
+	}
+	return nil
+}
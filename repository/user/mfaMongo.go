@@ -0,0 +1,40 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoMFARepository struct {
+	col *mongo.Collection
+}
+
+func NewMongoMFARepository(db *mongo.Database) *MongoMFARepository {
+	// Indexes are created by the Mongo migrations (util/database), not here
+	return &MongoMFARepository{
+		col: db.Collection("user_mfa"),
+	}
+}
+
+func (r *MongoMFARepository) Get(ctx context.Context, userID string) (mfa user.MFA, err error) {
+	err = r.col.FindOne(ctx, bson.M{"user_id": userID}).Decode(&mfa)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return mfa, user.ErrMFANotFound
+	}
+	return
+}
+
+func (r *MongoMFARepository) Save(ctx context.Context, mfa user.MFA) (err error) {
+	_, err = r.col.ReplaceOne(ctx, bson.M{"user_id": mfa.UserID}, mfa, options.Replace().SetUpsert(true))
+	return
+}
+
+func (r *MongoMFARepository) Delete(ctx context.Context, userID string) (err error) {
+	_, err = r.col.DeleteOne(ctx, bson.M{"user_id": userID})
+	return
+}
@@ -0,0 +1,47 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"gorm.io/gorm"
+)
+
+type (
+	GormRefreshTokenRepository struct {
+		*gorm.DB
+	}
+)
+
+func NewGormRefreshTokenRepository(db *gorm.DB) *GormRefreshTokenRepository {
+	return &GormRefreshTokenRepository{
+		db.Table("bg_refresh_tokens"),
+	}
+}
+
+func (r *GormRefreshTokenRepository) Create(ctx context.Context, token user.RefreshToken) (err error) {
+	return database.Conn(ctx, r.DB).Create(&token).Error
+}
+
+func (r *GormRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (token user.RefreshToken, err error) {
+	err = database.Conn(ctx, r.DB).First(&token, "token_hash = ?", hash).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return token, user.ErrRefreshTokenNotFound
+	}
+	return
+}
+
+func (r *GormRefreshTokenRepository) MarkUsed(ctx context.Context, id string) (ok bool, err error) {
+	result := database.Conn(ctx, r.DB).Where("id = ? AND used = ?", id, false).Update("used", true)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *GormRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) (err error) {
+	return database.Conn(ctx, r.DB).Where("family_id = ?", familyID).Update("revoked", true).Error
+}
+
+func (r *GormRefreshTokenRepository) RevokeUser(ctx context.Context, userID string) (err error) {
+	return database.Conn(ctx, r.DB).Where("user_id = ?", userID).Update("revoked", true).Error
+}
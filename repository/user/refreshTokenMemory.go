@@ -0,0 +1,122 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+)
+
+// MemoryRefreshTokenRepository keeps refresh tokens in process memory, for
+// demos and tests.
+type MemoryRefreshTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]user.RefreshToken
+}
+
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{
+		tokens: map[string]user.RefreshToken{},
+	}
+}
+
+func (r *MemoryRefreshTokenRepository) Create(ctx context.Context, token user.RefreshToken) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.ID] = token
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (token user.RefreshToken, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return token, user.ErrRefreshTokenNotFound
+}
+
+func (r *MemoryRefreshTokenRepository) MarkUsed(ctx context.Context, id string) (ok bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, found := r.tokens[id]
+	if !found || token.Used {
+		return false, nil
+	}
+	token.Used = true
+	r.tokens[id] = token
+	return true, nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeUser(ctx context.Context, userID string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+// Snapshot copies the current state, calling restore puts it back. The
+// memory transactor uses it to roll a unit of work back.
+func (r *MemoryRefreshTokenRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	tokens := maps.Clone(r.tokens)
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.tokens = tokens
+	}
+}
+
+func (r *MemoryRefreshTokenRepository) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := slices.AppendSeq(make([]user.RefreshToken, 0, len(r.tokens)), maps.Values(r.tokens))
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return json.Marshal(tokens)
+}
+
+func (r *MemoryRefreshTokenRepository) UnmarshalJSON(data []byte) error {
+	var tokens []user.RefreshToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = make(map[string]user.RefreshToken, len(tokens))
+	for _, token := range tokens {
+		r.tokens[token.ID] = token
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoRefreshTokenRepository struct {
+	col *mongo.Collection
+}
+
+func NewMongoRefreshTokenRepository(db *mongo.Database) *MongoRefreshTokenRepository {
+	// Indexes are created by the Mongo migrations (util/database), not here
+	return &MongoRefreshTokenRepository{
+		col: db.Collection("refresh_tokens"),
+	}
+}
+
+func (r *MongoRefreshTokenRepository) Create(ctx context.Context, token user.RefreshToken) (err error) {
+	_, err = r.col.InsertOne(ctx, token)
+	return
+}
+
+func (r *MongoRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (token user.RefreshToken, err error) {
+	err = r.col.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, user.ErrRefreshTokenNotFound
+	}
+	return
+}
+
+func (r *MongoRefreshTokenRepository) MarkUsed(ctx context.Context, id string) (ok bool, err error) {
+	result, err := r.col.UpdateOne(ctx, bson.M{"token_id": id, "used": false}, bson.M{"$set": bson.M{"used": true}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) (err error) {
+	_, err = r.col.UpdateMany(ctx, bson.M{"family_id": familyID}, bson.M{"$set": bson.M{"revoked": true}})
+	return
+}
+
+func (r *MongoRefreshTokenRepository) RevokeUser(ctx context.Context, userID string) (err error) {
+	_, err = r.col.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"revoked": true}})
+	return
+}
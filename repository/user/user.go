@@ -2,8 +2,12 @@ package user
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
 	"gorm.io/gorm"
 )
 
@@ -19,16 +23,82 @@ func NewGormRepository(db *gorm.DB) *GormRepository {
 	}
 }
 
-func (r *GormRepository) Create(user user.User) (err error) {
-	return r.DB.WithContext(context.Background()).Create(&user).Error
+func (r *GormRepository) Create(ctx context.Context, u user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Create(&u).Error
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", user.ErrEmailRegistered, err)
+	}
+	return
+}
+
+func (r *GormRepository) GetByID(ctx context.Context, id string) (u user.User, err error) {
+	err = database.Conn(ctx, r.DB).First(&u, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return u, user.ErrNotFound
+	}
+	return
+}
+
+// GetByEmail reads from the primary, a lagging replica would let login and the
+// password reset check an old password or lockout.
+func (r *GormRepository) GetByEmail(ctx context.Context, email string) (u user.User, err error) {
+	err = database.Conn(ctx, r.DB).First(&u, "email = ?", email).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return u, user.ErrNotFound
+	}
+	return
+}
+
+func (r *GormRepository) ReadAll(ctx context.Context, filter user.Filter, page int, limit int) (users []user.User, err error) {
+	query := database.Read(ctx, r.DB)
+	if filter.Search != "" {
+		search := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(fullname) LIKE ?", search, search)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	err = query.Order("email ASC").Offset((page - 1) * limit).Limit(limit).Find(&users).Error
+	return
 }
 
-func (r *GormRepository) GetByEmail(email string) (user user.User, err error) {
-	r.DB.WithContext(context.Background()).First(&user, "email = ?", email)
+func (r *GormRepository) UpdateEmailVerification(ctx context.Context, user user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", user.ID).Update("is_email_verified", user.IsEmailVerified).Error
 	return
 }
 
-func (r *GormRepository) UpdateEmailVerification(user user.User) (err error) {
-	err = r.DB.WithContext(context.Background()).Updates(&user).Error
+func (r *GormRepository) UpdateVerificationEmail(ctx context.Context, user user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"verification_email_status":  user.VerificationEmailStatus,
+		"verification_email_sent_at": user.VerificationEmailSentAt,
+	}).Error
+	return
+}
+
+func (r *GormRepository) UpdatePassword(ctx context.Context, user user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", user.ID).Update("password", user.Password).Error
+	return
+}
+
+func (r *GormRepository) UpdateRole(ctx context.Context, user user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", user.ID).Update("role", user.Role).Error
+	return
+}
+
+func (r *GormRepository) UpdateDeactivation(ctx context.Context, user user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", user.ID).Update("deactivated_at", user.DeactivatedAt).Error
+	return
+}
+
+func (r *GormRepository) UpdateFullname(ctx context.Context, user user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", user.ID).Update("fullname", user.Fullname).Error
+	return
+}
+
+func (r *GormRepository) UpdateEmail(ctx context.Context, u user.User) (err error) {
+	err = database.Conn(ctx, r.DB).Where("id = ?", u.ID).Update("email", u.Email).Error
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", user.ErrEmailRegistered, err)
+	}
 	return
 }
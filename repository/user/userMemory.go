@@ -0,0 +1,223 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+)
+
+// MemoryRepository keeps users in process memory, for demos and tests. Like
+// the bg_users table, ids and emails are unique.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]user.User
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users: map[string]user.User{},
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[u.ID]; ok {
+		return user.ErrEmailRegistered
+	}
+	if _, ok := r.findByEmail(u.Email); ok {
+		return user.ErrEmailRegistered
+	}
+	r.users[u.ID] = u
+	return nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (u user.User, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return u, user.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *MemoryRepository) GetByEmail(ctx context.Context, email string) (u user.User, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.findByEmail(email)
+	if !ok {
+		return u, user.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *MemoryRepository) ReadAll(ctx context.Context, filter user.Filter, page int, limit int) (users []user.User, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	for _, u := range r.users {
+		if filter.Role != "" && u.Role != filter.Role {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(u.Email), search) && !strings.Contains(strings.ToLower(u.Fullname), search) {
+			continue
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return database.Page(users, page, limit), nil
+}
+
+func (r *MemoryRepository) UpdateEmailVerification(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.IsEmailVerified = u.IsEmailVerified
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) UpdateVerificationEmail(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.VerificationEmailStatus = u.VerificationEmailStatus
+	stored.VerificationEmailSentAt = u.VerificationEmailSentAt
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) UpdatePassword(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.Password = u.Password
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) UpdateRole(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.Role = u.Role
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) UpdateDeactivation(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.DeactivatedAt = u.DeactivatedAt
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) UpdateFullname(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	stored.Fullname = u.Fullname
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) UpdateEmail(ctx context.Context, u user.User) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+	if other, ok := r.findByEmail(u.Email); ok && other.ID != u.ID {
+		return user.ErrEmailRegistered
+	}
+	stored.Email = u.Email
+	r.users[u.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) findByEmail(email string) (u user.User, ok bool) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, true
+		}
+	}
+	return u, false
+}
+
+// Snapshot copies the current state, calling restore puts it back. The
+// memory transactor uses it to roll a unit of work back.
+func (r *MemoryRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	users := maps.Clone(r.users)
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.users = users
+	}
+}
+
+func (r *MemoryRepository) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := slices.AppendSeq(make([]user.User, 0, len(r.users)), maps.Values(r.users))
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return json.Marshal(users)
+}
+
+func (r *MemoryRepository) UnmarshalJSON(data []byte) error {
+	var users []user.User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = make(map[string]user.User, len(users))
+	for _, u := range users {
+		r.users[u.ID] = u
+	}
+	return nil
+}
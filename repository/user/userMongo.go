@@ -2,11 +2,16 @@ package user
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"fmt"
+	"regexp"
 
 	"github.com/pobyzaarif/belajarGo2/service/user"
+	"github.com/pobyzaarif/belajarGo2/util/database"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoRepository struct {
@@ -14,28 +19,97 @@ type MongoRepository struct {
 }
 
 func NewMongoRepository(db *mongo.Database) *MongoRepository {
+	// The unique email index is created by the Mongo migrations (util/database)
 	return &MongoRepository{
 		col: db.Collection("users"),
 	}
 }
 
-func (r *MongoRepository) Create(user user.User) (err error) {
-	_, err = r.col.InsertOne(context.Background(), user)
+func (r *MongoRepository) Create(ctx context.Context, u user.User) (err error) {
+	_, err = r.col.InsertOne(ctx, u)
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", user.ErrEmailRegistered, err)
+	}
+	return
+}
+
+func (r *MongoRepository) GetByID(ctx context.Context, id string) (u user.User, err error) {
+	err = r.col.FindOne(ctx, bson.M{"user_id": id}).Decode(&u)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return u, user.ErrNotFound
+	}
+	return
+}
+
+func (r *MongoRepository) GetByEmail(ctx context.Context, email string) (u user.User, err error) {
+	err = r.col.FindOne(ctx, bson.M{"email": email}).Decode(&u)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return u, user.ErrNotFound
+	}
 	return
 }
 
-func (r *MongoRepository) GetByEmail(email string) (user user.User, err error) {
-	err = r.col.FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
+func (r *MongoRepository) ReadAll(ctx context.Context, filter user.Filter, page int, limit int) (users []user.User, err error) {
+	query := bson.M{}
+	if filter.Search != "" {
+		search := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Search), Options: "i"}
+		query["$or"] = bson.A{bson.M{"email": search}, bson.M{"fullname": search}}
+	}
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "email", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := r.col.Find(ctx, query, opts)
 	if err != nil {
-		if strings.Contains(err.Error(), "no documents") {
-			err = nil
-			return
-		}
+		return
 	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &users)
+	return
+}
+
+func (r *MongoRepository) UpdateEmailVerification(ctx context.Context, user user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{"is_email_verified": user.IsEmailVerified}})
 	return
 }
 
-func (r *MongoRepository) UpdateEmailVerification(user user.User) (err error) {
-	_, err = r.col.UpdateOne(context.Background(), bson.M{"email": user.Email}, bson.M{"$set": user})
+func (r *MongoRepository) UpdateVerificationEmail(ctx context.Context, user user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{
+		"verification_email_status":  user.VerificationEmailStatus,
+		"verification_email_sent_at": user.VerificationEmailSentAt,
+	}})
+	return
+}
+
+func (r *MongoRepository) UpdatePassword(ctx context.Context, user user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{"password": user.Password}})
+	return
+}
+
+func (r *MongoRepository) UpdateRole(ctx context.Context, user user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{"role": user.Role}})
+	return
+}
+
+func (r *MongoRepository) UpdateDeactivation(ctx context.Context, user user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{"deactivated_at": user.DeactivatedAt}})
+	return
+}
+
+func (r *MongoRepository) UpdateFullname(ctx context.Context, user user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{"fullname": user.Fullname}})
+	return
+}
+
+func (r *MongoRepository) UpdateEmail(ctx context.Context, u user.User) (err error) {
+	_, err = r.col.UpdateOne(ctx, bson.M{"user_id": u.ID}, bson.M{"$set": bson.M{"email": u.Email}})
+	if database.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", user.ErrEmailRegistered, err)
+	}
 	return
 }
@@ -0,0 +1,82 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/pobyzaarif/belajarGo2/repository/repotest"
+	userRepo "github.com/pobyzaarif/belajarGo2/repository/user"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	backends := []struct {
+		name    string
+		newRepo func(t *testing.T) user.Repository
+	}{
+		{"sqlite", func(t *testing.T) user.Repository { return userRepo.NewGormRepository(repotest.SQLite(t)) }},
+		{"mysql", func(t *testing.T) user.Repository { return userRepo.NewGormRepository(repotest.MySQL(t)) }},
+		{"postgres", func(t *testing.T) user.Repository { return userRepo.NewGormRepository(repotest.Postgres(t)) }},
+		{"mongo", func(t *testing.T) user.Repository { return userRepo.NewMongoRepository(repotest.Mongo(t)) }},
+		{"memory", func(t *testing.T) user.Repository { return userRepo.NewMemoryRepository() }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repotest.UserRepository(t, backend.newRepo)
+		})
+	}
+}
+
+func TestRefreshTokenRepositoryConformance(t *testing.T) {
+	backends := []struct {
+		name    string
+		newRepo func(t *testing.T) user.RefreshTokenRepository
+	}{
+		{"sqlite", func(t *testing.T) user.RefreshTokenRepository {
+			return userRepo.NewGormRefreshTokenRepository(repotest.SQLite(t))
+		}},
+		{"mysql", func(t *testing.T) user.RefreshTokenRepository {
+			return userRepo.NewGormRefreshTokenRepository(repotest.MySQL(t))
+		}},
+		{"postgres", func(t *testing.T) user.RefreshTokenRepository {
+			return userRepo.NewGormRefreshTokenRepository(repotest.Postgres(t))
+		}},
+		{"mongo", func(t *testing.T) user.RefreshTokenRepository {
+			return userRepo.NewMongoRefreshTokenRepository(repotest.Mongo(t))
+		}},
+		{"memory", func(t *testing.T) user.RefreshTokenRepository { return userRepo.NewMemoryRefreshTokenRepository() }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repotest.RefreshTokenRepository(t, backend.newRepo)
+		})
+	}
+}
+
+func TestMFARepositoryConformance(t *testing.T) {
+	backends := []struct {
+		name    string
+		newRepo func(t *testing.T) user.MFARepository
+	}{
+		{"sqlite", func(t *testing.T) user.MFARepository {
+			return userRepo.NewGormMFARepository(repotest.SQLite(t))
+		}},
+		{"mysql", func(t *testing.T) user.MFARepository {
+			return userRepo.NewGormMFARepository(repotest.MySQL(t))
+		}},
+		{"postgres", func(t *testing.T) user.MFARepository {
+			return userRepo.NewGormMFARepository(repotest.Postgres(t))
+		}},
+		{"mongo", func(t *testing.T) user.MFARepository {
+			return userRepo.NewMongoMFARepository(repotest.Mongo(t))
+		}},
+		{"memory", func(t *testing.T) user.MFARepository { return userRepo.NewMemoryMFARepository() }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repotest.MFARepository(t, backend.newRepo)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package workorder
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/workorder"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"gorm.io/gorm"
+)
+
+type (
+	GormRepository struct {
+		*gorm.DB
+	}
+)
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{
+		db.Table("bg_work_orders"),
+	}
+}
+
+func (r *GormRepository) Create(ctx context.Context, wo workorder.WorkOrder) (err error) {
+	return database.Conn(ctx, r.DB).Create(&wo).Error
+}
+
+func (r *GormRepository) ReadAll(ctx context.Context, status string, page int, limit int) (wos []workorder.WorkOrder, err error) {
+	query := database.Conn(ctx, r.DB)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err = query.Order("opened_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&wos).Error
+	return
+}
+
+func (r *GormRepository) ReadByID(ctx context.Context, id string) (wo workorder.WorkOrder, err error) {
+	err = database.Conn(ctx, r.DB).First(&wo, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return wo, workorder.ErrNotFound
+	}
+	return
+}
+
+func (r *GormRepository) Update(ctx context.Context, wo workorder.WorkOrder) (err error) {
+	result := database.Conn(ctx, r.DB).Where("id = ? AND status = ?", wo.ID, workorder.StatusOpen).Updates(map[string]any{
+		"technician": wo.Technician,
+		"cost":       wo.Cost,
+		"notes":      wo.Notes,
+	})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	// MySQL doesn't count a row written with the values it already had
+	getWo, err := r.ReadByID(ctx, wo.ID)
+	if err != nil {
+		return
+	}
+	if getWo.Status == workorder.StatusClosed {
+		return workorder.ErrClosedAlready
+	}
+	return nil
+}
+
+func (r *GormRepository) Close(ctx context.Context, id string, closedAt time.Time) (err error) {
+	result := database.Conn(ctx, r.DB).Where("id = ? AND status = ?", id, workorder.StatusOpen).Updates(map[string]any{
+		"status":    workorder.StatusClosed,
+		"closed_at": closedAt,
+	})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	if _, err = r.ReadByID(ctx, id); err != nil {
+		return
+	}
+	return workorder.ErrClosedAlready
+}
+
+func (r *GormRepository) CountOpenByCode(ctx context.Context, code string) (count int64, err error) {
+	err = database.Conn(ctx, r.DB).Where("inventory_code = ? AND status = ?", code, workorder.StatusOpen).Count(&count).Error
+	return
+}
+
+func (r *GormRepository) ReadRepairCost(ctx context.Context) (report []workorder.RepairCost, err error) {
+	err = database.Conn(ctx, r.DB).
+		Select("inventory_code, COUNT(*) AS work_orders, COALESCE(SUM(cost), 0) AS total_cost").
+		Group("inventory_code").
+		Order("total_cost DESC").
+		Scan(&report).Error
+	return
+}
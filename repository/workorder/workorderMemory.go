@@ -0,0 +1,163 @@
+package workorder
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/workorder"
+	"github.com/pobyzaarif/belajarGo2/util/database"
+)
+
+// MemoryRepository keeps work orders in process memory, for demos and tests.
+type MemoryRepository struct {
+	mu         sync.RWMutex
+	workOrders map[string]workorder.WorkOrder
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		workOrders: map[string]workorder.WorkOrder{},
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, wo workorder.WorkOrder) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.workOrders[wo.ID] = wo
+	return nil
+}
+
+func (r *MemoryRepository) ReadAll(ctx context.Context, status string, page int, limit int) (wos []workorder.WorkOrder, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, wo := range r.workOrders {
+		if status == "" || wo.Status == status {
+			wos = append(wos, wo)
+		}
+	}
+	sort.Slice(wos, func(i, j int) bool { return wos[i].OpenedAt.After(wos[j].OpenedAt) })
+	return database.Page(wos, page, limit), nil
+}
+
+func (r *MemoryRepository) ReadByID(ctx context.Context, id string) (wo workorder.WorkOrder, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wo, ok := r.workOrders[id]
+	if !ok {
+		return wo, workorder.ErrNotFound
+	}
+	return wo, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, wo workorder.WorkOrder) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	getWo, ok := r.workOrders[wo.ID]
+	if !ok {
+		return workorder.ErrNotFound
+	}
+	if getWo.Status == workorder.StatusClosed {
+		return workorder.ErrClosedAlready
+	}
+	getWo.Technician = wo.Technician
+	getWo.Cost = wo.Cost
+	getWo.Notes = wo.Notes
+	r.workOrders[wo.ID] = getWo
+	return nil
+}
+
+func (r *MemoryRepository) Close(ctx context.Context, id string, closedAt time.Time) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wo, ok := r.workOrders[id]
+	if !ok {
+		return workorder.ErrNotFound
+	}
+	if wo.Status == workorder.StatusClosed {
+		return workorder.ErrClosedAlready
+	}
+	wo.Status = workorder.StatusClosed
+	wo.ClosedAt = &closedAt
+	r.workOrders[id] = wo
+	return nil
+}
+
+func (r *MemoryRepository) CountOpenByCode(ctx context.Context, code string) (count int64, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, wo := range r.workOrders {
+		if wo.InventoryCode == code && wo.Status == workorder.StatusOpen {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRepository) ReadRepairCost(ctx context.Context) (report []workorder.RepairCost, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byCode := map[string]*workorder.RepairCost{}
+	for _, wo := range r.workOrders {
+		cost, ok := byCode[wo.InventoryCode]
+		if !ok {
+			cost = &workorder.RepairCost{InventoryCode: wo.InventoryCode}
+			byCode[wo.InventoryCode] = cost
+		}
+		cost.WorkOrders++
+		cost.TotalCost += wo.Cost
+	}
+	for _, cost := range byCode {
+		report = append(report, *cost)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].TotalCost > report[j].TotalCost })
+	return report, nil
+}
+
+// Snapshot copies the current state, calling restore puts it back.
+func (r *MemoryRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	workOrders := maps.Clone(r.workOrders)
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.workOrders = workOrders
+	}
+}
+
+func (r *MemoryRepository) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wos := slices.AppendSeq(make([]workorder.WorkOrder, 0, len(r.workOrders)), maps.Values(r.workOrders))
+	sort.Slice(wos, func(i, j int) bool { return wos[i].ID < wos[j].ID })
+	return json.Marshal(wos)
+}
+
+func (r *MemoryRepository) UnmarshalJSON(data []byte) error {
+	var wos []workorder.WorkOrder
+	if err := json.Unmarshal(data, &wos); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workOrders = make(map[string]workorder.WorkOrder, len(wos))
+	for _, wo := range wos {
+		r.workOrders[wo.ID] = wo
+	}
+	return nil
+}
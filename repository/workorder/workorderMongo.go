@@ -0,0 +1,109 @@
+package workorder
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/workorder"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	col *mongo.Collection
+}
+
+func NewMongoRepository(db *mongo.Database) *MongoRepository {
+	return &MongoRepository{
+		col: db.Collection("work_orders"),
+	}
+}
+
+func (r *MongoRepository) Create(ctx context.Context, wo workorder.WorkOrder) (err error) {
+	_, err = r.col.InsertOne(ctx, wo)
+	return
+}
+
+func (r *MongoRepository) ReadAll(ctx context.Context, status string, page int, limit int) (wos []workorder.WorkOrder, err error) {
+	query := bson.M{}
+	if status != "" {
+		query["status"] = status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "opened_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := r.col.Find(ctx, query, opts)
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &wos)
+	return
+}
+
+func (r *MongoRepository) ReadByID(ctx context.Context, id string) (wo workorder.WorkOrder, err error) {
+	err = r.col.FindOne(ctx, bson.M{"work_order_id": id}).Decode(&wo)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return wo, workorder.ErrNotFound
+	}
+	return
+}
+
+func (r *MongoRepository) Update(ctx context.Context, wo workorder.WorkOrder) (err error) {
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"work_order_id": wo.ID, "status": workorder.StatusOpen},
+		bson.M{"$set": bson.M{"technician": wo.Technician, "cost": wo.Cost, "notes": wo.Notes}},
+	)
+	if err != nil || result.MatchedCount > 0 {
+		return
+	}
+
+	if _, err = r.ReadByID(ctx, wo.ID); err != nil {
+		return
+	}
+	return workorder.ErrClosedAlready
+}
+
+func (r *MongoRepository) Close(ctx context.Context, id string, closedAt time.Time) (err error) {
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"work_order_id": id, "status": workorder.StatusOpen},
+		bson.M{"$set": bson.M{"status": workorder.StatusClosed, "closed_at": closedAt}},
+	)
+	if err != nil || result.MatchedCount > 0 {
+		return
+	}
+
+	if _, err = r.ReadByID(ctx, id); err != nil {
+		return
+	}
+	return workorder.ErrClosedAlready
+}
+
+func (r *MongoRepository) CountOpenByCode(ctx context.Context, code string) (count int64, err error) {
+	return r.col.CountDocuments(ctx, bson.M{"inventory_code": code, "status": workorder.StatusOpen})
+}
+
+func (r *MongoRepository) ReadRepairCost(ctx context.Context) (report []workorder.RepairCost, err error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$inventory_code"},
+			{Key: "work_orders", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "total_cost", Value: bson.D{{Key: "$sum", Value: "$cost"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total_cost", Value: -1}}}},
+	}
+
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &report)
+	return
+}
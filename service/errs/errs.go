@@ -0,0 +1,46 @@
+package errs
+
+import "errors"
+
+// Kinds of domain error, the delivery layers (echo, http-server, gRPC) map
+// these to their own status codes with errors.Is.
+var (
+	NotFound     = errors.New("not found")
+	Conflict     = errors.New("conflict")
+	Unauthorized = errors.New("unauthorized")
+	Forbidden    = errors.New("forbidden")
+	Validation   = errors.New("validation error")
+
+	TooManyRequests = errors.New("too many requests")
+)
+
+// Error is a domain error with a message that is safe to show to clients.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func New(kind error, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Message returns the client safe message of err, errors that aren't domain
+// errors (driver failures, timeouts, ...) must not leak to clients.
+func Message(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Message
+	}
+	return "internal server error"
+}
@@ -0,0 +1,12 @@
+package inventory
+
+import "github.com/pobyzaarif/belajarGo2/service/errs"
+
+var (
+	ErrNotFound            = errs.New(errs.NotFound, "inventory not found")
+	ErrConflict            = errs.New(errs.Conflict, "inventory code registered already")
+	ErrInsufficientStock   = errs.New(errs.Conflict, "insufficient stock")
+	ErrSavedSearchNotFound = errs.New(errs.NotFound, "saved search not found")
+	ErrSavedSearchConflict = errs.New(errs.Conflict, "saved search name used already")
+	ErrBulkRolledBack      = errs.New(errs.Conflict, "bulk operation rolled back")
+)
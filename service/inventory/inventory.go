@@ -1,11 +1,47 @@
 package inventory
 
+const (
+	BulkOpCreate = "create"
+	BulkOpUpdate = "update"
+	BulkOpDelete = "delete"
+
+	BulkStatusOK         = "ok"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolled_back"
+	BulkStatusSkipped    = "skipped"
+)
+
 type (
 	Inventory struct {
-		Code        string `json:"code"`
-		Name        string `json:"name"`
-		Stock       int    `json:"stock"`
-		Description string `json:"description"`
-		Status      string `json:"status"`
+		Code        string   `json:"code"`
+		Name        string   `json:"name"`
+		Stock       int      `json:"stock"`
+		Description string   `json:"description"`
+		Status      string   `json:"status"`
+		Tags        []string `json:"tags" gorm:"-" bson:"tags,omitempty"`
+	}
+
+	Filter struct {
+		Tags []string `json:"tags"`
+	}
+
+	BulkOperation struct {
+		Op        string    `json:"op"`
+		Inventory Inventory `json:"inventory"`
+	}
+
+	BulkResult struct {
+		Index  int    `json:"index"`
+		Op     string `json:"op"`
+		Code   string `json:"code"`
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	SavedSearch struct {
+		ID     string `json:"id" bson:"search_id"`
+		UserID string `json:"user_id" bson:"user_id"`
+		Name   string `json:"name"`
+		Filter Filter `json:"filter"`
 	}
 )
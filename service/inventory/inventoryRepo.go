@@ -1,9 +1,27 @@
 package inventory
 
+import "context"
+
+// Repository lookups by key (ReadByCode, ReadSavedSearchByID) return
+// ErrNotFound / ErrSavedSearchNotFound when nothing matches, any other error
+// is a real storage failure. AdjustStock adds delta to the stock of code in a
+// single write, returning ErrInsufficientStock instead of taking it below zero.
+// SetStatus changes only the status, like Update it doesn't create a missing
+// code.
 type Repository interface {
-	Create(inv Inventory) (err error)
-	ReadAll(page int, limit int) (invs []Inventory, err error)
-	ReadByCode(code string) (inv Inventory, err error)
-	Update(inv Inventory) (err error)
-	Delete(code string) (err error)
+	Create(ctx context.Context, inv Inventory) (err error)
+	ReadAll(ctx context.Context, filter Filter, page int, limit int) (invs []Inventory, err error)
+	ReadByCode(ctx context.Context, code string) (inv Inventory, err error)
+	Update(ctx context.Context, inv Inventory) (err error)
+	Delete(ctx context.Context, code string) (err error)
+	AdjustStock(ctx context.Context, code string, delta int) (err error)
+	SetStatus(ctx context.Context, code string, status string) (err error)
+
+	AddTags(ctx context.Context, code string, tags []string) (err error)
+	RemoveTags(ctx context.Context, code string, tags []string) (err error)
+
+	CreateSavedSearch(ctx context.Context, search SavedSearch) (err error)
+	ReadSavedSearches(ctx context.Context, userID string) (searches []SavedSearch, err error)
+	ReadSavedSearchByID(ctx context.Context, userID string, id string) (search SavedSearch, err error)
+	DeleteSavedSearch(ctx context.Context, userID string, id string) (err error)
 }
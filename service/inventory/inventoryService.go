@@ -1,39 +1,275 @@
 package inventory
 
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/transaction"
+)
+
 type service struct {
-	repo Repository
+	repo       Repository
+	transactor transaction.Transactor
 }
 
 type Service interface {
-	Create(inv Inventory) (err error)
-	GetAll(page int, limit int) (invs []Inventory, err error)
-	GetByCode(code string) (inv Inventory, err error)
-	Update(inv Inventory) (err error)
-	Delete(code string) (err error)
+	Create(ctx context.Context, inv Inventory) (err error)
+	GetAll(ctx context.Context, filter Filter, page int, limit int) (invs []Inventory, err error)
+	GetByCode(ctx context.Context, code string) (inv Inventory, err error)
+	Update(ctx context.Context, inv Inventory) (err error)
+	Delete(ctx context.Context, code string) (err error)
+	Bulk(ctx context.Context, ops []BulkOperation, atomic bool) (results []BulkResult, err error)
+
+	AddTags(ctx context.Context, code string, tags []string) (err error)
+	RemoveTags(ctx context.Context, code string, tags []string) (err error)
+
+	CreateSavedSearch(ctx context.Context, search SavedSearch) (id string, err error)
+	GetSavedSearches(ctx context.Context, userID string) (searches []SavedSearch, err error)
+	RunSavedSearch(ctx context.Context, userID string, id string, page int, limit int) (invs []Inventory, err error)
+	DeleteSavedSearch(ctx context.Context, userID string, id string) (err error)
 }
 
-func NewService(r Repository) Service {
+func NewService(r Repository, t transaction.Transactor) Service {
 	return &service{
-		repo: r,
+		repo:       r,
+		transactor: t,
+	}
+}
+
+const (
+	maxTagLength      = 50
+	MaxBulkOperations = 100
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_:.-]*$`)
+
+// NormalizeTags lowercases, trims and dedupes tags, keeping the first-seen order.
+func NormalizeTags(tags []string) (normalized []string, err error) {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, errs.New(errs.Validation, "invalid tag "+tag)
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
+
+func (s *service) Create(ctx context.Context, inv Inventory) (err error) {
+	return s.create(ctx, inv)
+}
+
+func (s *service) create(ctx context.Context, inv Inventory) (err error) {
+	inv.Tags, err = NormalizeTags(inv.Tags)
+	if err != nil {
+		return
+	}
+
+	if len(inv.Tags) == 0 {
+		return s.repo.Create(ctx, inv)
+	}
+
+	// The item and its tags are written together, a failed tag leaves no
+	// untagged item behind. Within Bulk this joins its unit of work.
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, inv); err != nil {
+			return err
+		}
+		return s.repo.AddTags(ctx, inv.Code, inv.Tags)
+	})
+}
+
+func (s *service) GetAll(ctx context.Context, filter Filter, page int, limit int) (invs []Inventory, err error) {
+	filter.Tags, err = NormalizeTags(filter.Tags)
+	if err != nil {
+		return
+	}
+	return s.repo.ReadAll(ctx, filter, page, limit)
+}
+
+func (s *service) GetByCode(ctx context.Context, code string) (inv Inventory, err error) {
+	return s.repo.ReadByCode(ctx, code)
+}
+
+func (s *service) Update(ctx context.Context, inv Inventory) (err error) {
+	if _, err = s.repo.ReadByCode(ctx, inv.Code); err != nil {
+		return
+	}
+	return s.repo.Update(ctx, inv)
+}
+
+func (s *service) Delete(ctx context.Context, code string) (err error) {
+	if _, err = s.repo.ReadByCode(ctx, code); err != nil {
+		return
+	}
+	return s.repo.Delete(ctx, code)
+}
+
+func validateBulkOperation(op BulkOperation) (err error) {
+	if op.Inventory.Code == "" {
+		return errs.New(errs.Validation, "invalid operation, code is required")
+	}
+
+	switch op.Op {
+	case BulkOpCreate, BulkOpUpdate:
+		if op.Inventory.Name == "" {
+			return errs.New(errs.Validation, "invalid operation, name is required")
+		}
+		if op.Inventory.Status != "active" && op.Inventory.Status != "broken" {
+			return errs.New(errs.Validation, "invalid operation, status must be active or broken")
+		}
+	case BulkOpDelete:
+	default:
+		return errs.New(errs.Validation, "invalid operation "+op.Op)
+	}
+	return nil
+}
+
+func (s *service) applyBulkOperation(ctx context.Context, op BulkOperation) (err error) {
+	if err = validateBulkOperation(op); err != nil {
+		return
+	}
+
+	if op.Op == BulkOpCreate {
+		return s.create(ctx, op.Inventory)
+	}
+
+	if _, err = s.repo.ReadByCode(ctx, op.Inventory.Code); err != nil {
+		return
+	}
+
+	if op.Op == BulkOpUpdate {
+		return s.repo.Update(ctx, op.Inventory)
 	}
+	return s.repo.Delete(ctx, op.Inventory.Code)
 }
 
-func (s *service) Create(inv Inventory) (err error) {
-	return s.repo.Create(inv)
+func (s *service) Bulk(ctx context.Context, ops []BulkOperation, atomic bool) (results []BulkResult, err error) {
+	if len(ops) == 0 || len(ops) > MaxBulkOperations {
+		return nil, errs.New(errs.Validation, "invalid bulk request, operations must be between 1 and 100")
+	}
+
+	results = make([]BulkResult, len(ops))
+	for i, op := range ops {
+		results[i] = BulkResult{Index: i, Op: op.Op, Code: op.Inventory.Code, Status: BulkStatusSkipped}
+	}
+
+	if !atomic {
+		for i, op := range ops {
+			if errOp := s.applyBulkOperation(ctx, op); errOp != nil {
+				results[i].Status = BulkStatusFailed
+				results[i].Error = errs.Message(errOp)
+				continue
+			}
+			results[i].Status = BulkStatusOK
+		}
+		return results, nil
+	}
+
+	failed := -1
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i, op := range ops {
+			if errOp := s.applyBulkOperation(ctx, op); errOp != nil {
+				failed = i
+				results[i].Status = BulkStatusFailed
+				results[i].Error = errs.Message(errOp)
+				return errOp
+			}
+			results[i].Status = BulkStatusOK
+		}
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+
+	for i := range results {
+		if results[i].Status == BulkStatusOK {
+			results[i].Status = BulkStatusRolledBack
+		}
+	}
+	if failed < 0 {
+		// The transaction itself failed (e.g. on commit), not one of the operations
+		return results, err
+	}
+	return results, ErrBulkRolledBack
 }
 
-func (s *service) GetAll(page int, limit int) (invs []Inventory, err error) {
-	return s.repo.ReadAll(page, limit)
+func (s *service) AddTags(ctx context.Context, code string, tags []string) (err error) {
+	tags, err = NormalizeTags(tags)
+	if err != nil {
+		return
+	}
+	if len(tags) == 0 {
+		return errs.New(errs.Validation, "invalid tag, at least one tag is required")
+	}
+
+	if _, err = s.repo.ReadByCode(ctx, code); err != nil {
+		return
+	}
+
+	return s.repo.AddTags(ctx, code, tags)
+}
+
+func (s *service) RemoveTags(ctx context.Context, code string, tags []string) (err error) {
+	tags, err = NormalizeTags(tags)
+	if err != nil {
+		return
+	}
+	if len(tags) == 0 {
+		return errs.New(errs.Validation, "invalid tag, at least one tag is required")
+	}
+
+	if _, err = s.repo.ReadByCode(ctx, code); err != nil {
+		return
+	}
+
+	return s.repo.RemoveTags(ctx, code, tags)
+}
+
+func (s *service) CreateSavedSearch(ctx context.Context, search SavedSearch) (id string, err error) {
+	search.Name = strings.TrimSpace(search.Name)
+	if search.UserID == "" || search.Name == "" {
+		return "", errs.New(errs.Validation, "invalid saved search, user id and name are required")
+	}
+
+	search.Filter.Tags, err = NormalizeTags(search.Filter.Tags)
+	if err != nil {
+		return "", err
+	}
+
+	search.ID = uuid.NewString()
+	if err = s.repo.CreateSavedSearch(ctx, search); err != nil {
+		return "", err
+	}
+
+	return search.ID, nil
 }
 
-func (s *service) GetByCode(code string) (inv Inventory, err error) {
-	return s.repo.ReadByCode(code)
+func (s *service) GetSavedSearches(ctx context.Context, userID string) (searches []SavedSearch, err error) {
+	return s.repo.ReadSavedSearches(ctx, userID)
 }
 
-func (s *service) Update(inv Inventory) (err error) {
-	return s.repo.Update(inv)
+func (s *service) RunSavedSearch(ctx context.Context, userID string, id string, page int, limit int) (invs []Inventory, err error) {
+	search, err := s.repo.ReadSavedSearchByID(ctx, userID, id)
+	if err != nil {
+		return
+	}
+
+	return s.repo.ReadAll(ctx, search.Filter, page, limit)
 }
 
-func (s *service) Delete(code string) (err error) {
-	return s.repo.Delete(code)
+func (s *service) DeleteSavedSearch(ctx context.Context, userID string, id string) (err error) {
+	return s.repo.DeleteSavedSearch(ctx, userID, id)
 }
@@ -0,0 +1,183 @@
+package inventory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	mock_inventory "github.com/pobyzaarif/belajarGo2/service/inventory/mock"
+	mock_transaction "github.com/pobyzaarif/belajarGo2/service/transaction/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTransaction makes the transactor run the unit of work as is, the mock
+// repository calls stand for what would be rolled back.
+func runTransaction(m *mock_transaction.MockTransactor) {
+	m.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	).AnyTimes()
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		input   inventory.Inventory
+		mock    func(m *mock_inventory.MockRepository, mt *mock_transaction.MockTransactor)
+		wantErr error
+	}{
+		{
+			name:  "without tags no transaction",
+			input: inventory.Inventory{Code: "INV001"},
+			mock: func(m *mock_inventory.MockRepository, mt *mock_transaction.MockTransactor) {
+				m.EXPECT().Create(gomock.Any(), inventory.Inventory{Code: "INV001"}).Return(nil)
+			},
+		},
+		{
+			name:  "tags normalized and written in one transaction",
+			input: inventory.Inventory{Code: "INV001", Tags: []string{" IT ", "it", "Office"}},
+			mock: func(m *mock_inventory.MockRepository, mt *mock_transaction.MockTransactor) {
+				runTransaction(mt)
+				m.EXPECT().Create(gomock.Any(), inventory.Inventory{Code: "INV001", Tags: []string{"it", "office"}}).Return(nil)
+				m.EXPECT().AddTags(gomock.Any(), "INV001", []string{"it", "office"}).Return(nil)
+			},
+		},
+		{
+			name:  "failed tags fail the unit of work",
+			input: inventory.Inventory{Code: "INV001", Tags: []string{"it"}},
+			mock: func(m *mock_inventory.MockRepository, mt *mock_transaction.MockTransactor) {
+				mt.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(ctx context.Context) error) error {
+						err := fn(ctx)
+						assert.NotNil(t, err)
+						return err
+					},
+				)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().AddTags(gomock.Any(), "INV001", []string{"it"}).Return(errors.New("db con error"))
+			},
+			wantErr: errors.New("db con error"),
+		},
+		{
+			name:    "invalid tag",
+			input:   inventory.Inventory{Code: "INV001", Tags: []string{"no spaces"}},
+			mock:    func(m *mock_inventory.MockRepository, mt *mock_transaction.MockTransactor) {},
+			wantErr: errs.Validation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mock_repo := mock_inventory.NewMockRepository(ctrl)
+			mock_transactor := mock_transaction.NewMockTransactor(ctrl)
+			tt.mock(mock_repo, mock_transactor)
+
+			err := inventory.NewService(mock_repo, mock_transactor).Create(ctx, tt.input)
+			switch {
+			case tt.wantErr == nil:
+				assert.Nil(t, err)
+			case errors.Is(tt.wantErr, errs.Validation):
+				assert.ErrorIs(t, err, errs.Validation)
+			default:
+				assert.EqualError(t, err, tt.wantErr.Error())
+			}
+		})
+	}
+}
+
+func TestGetAllTagFilter(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_repo := mock_inventory.NewMockRepository(ctrl)
+	inventoryService := inventory.NewService(mock_repo, mock_transaction.NewMockTransactor(ctrl))
+
+	// The filter is normalized the way the tags were stored
+	want := []inventory.Inventory{{Code: "INV001", Tags: []string{"it", "office"}}}
+	mock_repo.EXPECT().ReadAll(gomock.Any(), inventory.Filter{Tags: []string{"it", "office"}}, 2, 10).Return(want, nil)
+	invs, err := inventoryService.GetAll(ctx, inventory.Filter{Tags: []string{"IT", " office", "it", ""}}, 2, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, want, invs)
+
+	_, err = inventoryService.GetAll(ctx, inventory.Filter{Tags: []string{"it!"}}, 1, 10)
+	assert.ErrorIs(t, err, errs.Validation)
+}
+
+func TestBulk(t *testing.T) {
+	ctx := context.Background()
+	ops := []inventory.BulkOperation{
+		{Op: inventory.BulkOpCreate, Inventory: inventory.Inventory{Code: "INV001", Name: "Laptop", Status: "active"}},
+		{Op: inventory.BulkOpUpdate, Inventory: inventory.Inventory{Code: "INV002", Name: "Mouse", Status: "unknown"}},
+		{Op: inventory.BulkOpDelete, Inventory: inventory.Inventory{Code: "INV003"}},
+	}
+
+	t.Run("limits", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		inventoryService := inventory.NewService(mock_inventory.NewMockRepository(ctrl), mock_transaction.NewMockTransactor(ctrl))
+
+		_, err := inventoryService.Bulk(ctx, nil, false)
+		assert.ErrorIs(t, err, errs.Validation)
+		_, err = inventoryService.Bulk(ctx, make([]inventory.BulkOperation, inventory.MaxBulkOperations+1), false)
+		assert.ErrorIs(t, err, errs.Validation)
+	})
+
+	t.Run("not atomic keeps going", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mock_repo := mock_inventory.NewMockRepository(ctrl)
+		inventoryService := inventory.NewService(mock_repo, mock_transaction.NewMockTransactor(ctrl))
+
+		mock_repo.EXPECT().Create(gomock.Any(), ops[0].Inventory).Return(nil)
+		mock_repo.EXPECT().ReadByCode(gomock.Any(), "INV003").Return(inventory.Inventory{Code: "INV003"}, nil)
+		mock_repo.EXPECT().Delete(gomock.Any(), "INV003").Return(nil)
+
+		results, err := inventoryService.Bulk(ctx, ops, false)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{inventory.BulkStatusOK, inventory.BulkStatusFailed, inventory.BulkStatusOK},
+			[]string{results[0].Status, results[1].Status, results[2].Status})
+		assert.NotEmpty(t, results[1].Error)
+	})
+
+	t.Run("atomic rolls back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mock_repo := mock_inventory.NewMockRepository(ctrl)
+		mock_transactor := mock_transaction.NewMockTransactor(ctrl)
+		runTransaction(mock_transactor)
+		inventoryService := inventory.NewService(mock_repo, mock_transactor)
+
+		mock_repo.EXPECT().Create(gomock.Any(), ops[0].Inventory).Return(nil)
+
+		results, err := inventoryService.Bulk(ctx, ops, true)
+		assert.ErrorIs(t, err, inventory.ErrBulkRolledBack)
+		assert.Equal(t, []string{inventory.BulkStatusRolledBack, inventory.BulkStatusFailed, inventory.BulkStatusSkipped},
+			[]string{results[0].Status, results[1].Status, results[2].Status})
+	})
+
+	t.Run("atomic commit failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mock_repo := mock_inventory.NewMockRepository(ctrl)
+		mock_transactor := mock_transaction.NewMockTransactor(ctrl)
+		mock_transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(ctx context.Context) error) error {
+				assert.Nil(t, fn(ctx))
+				return errors.New("commit error")
+			},
+		)
+		inventoryService := inventory.NewService(mock_repo, mock_transactor)
+
+		mock_repo.EXPECT().Create(gomock.Any(), ops[0].Inventory).Return(nil)
+		results, err := inventoryService.Bulk(ctx, ops[:1], true)
+		assert.EqualError(t, err, "commit error")
+		assert.Equal(t, inventory.BulkStatusRolledBack, results[0].Status)
+	})
+}
@@ -0,0 +1,222 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service/inventory/inventoryRepo.go
+
+// Package mock_inventory is a generated GoMock package.
+package mock_inventory
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	inventory "github.com/pobyzaarif/belajarGo2/service/inventory"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddTags mocks base method.
+func (m *MockRepository) AddTags(ctx context.Context, code string, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTags", ctx, code, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddTags indicates an expected call of AddTags.
+func (mr *MockRepositoryMockRecorder) AddTags(ctx, code, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTags", reflect.TypeOf((*MockRepository)(nil).AddTags), ctx, code, tags)
+}
+
+// AdjustStock mocks base method.
+func (m *MockRepository) AdjustStock(ctx context.Context, code string, delta int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustStock", ctx, code, delta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdjustStock indicates an expected call of AdjustStock.
+func (mr *MockRepositoryMockRecorder) AdjustStock(ctx, code, delta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustStock", reflect.TypeOf((*MockRepository)(nil).AdjustStock), ctx, code, delta)
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(ctx context.Context, inv inventory.Inventory) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, inv)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(ctx, inv interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, inv)
+}
+
+// CreateSavedSearch mocks base method.
+func (m *MockRepository) CreateSavedSearch(ctx context.Context, search inventory.SavedSearch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSavedSearch", ctx, search)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSavedSearch indicates an expected call of CreateSavedSearch.
+func (mr *MockRepositoryMockRecorder) CreateSavedSearch(ctx, search interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSavedSearch", reflect.TypeOf((*MockRepository)(nil).CreateSavedSearch), ctx, search)
+}
+
+// Delete mocks base method.
+func (m *MockRepository) Delete(ctx context.Context, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRepositoryMockRecorder) Delete(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, code)
+}
+
+// DeleteSavedSearch mocks base method.
+func (m *MockRepository) DeleteSavedSearch(ctx context.Context, userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSavedSearch", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSavedSearch indicates an expected call of DeleteSavedSearch.
+func (mr *MockRepositoryMockRecorder) DeleteSavedSearch(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSavedSearch", reflect.TypeOf((*MockRepository)(nil).DeleteSavedSearch), ctx, userID, id)
+}
+
+// ReadAll mocks base method.
+func (m *MockRepository) ReadAll(ctx context.Context, filter inventory.Filter, page, limit int) ([]inventory.Inventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAll", ctx, filter, page, limit)
+	ret0, _ := ret[0].([]inventory.Inventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAll indicates an expected call of ReadAll.
+func (mr *MockRepositoryMockRecorder) ReadAll(ctx, filter, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAll", reflect.TypeOf((*MockRepository)(nil).ReadAll), ctx, filter, page, limit)
+}
+
+// ReadByCode mocks base method.
+func (m *MockRepository) ReadByCode(ctx context.Context, code string) (inventory.Inventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByCode", ctx, code)
+	ret0, _ := ret[0].(inventory.Inventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByCode indicates an expected call of ReadByCode.
+func (mr *MockRepositoryMockRecorder) ReadByCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByCode", reflect.TypeOf((*MockRepository)(nil).ReadByCode), ctx, code)
+}
+
+// ReadSavedSearchByID mocks base method.
+func (m *MockRepository) ReadSavedSearchByID(ctx context.Context, userID, id string) (inventory.SavedSearch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadSavedSearchByID", ctx, userID, id)
+	ret0, _ := ret[0].(inventory.SavedSearch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadSavedSearchByID indicates an expected call of ReadSavedSearchByID.
+func (mr *MockRepositoryMockRecorder) ReadSavedSearchByID(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadSavedSearchByID", reflect.TypeOf((*MockRepository)(nil).ReadSavedSearchByID), ctx, userID, id)
+}
+
+// ReadSavedSearches mocks base method.
+func (m *MockRepository) ReadSavedSearches(ctx context.Context, userID string) ([]inventory.SavedSearch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadSavedSearches", ctx, userID)
+	ret0, _ := ret[0].([]inventory.SavedSearch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadSavedSearches indicates an expected call of ReadSavedSearches.
+func (mr *MockRepositoryMockRecorder) ReadSavedSearches(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadSavedSearches", reflect.TypeOf((*MockRepository)(nil).ReadSavedSearches), ctx, userID)
+}
+
+// RemoveTags mocks base method.
+func (m *MockRepository) RemoveTags(ctx context.Context, code string, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTags", ctx, code, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveTags indicates an expected call of RemoveTags.
+func (mr *MockRepositoryMockRecorder) RemoveTags(ctx, code, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTags", reflect.TypeOf((*MockRepository)(nil).RemoveTags), ctx, code, tags)
+}
+
+// SetStatus mocks base method.
+func (m *MockRepository) SetStatus(ctx context.Context, code, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStatus", ctx, code, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStatus indicates an expected call of SetStatus.
+func (mr *MockRepositoryMockRecorder) SetStatus(ctx, code, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatus", reflect.TypeOf((*MockRepository)(nil).SetStatus), ctx, code, status)
+}
+
+// Update mocks base method.
+func (m *MockRepository) Update(ctx context.Context, inv inventory.Inventory) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, inv)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRepositoryMockRecorder) Update(ctx, inv interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, inv)
+}
@@ -0,0 +1,10 @@
+package loan
+
+import "github.com/pobyzaarif/belajarGo2/service/errs"
+
+var (
+	ErrNotFound          = errs.New(errs.NotFound, "loan not found")
+	ErrReturnedAlready   = errs.New(errs.Conflict, "loan returned already")
+	ErrNotAvailable      = errs.New(errs.Conflict, "inventory is not available for loan")
+	ErrInsufficientStock = errs.New(errs.Conflict, "insufficient stock")
+)
@@ -0,0 +1,20 @@
+package loan
+
+import "time"
+
+type (
+	Loan struct {
+		ID             string     `json:"id" bson:"loan_id"`
+		InventoryCode  string     `json:"inventory_code" bson:"inventory_code"`
+		UserID         string     `json:"user_id" bson:"user_id"`
+		Quantity       int        `json:"quantity"`
+		CheckedOutAt   time.Time  `json:"checked_out_at" bson:"checked_out_at"`
+		DueAt          time.Time  `json:"due_at" bson:"due_at"`
+		ReturnedAt     *time.Time `json:"returned_at" bson:"returned_at"`
+		ReminderSentAt *time.Time `json:"reminder_sent_at" bson:"reminder_sent_at"`
+	}
+)
+
+func (l Loan) IsOverdue(now time.Time) bool {
+	return l.ReturnedAt == nil && now.After(l.DueAt)
+}
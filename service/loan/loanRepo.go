@@ -0,0 +1,19 @@
+package loan
+
+import (
+	"context"
+	"time"
+)
+
+// Repository ReadByID returns ErrNotFound when nothing matches. Return sets
+// ReturnedAt only on a loan that isn't returned yet, ErrReturnedAlready
+// otherwise, so a loan is checked in once. MarkReminded only sets
+// ReminderSentAt, and leaves a loan returned meanwhile as it is.
+type Repository interface {
+	Create(ctx context.Context, loan Loan) (err error)
+	ReadAll(ctx context.Context, userID string, page int, limit int) (loans []Loan, err error)
+	ReadByID(ctx context.Context, id string) (loan Loan, err error)
+	ReadOverdue(ctx context.Context, userID string, now time.Time) (loans []Loan, err error)
+	MarkReminded(ctx context.Context, id string, at time.Time) (err error)
+	Return(ctx context.Context, id string, returnedAt time.Time) (err error)
+}
@@ -0,0 +1,182 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/notification"
+	"github.com/pobyzaarif/belajarGo2/service/transaction"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+)
+
+type service struct {
+	logger     *slog.Logger
+	repo       Repository
+	invRepo    inventory.Repository
+	userRepo   user.Repository
+	transactor transaction.Transactor
+	notifRepo  notification.Repository
+}
+
+const (
+	reminderInterval = 24 * time.Hour
+)
+
+type Service interface {
+	Checkout(ctx context.Context, loan Loan) (id string, err error)
+	Checkin(ctx context.Context, id string) (err error)
+	GetAll(ctx context.Context, userID string, page int, limit int) (loans []Loan, err error)
+	GetByID(ctx context.Context, id string) (loan Loan, err error)
+	GetOverdue(ctx context.Context, userID string) (loans []Loan, err error)
+	SendOverdueReminders(ctx context.Context) (sent int, err error)
+}
+
+func NewService(
+	logger *slog.Logger,
+	repo Repository,
+	invRepo inventory.Repository,
+	userRepo user.Repository,
+	transactor transaction.Transactor,
+	notifRepo notification.Repository,
+) Service {
+	return &service{
+		logger:     logger,
+		repo:       repo,
+		invRepo:    invRepo,
+		userRepo:   userRepo,
+		transactor: transactor,
+		notifRepo:  notifRepo,
+	}
+}
+
+const (
+	SubjectLoanOverdue   = "Loan Overdue Reminder"
+	EmailBodyLoanOverdue = `Halo, %v, pinjaman %v unit %v (%v) sudah melewati batas waktu pada %v. Mohon segera dikembalikan.`
+)
+
+func (s *service) Checkout(ctx context.Context, loan Loan) (id string, err error) {
+	timeNow := time.Now()
+	if loan.Quantity < 1 {
+		return "", errs.New(errs.Validation, "invalid loan quantity")
+	}
+	if !loan.DueAt.After(timeNow) {
+		return "", errs.New(errs.Validation, "invalid loan due date")
+	}
+
+	if _, err = s.userRepo.GetByID(ctx, loan.UserID); err != nil {
+		return
+	}
+
+	inv, err := s.invRepo.ReadByCode(ctx, loan.InventoryCode)
+	if err != nil {
+		return
+	}
+	if inv.Status != "active" {
+		return "", ErrNotAvailable
+	}
+
+	loan.ID = uuid.NewString()
+	loan.CheckedOutAt = timeNow
+	loan.ReturnedAt = nil
+	loan.ReminderSentAt = nil
+
+	// The stock is taken by the repository in one write, concurrent checkouts
+	// can't oversell, and given back with the rollback if the loan isn't saved
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.invRepo.AdjustStock(ctx, loan.InventoryCode, -loan.Quantity)
+		if errors.Is(err, inventory.ErrInsufficientStock) {
+			return ErrInsufficientStock
+		}
+		if err != nil {
+			return err
+		}
+		return s.repo.Create(ctx, loan)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return loan.ID, nil
+}
+
+func (s *service) Checkin(ctx context.Context, id string) (err error) {
+	loan, err := s.repo.ReadByID(ctx, id)
+	if err != nil {
+		return
+	}
+	if loan.ReturnedAt != nil {
+		return ErrReturnedAlready
+	}
+
+	// Return refuses a loan checked in meanwhile, its units go back only once
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Return(ctx, loan.ID, time.Now()); err != nil {
+			return err
+		}
+
+		err := s.invRepo.AdjustStock(ctx, loan.InventoryCode, loan.Quantity)
+		if errors.Is(err, inventory.ErrNotFound) {
+			s.logger.Warn("loan checkin err", slog.Any("err", err), slog.String("code", loan.InventoryCode))
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *service) GetAll(ctx context.Context, userID string, page int, limit int) (loans []Loan, err error) {
+	return s.repo.ReadAll(ctx, userID, page, limit)
+}
+
+func (s *service) GetByID(ctx context.Context, id string) (loan Loan, err error) {
+	return s.repo.ReadByID(ctx, id)
+}
+
+func (s *service) GetOverdue(ctx context.Context, userID string) (loans []Loan, err error) {
+	return s.repo.ReadOverdue(ctx, userID, time.Now())
+}
+
+func (s *service) SendOverdueReminders(ctx context.Context) (sent int, err error) {
+	timeNow := time.Now()
+	loans, err := s.repo.ReadOverdue(ctx, "", timeNow)
+	if err != nil {
+		return
+	}
+
+	for _, loan := range loans {
+		if loan.ReminderSentAt != nil && timeNow.Sub(*loan.ReminderSentAt) < reminderInterval {
+			continue
+		}
+
+		getUser, err := s.userRepo.GetByID(ctx, loan.UserID)
+		if err != nil {
+			s.logger.Error("loan reminder err", slog.Any("err", err), slog.String("loan_id", loan.ID))
+			continue
+		}
+
+		inv, err := s.invRepo.ReadByCode(ctx, loan.InventoryCode)
+		if err != nil {
+			s.logger.Error("loan reminder err", slog.Any("err", err), slog.String("loan_id", loan.ID))
+			continue
+		}
+
+		message := fmt.Sprintf(EmailBodyLoanOverdue, getUser.Fullname, loan.Quantity, inv.Name, loan.InventoryCode, loan.DueAt.Format("2006-01-02 15:04"))
+		if err := s.notifRepo.SendEmail(ctx, getUser.Fullname, getUser.Email, SubjectLoanOverdue, message); err != nil {
+			s.logger.Error("loan reminder send email err", slog.Any("err", err), slog.String("loan_id", loan.ID))
+			continue
+		}
+
+		if err := s.repo.MarkReminded(ctx, loan.ID, timeNow); err != nil {
+			s.logger.Error("loan reminder update err", slog.Any("err", err), slog.String("loan_id", loan.ID))
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
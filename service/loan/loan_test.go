@@ -0,0 +1,293 @@
+package loan_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	mock_inventory "github.com/pobyzaarif/belajarGo2/service/inventory/mock"
+	"github.com/pobyzaarif/belajarGo2/service/loan"
+	mock_loan "github.com/pobyzaarif/belajarGo2/service/loan/mock"
+	mock_notification "github.com/pobyzaarif/belajarGo2/service/notification/mock"
+	mock_transaction "github.com/pobyzaarif/belajarGo2/service/transaction/mock"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	mock_user "github.com/pobyzaarif/belajarGo2/service/user/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+var loggerOption = slog.HandlerOptions{AddSource: true}
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
+
+var errDB = errors.New("db con error")
+
+type mocks struct {
+	repo       *mock_loan.MockRepository
+	invRepo    *mock_inventory.MockRepository
+	userRepo   *mock_user.MockRepository
+	transactor *mock_transaction.MockTransactor
+	notifRepo  *mock_notification.MockRepository
+}
+
+func newService(ctrl *gomock.Controller) (loan.Service, mocks) {
+	m := mocks{
+		repo:       mock_loan.NewMockRepository(ctrl),
+		invRepo:    mock_inventory.NewMockRepository(ctrl),
+		userRepo:   mock_user.NewMockRepository(ctrl),
+		transactor: mock_transaction.NewMockTransactor(ctrl),
+		notifRepo:  mock_notification.NewMockRepository(ctrl),
+	}
+	// The unit of work runs as is, what it returns is what would be rolled back
+	m.transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	).AnyTimes()
+	return loan.NewService(logger, m.repo, m.invRepo, m.userRepo, m.transactor, m.notifRepo), m
+}
+
+func TestCheckout(t *testing.T) {
+	ctx := context.Background()
+	dueAt := time.Now().Add(24 * time.Hour)
+	laptop := inventory.Inventory{Code: "INV001", Name: "Laptop", Stock: 2, Status: "active"}
+	input := loan.Loan{InventoryCode: laptop.Code, UserID: "1", Quantity: 2, DueAt: dueAt}
+
+	tests := []struct {
+		name    string
+		input   loan.Loan
+		mock    func(m mocks)
+		wantErr error
+	}{
+		{
+			name:    "invalid quantity",
+			input:   loan.Loan{InventoryCode: laptop.Code, UserID: "1", Quantity: 0, DueAt: dueAt},
+			mock:    func(m mocks) {},
+			wantErr: errs.Validation,
+		},
+		{
+			name:    "due date in the past",
+			input:   loan.Loan{InventoryCode: laptop.Code, UserID: "1", Quantity: 1, DueAt: time.Now().Add(-time.Hour)},
+			mock:    func(m mocks) {},
+			wantErr: errs.Validation,
+		},
+		{
+			name:  "inventory not active",
+			input: input,
+			mock: func(m mocks) {
+				m.userRepo.EXPECT().GetByID(gomock.Any(), "1").Return(user.User{ID: "1"}, nil)
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), laptop.Code).Return(inventory.Inventory{Code: laptop.Code, Stock: 2, Status: "broken"}, nil)
+			},
+			wantErr: loan.ErrNotAvailable,
+		},
+		{
+			name:  "insufficient stock creates no loan",
+			input: input,
+			mock: func(m mocks) {
+				m.userRepo.EXPECT().GetByID(gomock.Any(), "1").Return(user.User{ID: "1"}, nil)
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), laptop.Code).Return(laptop, nil)
+				m.invRepo.EXPECT().AdjustStock(gomock.Any(), laptop.Code, -2).Return(inventory.ErrInsufficientStock)
+			},
+			wantErr: loan.ErrInsufficientStock,
+		},
+		{
+			name:  "failed loan fails the unit of work",
+			input: input,
+			mock: func(m mocks) {
+				m.userRepo.EXPECT().GetByID(gomock.Any(), "1").Return(user.User{ID: "1"}, nil)
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), laptop.Code).Return(laptop, nil)
+				m.invRepo.EXPECT().AdjustStock(gomock.Any(), laptop.Code, -2).Return(nil)
+				m.repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errDB)
+			},
+			wantErr: errDB,
+		},
+		{
+			name:  "success",
+			input: input,
+			mock: func(m mocks) {
+				m.userRepo.EXPECT().GetByID(gomock.Any(), "1").Return(user.User{ID: "1"}, nil)
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), laptop.Code).Return(laptop, nil)
+				m.invRepo.EXPECT().AdjustStock(gomock.Any(), laptop.Code, -2).Return(nil)
+				m.repo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, l loan.Loan) error {
+					assert.NotEmpty(t, l.ID)
+					assert.Nil(t, l.ReturnedAt)
+					assert.False(t, l.CheckedOutAt.IsZero())
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			loanService, m := newService(ctrl)
+			tt.mock(m)
+
+			id, err := loanService.Checkout(ctx, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.NotEmpty(t, id)
+		})
+	}
+}
+
+func TestCheckin(t *testing.T) {
+	ctx := context.Background()
+	returnedAt := time.Now().Add(-time.Hour)
+	open := loan.Loan{ID: "L1", InventoryCode: "INV001", UserID: "1", Quantity: 2}
+
+	tests := []struct {
+		name    string
+		mock    func(m mocks)
+		wantErr error
+	}{
+		{
+			name: "loan not found",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "L1").Return(loan.Loan{}, loan.ErrNotFound)
+			},
+			wantErr: loan.ErrNotFound,
+		},
+		{
+			name: "returned already",
+			mock: func(m mocks) {
+				returned := open
+				returned.ReturnedAt = &returnedAt
+				m.repo.EXPECT().ReadByID(gomock.Any(), "L1").Return(returned, nil)
+			},
+			wantErr: loan.ErrReturnedAlready,
+		},
+		{
+			name: "returned concurrently gives no stock back",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "L1").Return(open, nil)
+				m.repo.EXPECT().Return(gomock.Any(), "L1", gomock.Any()).Return(loan.ErrReturnedAlready)
+			},
+			wantErr: loan.ErrReturnedAlready,
+		},
+		{
+			name: "failed stock fails the unit of work",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "L1").Return(open, nil)
+				m.repo.EXPECT().Return(gomock.Any(), "L1", gomock.Any()).Return(nil)
+				m.invRepo.EXPECT().AdjustStock(gomock.Any(), "INV001", 2).Return(errDB)
+			},
+			wantErr: errDB,
+		},
+		{
+			name: "deleted inventory",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "L1").Return(open, nil)
+				m.repo.EXPECT().Return(gomock.Any(), "L1", gomock.Any()).Return(nil)
+				m.invRepo.EXPECT().AdjustStock(gomock.Any(), "INV001", 2).Return(inventory.ErrNotFound)
+			},
+		},
+		{
+			name: "success",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "L1").Return(open, nil)
+				m.repo.EXPECT().Return(gomock.Any(), "L1", gomock.Any()).Return(nil)
+				m.invRepo.EXPECT().AdjustStock(gomock.Any(), "INV001", 2).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			loanService, m := newService(ctrl)
+			tt.mock(m)
+
+			err := loanService.Checkin(ctx, "L1")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGetAllScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	loanService, m := newService(ctrl)
+
+	// An empty user id lists every loan, the controller decides which applies
+	m.repo.EXPECT().ReadAll(gomock.Any(), "1", 1, 10).Return([]loan.Loan{{ID: "L1", UserID: "1"}}, nil)
+	m.repo.EXPECT().ReadAll(gomock.Any(), "", 1, 10).Return([]loan.Loan{{ID: "L1", UserID: "1"}, {ID: "L2", UserID: "2"}}, nil)
+	m.repo.EXPECT().ReadOverdue(gomock.Any(), "1", gomock.Any()).Return(nil, nil)
+
+	loans, err := loanService.GetAll(ctx, "1", 1, 10)
+	assert.Nil(t, err)
+	assert.Len(t, loans, 1)
+
+	loans, err = loanService.GetAll(ctx, "", 1, 10)
+	assert.Nil(t, err)
+	assert.Len(t, loans, 2)
+
+	_, err = loanService.GetOverdue(ctx, "1")
+	assert.Nil(t, err)
+}
+
+func TestSendOverdueReminders(t *testing.T) {
+	ctx := context.Background()
+	dueAt := time.Now().Add(-48 * time.Hour)
+	remindedRecently := time.Now().Add(-time.Hour)
+	remindedLongAgo := time.Now().Add(-25 * time.Hour)
+	alice := user.User{ID: "1", Fullname: "Alice", Email: "alice@example.com"}
+	laptop := inventory.Inventory{Code: "INV001", Name: "Laptop"}
+
+	t.Run("read overdue error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		loanService, m := newService(ctrl)
+		m.repo.EXPECT().ReadOverdue(gomock.Any(), "", gomock.Any()).Return(nil, errDB)
+
+		sent, err := loanService.SendOverdueReminders(ctx)
+		assert.ErrorIs(t, err, errDB)
+		assert.Equal(t, 0, sent)
+	})
+
+	t.Run("reminds at most once a day and goes on after failures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		loanService, m := newService(ctrl)
+
+		m.repo.EXPECT().ReadOverdue(gomock.Any(), "", gomock.Any()).Return([]loan.Loan{
+			{ID: "L1", InventoryCode: laptop.Code, UserID: "1", Quantity: 1, DueAt: dueAt},
+			{ID: "L2", InventoryCode: laptop.Code, UserID: "1", Quantity: 1, DueAt: dueAt, ReminderSentAt: &remindedRecently},
+			{ID: "L3", InventoryCode: laptop.Code, UserID: "2", Quantity: 1, DueAt: dueAt},
+			{ID: "L4", InventoryCode: laptop.Code, UserID: "1", Quantity: 3, DueAt: dueAt, ReminderSentAt: &remindedLongAgo},
+			{ID: "L5", InventoryCode: laptop.Code, UserID: "1", Quantity: 1, DueAt: dueAt},
+		}, nil)
+		m.userRepo.EXPECT().GetByID(gomock.Any(), "1").Return(alice, nil).Times(3)
+		m.userRepo.EXPECT().GetByID(gomock.Any(), "2").Return(user.User{}, user.ErrNotFound)
+		m.invRepo.EXPECT().ReadByCode(gomock.Any(), laptop.Code).Return(laptop, nil).Times(3)
+
+		gomock.InOrder(
+			m.notifRepo.EXPECT().SendEmail(gomock.Any(), alice.Fullname, alice.Email, loan.SubjectLoanOverdue, gomock.Any()).Return(nil),
+			m.notifRepo.EXPECT().SendEmail(gomock.Any(), alice.Fullname, alice.Email, loan.SubjectLoanOverdue, gomock.Any()).Return(nil),
+			m.notifRepo.EXPECT().SendEmail(gomock.Any(), alice.Fullname, alice.Email, loan.SubjectLoanOverdue, gomock.Any()).Return(errors.New("mailjet error")),
+		)
+
+		var updated []string
+		m.repo.EXPECT().MarkReminded(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, id string, at time.Time) error {
+			assert.True(t, at.After(remindedRecently))
+			updated = append(updated, id)
+			return nil
+		}).Times(2)
+
+		sent, err := loanService.SendOverdueReminders(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, sent)
+		assert.Equal(t, []string{"L1", "L4"}, updated)
+	})
+}
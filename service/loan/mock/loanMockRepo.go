@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service/loan/loanRepo.go
+
+// Package mock_loan is a generated GoMock package.
+package mock_loan
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	loan "github.com/pobyzaarif/belajarGo2/service/loan"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(ctx context.Context, loan loan.Loan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, loan)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(ctx, loan interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, loan)
+}
+
+// MarkReminded mocks base method.
+func (m *MockRepository) MarkReminded(ctx context.Context, id string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkReminded", ctx, id, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkReminded indicates an expected call of MarkReminded.
+func (mr *MockRepositoryMockRecorder) MarkReminded(ctx, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkReminded", reflect.TypeOf((*MockRepository)(nil).MarkReminded), ctx, id, at)
+}
+
+// ReadAll mocks base method.
+func (m *MockRepository) ReadAll(ctx context.Context, userID string, page, limit int) ([]loan.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAll", ctx, userID, page, limit)
+	ret0, _ := ret[0].([]loan.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAll indicates an expected call of ReadAll.
+func (mr *MockRepositoryMockRecorder) ReadAll(ctx, userID, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAll", reflect.TypeOf((*MockRepository)(nil).ReadAll), ctx, userID, page, limit)
+}
+
+// ReadByID mocks base method.
+func (m *MockRepository) ReadByID(ctx context.Context, id string) (loan.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByID", ctx, id)
+	ret0, _ := ret[0].(loan.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByID indicates an expected call of ReadByID.
+func (mr *MockRepositoryMockRecorder) ReadByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByID", reflect.TypeOf((*MockRepository)(nil).ReadByID), ctx, id)
+}
+
+// ReadOverdue mocks base method.
+func (m *MockRepository) ReadOverdue(ctx context.Context, userID string, now time.Time) ([]loan.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadOverdue", ctx, userID, now)
+	ret0, _ := ret[0].([]loan.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadOverdue indicates an expected call of ReadOverdue.
+func (mr *MockRepositoryMockRecorder) ReadOverdue(ctx, userID, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadOverdue", reflect.TypeOf((*MockRepository)(nil).ReadOverdue), ctx, userID, now)
+}
+
+// Return mocks base method.
+func (m *MockRepository) Return(ctx context.Context, id string, returnedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Return", ctx, id, returnedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Return indicates an expected call of Return.
+func (mr *MockRepositoryMockRecorder) Return(ctx, id, returnedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Return", reflect.TypeOf((*MockRepository)(nil).Return), ctx, id, returnedAt)
+}
@@ -5,6 +5,7 @@
 package mock_notification
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,15 +35,15 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 }
 
 // SendEmail mocks base method.
-func (m *MockRepository) SendEmail(toName, toEmail, subject, message string) error {
+func (m *MockRepository) SendEmail(ctx context.Context, toName, toEmail, subject, message string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendEmail", toName, toEmail, subject, message)
+	ret := m.ctrl.Call(m, "SendEmail", ctx, toName, toEmail, subject, message)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SendEmail indicates an expected call of SendEmail.
-func (mr *MockRepositoryMockRecorder) SendEmail(toName, toEmail, subject, message interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) SendEmail(ctx, toName, toEmail, subject, message interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEmail", reflect.TypeOf((*MockRepository)(nil).SendEmail), toName, toEmail, subject, message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEmail", reflect.TypeOf((*MockRepository)(nil).SendEmail), ctx, toName, toEmail, subject, message)
 }
@@ -1,5 +1,7 @@
 package notification
 
+import "context"
+
 type Repository interface {
-	SendEmail(toName, toEmail, subject, message string) (err error)
+	SendEmail(ctx context.Context, toName, toEmail, subject, message string) (err error)
 }
@@ -0,0 +1,10 @@
+package rbac
+
+import "github.com/pobyzaarif/belajarGo2/service/errs"
+
+var (
+	ErrRoleNotFound      = errs.New(errs.NotFound, "role not found")
+	ErrUnknownPermission = errs.New(errs.Validation, "unknown permission")
+	ErrOwnRole           = errs.New(errs.Forbidden, "you can't take roles:manage from your own role")
+	ErrPermissionDenied  = errs.New(errs.Forbidden, "you don't have permission to do this")
+)
@@ -0,0 +1,56 @@
+package rbac
+
+import (
+	"slices"
+	"time"
+)
+
+// Permissions route guards require, a role is given a set of them.
+const (
+	InventoryRead    = "inventory:read"
+	InventoryWrite   = "inventory:write"
+	InventoryDelete  = "inventory:delete"
+	LoansRead        = "loans:read"
+	LoansReadAll     = "loans:read_all"
+	LoansWrite       = "loans:write"
+	WorkOrdersManage = "work_orders:manage"
+	UsersUnlock      = "users:unlock"
+	UsersManage      = "users:manage"
+	RolesManage      = "roles:manage"
+)
+
+// Permissions are every permission there is.
+var Permissions = []string{
+	InventoryRead,
+	InventoryWrite,
+	InventoryDelete,
+	LoansRead,
+	LoansReadAll,
+	LoansWrite,
+	WorkOrdersManage,
+	UsersUnlock,
+	UsersManage,
+	RolesManage,
+}
+
+// DefaultPermissions are the permissions of a role until they are edited.
+var DefaultPermissions = map[string][]string{
+	"superadmin": slices.Clone(Permissions),
+	"admin":      {InventoryRead, InventoryWrite, LoansRead, LoansReadAll, LoansWrite, WorkOrdersManage, UsersUnlock},
+	"user":       {InventoryRead, LoansRead},
+}
+
+// Role is a role with its permissions. UpdatedAt is zero for a role that
+// still has its DefaultPermissions.
+type Role struct {
+	Name        string    `json:"name" bson:"name" gorm:"primaryKey"`
+	Permissions []string  `json:"permissions" bson:"permissions" gorm:"serializer:json"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero" bson:"updated_at" gorm:"autoUpdateTime:false"`
+}
+
+// Config tunes the service, zero values fall back to the defaults.
+type Config struct {
+	// 1 minute. How long a role's permissions are cached, an edit shows
+	// right away where the cache is shared, after that long elsewhere
+	CacheTTL time.Duration
+}
@@ -0,0 +1,11 @@
+package rbac
+
+import "context"
+
+// Repository stores the roles whose permissions were edited. Get returns
+// ErrRoleNotFound for a role that never was, Save creates or replaces it.
+type Repository interface {
+	Get(ctx context.Context, name string) (role Role, err error)
+	GetAll(ctx context.Context) (roles []Role, err error)
+	Save(ctx context.Context, role Role) (err error)
+}
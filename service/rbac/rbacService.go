@@ -0,0 +1,153 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	cache "github.com/pobyzaarif/go-cache"
+)
+
+const rolePrefixKey = "rbac:role:"
+
+type service struct {
+	logger    *slog.Logger
+	repo      Repository
+	cacheRepo cache.Repository
+	config    Config
+}
+
+type Service interface {
+	GetAll(ctx context.Context) (roles []Role, err error)
+	SetPermissions(ctx context.Context, adminID string, adminRole string, name string, permissions []string) (role Role, err error)
+	Authorize(ctx context.Context, role string, permissions ...string) (err error)
+}
+
+func NewService(
+	logger *slog.Logger,
+	repo Repository,
+	cacheRepo cache.Repository,
+	config Config,
+) Service {
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = time.Minute
+	}
+
+	return &service{
+		logger:    logger,
+		repo:      repo,
+		cacheRepo: cacheRepo,
+		config:    config,
+	}
+}
+
+// cachedRole is a role's permissions in the cache, Cached tells an empty set
+// of permissions apart from a miss.
+type cachedRole struct {
+	Permissions []string `json:"permissions"`
+	Cached      bool     `json:"cached"`
+}
+
+// GetAll returns every role with its permissions, the stored ones or the
+// defaults.
+func (s *service) GetAll(ctx context.Context) (roles []Role, err error) {
+	stored, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles = make([]Role, 0, len(user.Roles))
+	for _, name := range user.Roles {
+		role := Role{Name: name, Permissions: slices.Clone(DefaultPermissions[name])}
+		for _, r := range stored {
+			if r.Name == name {
+				role = r
+			}
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// SetPermissions replaces the permissions of role name, on behalf of adminID
+// whose role is adminRole. Admins can't take roles:manage from their own
+// role, nobody could give it back.
+func (s *service) SetPermissions(ctx context.Context, adminID string, adminRole string, name string, permissions []string) (role Role, err error) {
+	if !slices.Contains(user.Roles, name) {
+		return role, user.ErrInvalidRole
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(Permissions, permission) {
+			return role, ErrUnknownPermission
+		}
+	}
+	if name == adminRole && !slices.Contains(permissions, RolesManage) {
+		return role, ErrOwnRole
+	}
+
+	// Kept in the order of Permissions, without duplicates
+	granted := make([]string, 0, len(permissions))
+	for _, permission := range Permissions {
+		if slices.Contains(permissions, permission) {
+			granted = append(granted, permission)
+		}
+	}
+
+	role = Role{Name: name, Permissions: granted, UpdatedAt: time.Now()}
+	if err := s.repo.Save(ctx, role); err != nil {
+		return Role{}, err
+	}
+	s.cacheRepo.Delete(rolePrefixKey + name)
+	s.logger.Info("role permissions changed",
+		slog.String("audit", "role.permissions_changed"),
+		slog.String("role", name),
+		slog.Any("permissions", granted),
+		slog.String("by", adminID),
+	)
+
+	return role, nil
+}
+
+// Authorize returns ErrPermissionDenied unless role has every one of
+// permissions. An unknown role has none.
+func (s *service) Authorize(ctx context.Context, role string, permissions ...string) (err error) {
+	granted, err := s.permissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+// permissions returns the permissions of role, from the cache when they are
+// in it.
+func (s *service) permissions(ctx context.Context, name string) (permissions []string, err error) {
+	var cached cachedRole
+	if err := s.cacheRepo.Get(rolePrefixKey+name, &cached); err != nil {
+		s.logger.Error("rbac cache get error", slog.Any("error", err))
+	} else if cached.Cached {
+		return cached.Permissions, nil
+	}
+
+	role, err := s.repo.Get(ctx, name)
+	switch {
+	case errors.Is(err, ErrRoleNotFound):
+		permissions = DefaultPermissions[name]
+	case err != nil:
+		return nil, err
+	default:
+		permissions = role.Permissions
+	}
+
+	if err := s.cacheRepo.Set(rolePrefixKey+name, cachedRole{Permissions: permissions, Cached: true}, s.config.CacheTTL); err != nil {
+		s.logger.Error("rbac cache set error", slog.Any("error", err))
+	}
+	return permissions, nil
+}
@@ -0,0 +1,109 @@
+package rbac_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pobyzaarif/belajarGo2/service/rbac"
+	mock_rbac "github.com/pobyzaarif/belajarGo2/service/rbac/mock"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	cache "github.com/pobyzaarif/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+var loggerOption = slog.HandlerOptions{AddSource: true}
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
+
+func newService(t *testing.T, repo rbac.Repository) rbac.Service {
+	cacheRepo, err := cache.NewMemoryARCCacheRepository(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rbac.NewService(logger, repo, cacheRepo, rbac.Config{})
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_repo := mock_rbac.NewMockRepository(ctrl)
+	rbacService := newService(t, mock_repo)
+
+	// Roles never edited have their defaults, read once then cached
+	mock_repo.EXPECT().Get(gomock.Any(), "user").Return(rbac.Role{}, rbac.ErrRoleNotFound).Times(1)
+	assert.Nil(t, rbacService.Authorize(ctx, "user", rbac.InventoryRead))
+	assert.Nil(t, rbacService.Authorize(ctx, "user", rbac.InventoryRead, rbac.LoansRead))
+	assert.ErrorIs(t, rbacService.Authorize(ctx, "user", rbac.InventoryRead, rbac.InventoryWrite), rbac.ErrPermissionDenied)
+
+	// An edited role has what it was given, even nothing
+	mock_repo.EXPECT().Get(gomock.Any(), "admin").Return(rbac.Role{Name: "admin", Permissions: []string{}}, nil).Times(1)
+	assert.ErrorIs(t, rbacService.Authorize(ctx, "admin", rbac.InventoryRead), rbac.ErrPermissionDenied)
+	assert.ErrorIs(t, rbacService.Authorize(ctx, "admin", rbac.InventoryRead), rbac.ErrPermissionDenied)
+
+	// Superadmins are no exception, an unknown role has nothing
+	mock_repo.EXPECT().Get(gomock.Any(), "superadmin").Return(rbac.Role{Name: "superadmin", Permissions: []string{rbac.RolesManage}}, nil)
+	assert.ErrorIs(t, rbacService.Authorize(ctx, "superadmin", rbac.InventoryDelete), rbac.ErrPermissionDenied)
+	mock_repo.EXPECT().Get(gomock.Any(), "").Return(rbac.Role{}, rbac.ErrRoleNotFound)
+	assert.ErrorIs(t, rbacService.Authorize(ctx, "", rbac.InventoryRead), rbac.ErrPermissionDenied)
+
+	// A storage failure isn't a denial
+	mock_repo.EXPECT().Get(gomock.Any(), "guest").Return(rbac.Role{}, errors.New("db con error"))
+	err := rbacService.Authorize(ctx, "guest", rbac.InventoryRead)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, rbac.ErrPermissionDenied))
+}
+
+func TestSetPermissions(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_repo := mock_rbac.NewMockRepository(ctrl)
+	rbacService := newService(t, mock_repo)
+
+	_, err := rbacService.SetPermissions(ctx, "9", "superadmin", "guest", []string{rbac.InventoryRead})
+	assert.ErrorIs(t, err, user.ErrInvalidRole)
+	_, err = rbacService.SetPermissions(ctx, "9", "superadmin", "user", []string{"inventory:everything"})
+	assert.ErrorIs(t, err, rbac.ErrUnknownPermission)
+	_, err = rbacService.SetPermissions(ctx, "9", "superadmin", "superadmin", []string{rbac.UsersManage})
+	assert.ErrorIs(t, err, rbac.ErrOwnRole)
+
+	// Sorted and without duplicates, the cached permissions are dropped
+	mock_repo.EXPECT().Get(gomock.Any(), "user").Return(rbac.Role{}, rbac.ErrRoleNotFound)
+	assert.ErrorIs(t, rbacService.Authorize(ctx, "user", rbac.LoansWrite), rbac.ErrPermissionDenied)
+
+	want := []string{rbac.InventoryRead, rbac.LoansRead, rbac.LoansWrite}
+	mock_repo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, role rbac.Role) error {
+		assert.Equal(t, "user", role.Name)
+		assert.Equal(t, want, role.Permissions)
+		assert.False(t, role.UpdatedAt.IsZero())
+		return nil
+	})
+	role, err := rbacService.SetPermissions(ctx, "9", "superadmin", "user", []string{rbac.LoansWrite, rbac.InventoryRead, rbac.LoansRead, rbac.LoansWrite})
+	assert.Nil(t, err)
+	assert.Equal(t, want, role.Permissions)
+
+	mock_repo.EXPECT().Get(gomock.Any(), "user").Return(role, nil)
+	assert.Nil(t, rbacService.Authorize(ctx, "user", rbac.LoansWrite))
+}
+
+func TestGetAll(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_repo := mock_rbac.NewMockRepository(ctrl)
+	rbacService := newService(t, mock_repo)
+
+	edited := rbac.Role{Name: "admin", Permissions: []string{rbac.InventoryRead}}
+	mock_repo.EXPECT().GetAll(gomock.Any()).Return([]rbac.Role{edited}, nil)
+	roles, err := rbacService.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []rbac.Role{
+		{Name: "superadmin", Permissions: rbac.Permissions},
+		edited,
+		{Name: "user", Permissions: []string{rbac.InventoryRead, rbac.LoansRead}},
+	}, roles)
+}
@@ -0,0 +1,13 @@
+package transaction
+
+import "context"
+
+// Transactor runs fn as a single unit of work, every repository call made with
+// the ctx handed to fn commits or rolls back together. It's rolled back when fn
+// returns an error. Calls nested in an outer unit of work join it.
+//
+// A unit of work only spans repositories of the same backend, gorm and Mongo
+// writes can't be committed together.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error)
+}
@@ -0,0 +1,28 @@
+package user
+
+import "github.com/pobyzaarif/belajarGo2/service/errs"
+
+var (
+	ErrNotFound                = errs.New(errs.NotFound, "user not found")
+	ErrEmailRegistered         = errs.New(errs.Conflict, "email registered already")
+	ErrInvalidCredentials      = errs.New(errs.Unauthorized, "wrong email address or password")
+	ErrEmailNotVerified        = errs.New(errs.Unauthorized, "email address has not been verified")
+	ErrInvalidVerificationCode = errs.New(errs.Unauthorized, "invalid or expired url")
+	ErrRefreshTokenNotFound    = errs.New(errs.NotFound, "refresh token not found")
+	ErrInvalidRefreshToken     = errs.New(errs.Unauthorized, "invalid or expired refresh token")
+	ErrInvalidAccessToken      = errs.New(errs.Unauthorized, "invalid or expired access token")
+	ErrInvalidResetCode        = errs.New(errs.Unauthorized, "invalid or expired reset code")
+	ErrInvalidUnlockCode       = errs.New(errs.Unauthorized, "invalid or expired unlock code")
+	ErrLoginLocked             = errs.New(errs.TooManyRequests, "too many failed logins, try again later")
+	ErrMFANotFound             = errs.New(errs.NotFound, "two-factor authentication is not enabled")
+	ErrMFAEnabled              = errs.New(errs.Conflict, "two-factor authentication is enabled already")
+	ErrInvalidMFACode          = errs.New(errs.Unauthorized, "invalid two-factor authentication code")
+	ErrInvalidMFAToken         = errs.New(errs.Unauthorized, "invalid or expired two-factor authentication token")
+	ErrWrongPassword           = errs.New(errs.Unauthorized, "current password is wrong")
+	ErrInvalidEmailChangeCode  = errs.New(errs.Unauthorized, "invalid or expired email change code")
+	ErrInvalidRole             = errs.New(errs.Validation, "role must be one of superadmin, admin or user")
+	ErrInvalidFullname         = errs.New(errs.Validation, "fullname is required")
+	ErrOwnAccount              = errs.New(errs.Forbidden, "you can't do this to your own account")
+	ErrAccountDeactivated      = errs.New(errs.Forbidden, "account has been deactivated")
+	ErrMFARequired             = errs.New(errs.Forbidden, "two-factor authentication is required for your role")
+)
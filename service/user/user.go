@@ -1,5 +1,10 @@
 package user
 
+import "time"
+
+// Roles are the roles a user can have.
+var Roles = []string{"superadmin", "admin", "user"}
+
 type (
 	User struct {
 		ID              string `bson:"user_id"`
@@ -8,5 +13,108 @@ type (
 		Fullname        string
 		Role            string
 		IsEmailVerified bool `bson:"is_email_verified"`
+
+		// The last verification email, when it was sent and whether it went
+		// out, so support can see the failed deliveries
+		VerificationEmailStatus string     `bson:"verification_email_status"`
+		VerificationEmailSentAt *time.Time `bson:"verification_email_sent_at"`
+
+		// Set when an admin deactivated the account, it can't log in
+		DeactivatedAt *time.Time `bson:"deactivated_at"`
+	}
+
+	// Filter narrows a listing of users. Search matches a part of the email
+	// or the fullname, whatever the case, Role the role exactly.
+	Filter struct {
+		Search string
+		Role   string
+	}
+
+	// Tokens are what Login and Refresh hand out. The access token is a short
+	// lived JWT, the refresh token an opaque value traded once for new Tokens.
+	// When the user has two-factor authentication Login only returns an
+	// MFAToken, traded with a code by LoginMFA for the other ones.
+	Tokens struct {
+		AccessToken  string `json:"access_token,omitempty"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		ExpiresIn    int64  `json:"expires_in,omitempty"` // seconds the access token is valid
+		MFARequired  bool   `json:"mfa_required,omitempty"`
+		MFAToken     string `json:"mfa_token,omitempty"`
+	}
+
+	// AccessToken is what an access token claims, read back from a verified
+	// JWT. ID is its jti. MFAPending is set when the role of the user requires
+	// two-factor authentication they haven't enabled yet, the token is only
+	// good to enable it.
+	AccessToken struct {
+		ID         string
+		UserID     string
+		Role       string
+		IssuedAt   time.Time
+		ExpiresAt  time.Time
+		MFAPending bool
+	}
+
+	// MFA is the TOTP two-factor authentication of a user. Secret is
+	// encrypted, it is Enabled once a first code was confirmed. Only the
+	// sha256 of the unused recovery codes is kept. LastStep is the time step
+	// of the last code accepted, a code can't be used twice.
+	MFA struct {
+		UserID        string    `bson:"user_id" gorm:"primaryKey"`
+		Secret        string    `bson:"secret"`
+		Enabled       bool      `bson:"enabled"`
+		RecoveryCodes []string  `bson:"recovery_codes" gorm:"serializer:json"`
+		LastStep      int64     `bson:"last_step"`
+		CreatedAt     time.Time `bson:"created_at"`
 	}
+
+	// MFAEnrolment is what a user adds to an authenticator app, URI is shown
+	// as a QR code.
+	MFAEnrolment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+
+	// RefreshToken is a stored refresh token, only the sha256 of the value the
+	// user holds is kept. Tokens rotated from one another share a FamilyID.
+	RefreshToken struct {
+		ID        string    `bson:"token_id"`
+		FamilyID  string    `bson:"family_id"`
+		UserID    string    `bson:"user_id"`
+		TokenHash string    `bson:"token_hash"`
+		ExpiresAt time.Time `bson:"expires_at"`
+		CreatedAt time.Time `bson:"created_at"`
+		Used      bool      `bson:"used"`
+		Revoked   bool      `bson:"revoked"`
+	}
+)
+
+const (
+	VerificationEmailSent   = "sent"
+	VerificationEmailFailed = "failed"
 )
+
+// PasswordResetPath is where the API serves the password reset form, the
+// default page of the emailed reset links.
+const PasswordResetPath = "/users/password/reset"
+
+// Config tunes the service, zero values fall back to the defaults.
+type Config struct {
+	AccessTokenTTL  time.Duration // 15 minutes
+	RefreshTokenTTL time.Duration // 30 days
+
+	VerificationCodeTTL        time.Duration // 5 minutes
+	VerificationResendInterval time.Duration // 1 minute between two verification emails
+
+	MaxLoginFailures     int           // 5 failed logins of an account before it is locked
+	MaxIPLoginFailures   int           // 20 failed logins from a client ip before it is locked
+	LoginLockDuration    time.Duration // 1 minute, doubled by every failure past the limit
+	MaxLoginLockDuration time.Duration // 1 hour
+
+	MFAIssuer        string   // belajarGo2, the name authenticator apps show
+	MFARequiredRoles []string // roles that must enable two-factor authentication
+
+	// The page emailed password reset links open with ?code=, a frontend's or
+	// the form served at appDeploymentUrl + PasswordResetPath
+	PasswordResetURL string
+}
@@ -1,7 +1,56 @@
 package user
 
+import (
+	"context"
+	"time"
+
+	cache "github.com/pobyzaarif/go-cache"
+)
+
+// SecurityCacheRepository keeps the logged out tokens and the failed login
+// counters, it mustn't evict valid entries to make room for others. Incr adds
+// one to the counter under key atomically and returns it, the counter goes
+// away expiration after its last increment.
+type SecurityCacheRepository interface {
+	cache.Repository
+	Incr(key string, expiration time.Duration) (count int64, err error)
+}
+
+// Repository lookups (GetByID, GetByEmail) return ErrNotFound when nothing
+// matches, any other error is a real storage failure. ReadAll lists users by
+// email. UpdateEmail returns ErrEmailRegistered when another user has it.
 type Repository interface {
-	Create(user User) (err error)
-	GetByEmail(email string) (user User, err error)
-	UpdateEmailVerification(user User) (err error)
+	Create(ctx context.Context, user User) (err error)
+	GetByID(ctx context.Context, id string) (user User, err error)
+	GetByEmail(ctx context.Context, email string) (user User, err error)
+	ReadAll(ctx context.Context, filter Filter, page int, limit int) (users []User, err error)
+	UpdateEmailVerification(ctx context.Context, user User) (err error)
+	UpdatePassword(ctx context.Context, user User) (err error)
+	UpdateVerificationEmail(ctx context.Context, user User) (err error)
+	UpdateRole(ctx context.Context, user User) (err error)
+	UpdateFullname(ctx context.Context, user User) (err error)
+	UpdateEmail(ctx context.Context, user User) (err error)
+	UpdateDeactivation(ctx context.Context, user User) (err error)
+}
+
+// RefreshTokenRepository stores refresh tokens. GetByHash returns
+// ErrRefreshTokenNotFound when nothing matches. MarkUsed flags a token that
+// wasn't used yet and reports whether it did, so of two concurrent refreshes
+// with the same token only one wins. RevokeUser revokes every token of a user,
+// e.g. to log them out everywhere.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token RefreshToken) (err error)
+	GetByHash(ctx context.Context, hash string) (token RefreshToken, err error)
+	MarkUsed(ctx context.Context, id string) (ok bool, err error)
+	RevokeFamily(ctx context.Context, familyID string) (err error)
+	RevokeUser(ctx context.Context, userID string) (err error)
+}
+
+// MFARepository stores the two-factor authentication of users, one per user.
+// Get returns ErrMFANotFound when the user has none, Save creates or replaces
+// it.
+type MFARepository interface {
+	Get(ctx context.Context, userID string) (mfa MFA, err error)
+	Save(ctx context.Context, mfa MFA) (err error)
+	Delete(ctx context.Context, userID string) (err error)
 }
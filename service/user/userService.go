@@ -1,9 +1,17 @@
 package user
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,6 +19,8 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/pobyzaarif/belajarGo2/service/notification"
+	"github.com/pobyzaarif/belajarGo2/service/transaction"
+	"github.com/pobyzaarif/belajarGo2/util/totp"
 	"github.com/pobyzaarif/goshortcute"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -18,56 +28,166 @@ import (
 type service struct {
 	logger                  *slog.Logger
 	repo                    Repository
+	refreshTokenRepo        RefreshTokenRepository
+	mfaRepo                 MFARepository
+	cacheRepo               SecurityCacheRepository
+	transactor              transaction.Transactor
 	appDeploymentUrl        string
 	jwtSign                 string
 	appEmailVerificationKey string
 	notifRepo               notification.Repository
+	config                  Config
+	authenticator           *Authenticator
 }
 
 const (
-	verificationCodeTTL = 5
+	passwordResetCodeTTL = 30
+	unlockCodeTTL        = 60
+
+	defaultAccessTokenTTL             = 15 * time.Minute
+	defaultRefreshTokenTTL            = 30 * 24 * time.Hour
+	defaultVerificationCodeTTL        = 5 * time.Minute
+	defaultVerificationResendInterval = time.Minute
+	defaultMaxLoginFailures           = 5
+	defaultMaxIPLoginFailures         = 20
+	defaultLoginLockDuration          = time.Minute
+	defaultMaxLoginLockDuration       = time.Hour
+	defaultMFAIssuer                  = "belajarGo2"
+
+	mfaTokenTTL          = 5 * time.Minute
+	mfaSkew              = 1 // time steps of clock drift tolerated either way
+	mfaRecoveryCodeCount = 10
+
+	denylistTokenPrefixKey = "denylist:token:"
+	denylistUserPrefixKey  = "denylist:user:"
+	loginAccountPrefixKey  = "login:account:"
+	loginIPPrefixKey       = "login:ip:"
+	mfaUserPrefixKey       = "mfa:user:"
+	loginFailuresSuffixKey = ":failures"
+	loginLockSuffixKey     = ":locked_until"
 )
 
+// errRefreshTokenReused aborts the rotation of a token another refresh used
+// first, the family is revoked once the transaction is rolled back.
+var errRefreshTokenReused = errors.New("refresh token reused")
+
 type Service interface {
-	Register(user User) (id string, err error)
-	Login(username string, password string) (accessToken string, err error)
-	GetByEmail(email string) (user User, err error)
-	VerifyEmail(verificationCodeEncrypt string) (err error)
+	Register(ctx context.Context, user User) (id string, err error)
+	Login(ctx context.Context, username string, password string, ip string) (tokens Tokens, err error)
+	LoginMFA(ctx context.Context, mfaToken string, code string, ip string) (tokens Tokens, err error)
+	EnrollMFA(ctx context.Context, userID string) (enrolment MFAEnrolment, err error)
+	ConfirmMFA(ctx context.Context, userID string, code string) (recoveryCodes []string, err error)
+	DisableMFA(ctx context.Context, userID string, code string) (err error)
+	UnlockAccount(ctx context.Context, unlockCodeEncrypt string) (err error)
+	UnlockUser(ctx context.Context, adminID string, id string) (err error)
+	Refresh(ctx context.Context, refreshToken string) (tokens Tokens, err error)
+	Logout(ctx context.Context, token AccessToken, refreshToken string) (err error)
+	LogoutAll(ctx context.Context, token AccessToken) (err error)
+	Authenticate(ctx context.Context, signedToken string) (token AccessToken, err error)
+	IsRevoked(ctx context.Context, token AccessToken) (revoked bool, err error)
+	GetByEmail(ctx context.Context, email string) (user User, err error)
+	GetByID(ctx context.Context, id string) (user User, err error)
+	GetAll(ctx context.Context, filter Filter, page int, limit int) (users []User, err error)
+	ChangeRole(ctx context.Context, adminID string, id string, role string) (err error)
+	DeactivateUser(ctx context.Context, adminID string, id string) (err error)
+	ActivateUser(ctx context.Context, adminID string, id string) (err error)
+	VerifyUserEmail(ctx context.Context, adminID string, id string) (err error)
+	ResetUserPassword(ctx context.Context, adminID string, id string) (err error)
+	VerifyEmail(ctx context.Context, verificationCodeEncrypt string) (err error)
+	ResendVerification(ctx context.Context, email string) (err error)
+	ForgotPassword(ctx context.Context, email string) (err error)
+	ResetPassword(ctx context.Context, resetCodeEncrypt string, password string) (err error)
+	UpdateProfile(ctx context.Context, id string, fullname string) (err error)
+	ChangePassword(ctx context.Context, id string, currentPassword string, password string) (err error)
+	RequestEmailChange(ctx context.Context, id string, email string, password string) (err error)
+	ConfirmEmailChange(ctx context.Context, emailChangeCodeEncrypt string) (err error)
 }
 
 func NewService(
 	logger *slog.Logger,
 	repo Repository,
+	refreshTokenRepo RefreshTokenRepository,
+	mfaRepo MFARepository,
+	cacheRepo SecurityCacheRepository,
+	transactor transaction.Transactor,
 	appDeploymentUrl string,
 	jwtSign string,
 	appEmailVerificationKey string,
 	notifRepo notification.Repository,
+	config Config,
 ) Service {
+	if config.AccessTokenTTL <= 0 {
+		config.AccessTokenTTL = defaultAccessTokenTTL
+	}
+	if config.RefreshTokenTTL <= 0 {
+		config.RefreshTokenTTL = defaultRefreshTokenTTL
+	}
+	if config.VerificationCodeTTL <= 0 {
+		config.VerificationCodeTTL = defaultVerificationCodeTTL
+	}
+	if config.VerificationResendInterval <= 0 {
+		config.VerificationResendInterval = defaultVerificationResendInterval
+	}
+	if config.MaxLoginFailures <= 0 {
+		config.MaxLoginFailures = defaultMaxLoginFailures
+	}
+	if config.MaxIPLoginFailures <= 0 {
+		config.MaxIPLoginFailures = defaultMaxIPLoginFailures
+	}
+	if config.LoginLockDuration <= 0 {
+		config.LoginLockDuration = defaultLoginLockDuration
+	}
+	if config.MaxLoginLockDuration <= 0 {
+		config.MaxLoginLockDuration = defaultMaxLoginLockDuration
+	}
+	if config.MFAIssuer == "" {
+		config.MFAIssuer = defaultMFAIssuer
+	}
+	if config.PasswordResetURL == "" {
+		config.PasswordResetURL = appDeploymentUrl + PasswordResetPath
+	}
+
 	return &service{
 		logger:                  logger,
 		repo:                    repo,
+		refreshTokenRepo:        refreshTokenRepo,
+		mfaRepo:                 mfaRepo,
+		cacheRepo:               cacheRepo,
+		transactor:              transactor,
 		appDeploymentUrl:        appDeploymentUrl,
 		jwtSign:                 jwtSign,
+		authenticator:           NewAuthenticator(jwtSign, cacheRepo),
 		appEmailVerificationKey: appEmailVerificationKey,
 		notifRepo:               notifRepo,
+		config:                  config,
 	}
 }
 
 const (
 	SubjectRegisterAccount   = "Activate Your Account!"
 	EmailBodyRegisterAccount = `Halo, %v, Aktivasi akun anda dengan membuka tautan dibawah<br/><br/>%v<br/>catatan: link hanya berlaku %v menit`
+
+	SubjectAccountLocked   = "Your Account Has Been Locked"
+	EmailBodyAccountLocked = `Halo, %v, Akun anda dikunci sementara karena terlalu banyak percobaan login yang gagal, segera ganti kata sandi anda jika itu bukan anda. Buka kunci akun anda dengan membuka tautan dibawah<br/><br/>%v<br/>catatan: link hanya berlaku %v menit`
+
+	SubjectResetPassword   = "Reset Your Password"
+	EmailBodyResetPassword = `Halo, %v, Atur ulang kata sandi anda dengan membuka tautan dibawah<br/><br/>%v<br/>catatan: link hanya berlaku %v menit, abaikan email ini jika anda tidak memintanya`
+
+	SubjectChangeEmail   = "Confirm Your New Email Address"
+	EmailBodyChangeEmail = `Halo, %v, Konfirmasi alamat email baru anda dengan membuka tautan dibawah<br/><br/>%v<br/>catatan: link hanya berlaku %v menit, abaikan email ini jika anda tidak memintanya`
+
+	SubjectEmailChanged   = "Your Email Address Has Been Changed"
+	EmailBodyEmailChanged = `Halo, %v, Alamat email akun anda telah diganti menjadi %v, segera ganti kata sandi anda jika itu bukan anda`
 )
 
-func (s *service) Register(user User) (id string, err error) {
+func (s *service) Register(ctx context.Context, user User) (id string, err error) {
 	// Find user by email
-	getUser, err := s.repo.GetByEmail(user.Email)
-	if err != nil {
-		return
+	_, err = s.repo.GetByEmail(ctx, user.Email)
+	if err == nil {
+		return "", ErrEmailRegistered
 	}
-
-	if getUser.Email != "" {
-		err = errors.New("email registered already")
-		return
+	if !errors.Is(err, ErrNotFound) {
+		return "", err
 	}
 
 	// Hashing plain pass
@@ -80,36 +200,85 @@ func (s *service) Register(user User) (id string, err error) {
 	user.Password = string(encPassword)
 	user.Role = "user"
 
-	if err = s.repo.Create(user); err != nil {
-		return
+	timeNow := time.Now()
+	user.VerificationEmailStatus = VerificationEmailSent
+	user.VerificationEmailSentAt = &timeNow
+	if err = s.repo.Create(ctx, user); err != nil {
+		return "", err
+	}
+
+	// The account is kept when its activation email doesn't go out, the failure
+	// is recorded like ResendVerification does and the link can be resent
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		s.logger.Error("register send email err", slog.Any("err", err), slog.String("user_id", user.ID))
+		user.VerificationEmailStatus = VerificationEmailFailed
+		if err := s.repo.UpdateVerificationEmail(ctx, user); err != nil {
+			s.logger.Error("register update verification email err", slog.Any("err", err), slog.String("user_id", user.ID))
+		}
+	}
+
+	return user.ID, nil
+}
+
+// ResendVerification sends a new activation link to email, at most once every
+// VerificationResendInterval. It answers the same whether the email is
+// registered, verified already or throttled, and a failed delivery is only
+// recorded on the user.
+func (s *service) ResendVerification(ctx context.Context, email string) (err error) {
+	getUser, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("resend verification err", slog.Any("err", err))
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
+	if getUser.IsEmailVerified {
+		s.logger.Warn("resend verification err", slog.Any("err", "email verified already"))
+		return nil
+	}
 	timeNow := time.Now()
-	expAt := timeNow.Add(time.Duration(time.Minute * verificationCodeTTL)).Unix()
+	if sentAt := getUser.VerificationEmailSentAt; sentAt != nil && timeNow.Sub(*sentAt) < s.config.VerificationResendInterval {
+		s.logger.Warn("resend verification err", slog.Any("err", "resent too soon"), slog.String("user_id", getUser.ID))
+		return nil
+	}
+
+	getUser.VerificationEmailStatus = VerificationEmailSent
+	getUser.VerificationEmailSentAt = &timeNow
+	if err := s.sendVerificationEmail(ctx, getUser); err != nil {
+		s.logger.Error("resend verification err", slog.Any("err", err), slog.String("user_id", getUser.ID))
+		getUser.VerificationEmailStatus = VerificationEmailFailed
+	}
+	return s.repo.UpdateVerificationEmail(ctx, getUser)
+}
+
+// sendVerificationEmail emails user a link to verify their email address,
+// valid VerificationCodeTTL.
+func (s *service) sendVerificationEmail(ctx context.Context, user User) (err error) {
+	expAt := time.Now().Add(s.config.VerificationCodeTTL).Unix()
 
 	verificationCode := fmt.Sprintf("%v|%v", user.Email, expAt)
 	verificationCodeEncrypt, _ := goshortcute.AESCBCEncrypt([]byte(verificationCode), []byte(s.appEmailVerificationKey))
 	verifCode := goshortcute.StringtoBase64Encode(verificationCodeEncrypt)
 	activationLink := s.appDeploymentUrl + "/users/email-verification/" + verifCode
 
-	_ = s.notifRepo.SendEmail(user.Fullname, user.Email, SubjectRegisterAccount, fmt.Sprintf(EmailBodyRegisterAccount, user.Fullname, activationLink, verificationCodeTTL))
-
-	// Create user
-	return user.ID, nil
+	ttlMinutes := int(s.config.VerificationCodeTTL.Minutes())
+	return s.notifRepo.SendEmail(ctx, user.Fullname, user.Email, SubjectRegisterAccount, fmt.Sprintf(EmailBodyRegisterAccount, user.Fullname, activationLink, ttlMinutes))
 }
 
-func (s *service) VerifyEmail(verificationCodeEncrypt string) (err error) {
+func (s *service) VerifyEmail(ctx context.Context, verificationCodeEncrypt string) (err error) {
 	verifCodeDecode := goshortcute.StringtoBase64Decode(verificationCodeEncrypt)
 	verificationCodeDecrypt, err := goshortcute.AESCBCDecrypt([]byte(verifCodeDecode), []byte(s.appEmailVerificationKey))
 	if err != nil {
 		s.logger.Error("verify email err", slog.Any("err", err.Error()))
-		return errors.New("invalid or expired url")
+		return ErrInvalidVerificationCode
 	}
 
 	verificationCode := strings.Split(verificationCodeDecrypt, "|")
 	if len(verificationCode) != 2 {
 		s.logger.Error("verify email err", slog.Any("err", verificationCodeDecrypt))
-		return errors.New("invalid or expired url")
+		return ErrInvalidVerificationCode
 	}
 
 	email := verificationCode[0]
@@ -118,14 +287,18 @@ func (s *service) VerifyEmail(verificationCodeEncrypt string) (err error) {
 	ts, err := strconv.ParseInt(expAtStr, 10, 64)
 	if err != nil {
 		s.logger.Error("verify email err", slog.Any("err", verificationCodeDecrypt))
-		return errors.New("invalid or expired url")
+		return ErrInvalidVerificationCode
 	}
 	expAt := time.Unix(ts, 0)
 	if time.Now().After(expAt) {
-		return errors.New("invalid or expired url")
+		return ErrInvalidVerificationCode
 	}
 
-	getUser, err := s.repo.GetByEmail(email)
+	getUser, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("verify email err", slog.Any("err", err))
+		return ErrInvalidVerificationCode
+	}
 	if err != nil {
 		s.logger.Error("verify email err", slog.Any("err", err))
 		return err
@@ -133,11 +306,11 @@ func (s *service) VerifyEmail(verificationCodeEncrypt string) (err error) {
 
 	if getUser.IsEmailVerified {
 		s.logger.Warn("verify email err", slog.Any("err", "email verified already"))
-		return errors.New("invalid or expired url")
+		return ErrInvalidVerificationCode
 	}
 
 	getUser.IsEmailVerified = true
-	if err := s.repo.UpdateEmailVerification(getUser); err != nil {
+	if err := s.repo.UpdateEmailVerification(ctx, getUser); err != nil {
 		s.logger.Error("verify email err", slog.Any("err", err))
 		return err
 	}
@@ -145,49 +318,745 @@ func (s *service) VerifyEmail(verificationCodeEncrypt string) (err error) {
 	return nil
 }
 
-func (s *service) Login(email string, password string) (accessToken string, err error) {
-	getUser, err := s.repo.GetByEmail(email)
+// ForgotPassword emails a link to reset the password of email. It answers the
+// same whether the email is registered or not, so it can't be used to find
+// out which ones are.
+func (s *service) ForgotPassword(ctx context.Context, email string) (err error) {
+	getUser, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("forgot password err", slog.Any("err", err))
+		return nil
+	}
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	if err := s.sendPasswordResetEmail(ctx, getUser); err != nil {
+		// Failing only for registered emails would tell them apart
+		s.logger.Error("forgot password err", slog.Any("err", err))
+	}
+	return nil
+}
+
+func (s *service) sendPasswordResetEmail(ctx context.Context, user User) (err error) {
+	expAt := time.Now().Add(time.Duration(time.Minute * passwordResetCodeTTL)).Unix()
+
+	// The code is bound to the current password, resetting it once spends it
+	resetCode := fmt.Sprintf("%v|%v|%v", user.Email, expAt, passwordFingerprint(user.Password))
+	resetCodeEncrypt, _ := goshortcute.AESCBCEncrypt([]byte(resetCode), []byte(s.appEmailVerificationKey))
+	code := goshortcute.StringtoBase64Encode(resetCodeEncrypt)
+	code += "." + s.signCode("password-reset", code)
+	resetLink := s.config.PasswordResetURL + "?code=" + url.QueryEscape(code)
+
+	return s.notifRepo.SendEmail(ctx, user.Fullname, user.Email, SubjectResetPassword, fmt.Sprintf(EmailBodyResetPassword, user.Fullname, resetLink, passwordResetCodeTTL))
+}
+
+// ResetPassword sets password for the user of a code sent by ForgotPassword
+// and logs them out everywhere.
+func (s *service) ResetPassword(ctx context.Context, resetCodeEncrypt string, password string) (err error) {
+	resetCodeEncrypt, signature, _ := strings.Cut(resetCodeEncrypt, ".")
+	if !hmac.Equal([]byte(signature), []byte(s.signCode("password-reset", resetCodeEncrypt))) {
+		s.logger.Warn("reset password err", slog.Any("err", "invalid signature"))
+		return ErrInvalidResetCode
+	}
+
+	resetCodeDecode := goshortcute.StringtoBase64Decode(resetCodeEncrypt)
+	resetCodeDecrypt, err := goshortcute.AESCBCDecrypt([]byte(resetCodeDecode), []byte(s.appEmailVerificationKey))
+	if err != nil {
+		s.logger.Error("reset password err", slog.Any("err", err.Error()))
+		return ErrInvalidResetCode
+	}
+
+	resetCode := strings.Split(resetCodeDecrypt, "|")
+	if len(resetCode) != 3 {
+		s.logger.Error("reset password err", slog.Any("err", "malformed reset code"))
+		return ErrInvalidResetCode
+	}
+
+	ts, err := strconv.ParseInt(resetCode[1], 10, 64)
+	if err != nil {
+		s.logger.Error("reset password err", slog.Any("err", err))
+		return ErrInvalidResetCode
+	}
+	if time.Now().After(time.Unix(ts, 0)) {
+		return ErrInvalidResetCode
+	}
+
+	getUser, err := s.repo.GetByEmail(ctx, resetCode[0])
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("reset password err", slog.Any("err", err))
+		return ErrInvalidResetCode
+	}
+	if err != nil {
+		return err
+	}
+
+	if resetCode[2] != passwordFingerprint(getUser.Password) {
+		s.logger.Warn("reset password err", slog.Any("err", "reset code used already"))
+		return ErrInvalidResetCode
+	}
+
+	encPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	getUser.Password = string(encPassword)
+	if err := s.repo.UpdatePassword(ctx, getUser); err != nil {
+		s.logger.Error("reset password err", slog.Any("err", err))
+		return err
+	}
+
+	return s.revokeSessions(ctx, getUser.ID)
+}
+
+// signCode signs a code sent by email, purpose keeps a code of one kind from
+// being used as another.
+func (s *service) signCode(purpose string, code string) string {
+	mac := hmac.New(sha256.New, []byte(s.appEmailVerificationKey))
+	mac.Write([]byte(purpose + "|" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// passwordFingerprint identifies a password hash without revealing it.
+func passwordFingerprint(passwordHash string) string {
+	return hashToken(passwordHash)[:16]
+}
+
+// Login checks the credentials of email. Failures are counted per account and
+// per client ip, past the limits they are locked out for LoginLockDuration,
+// doubled by every further failure up to MaxLoginLockDuration.
+func (s *service) Login(ctx context.Context, email string, password string, ip string) (tokens Tokens, err error) {
+	accountKey := loginAccountPrefixKey + strings.ToLower(email)
+	ipKey := loginIPPrefixKey + ip
+
+	timeNow := time.Now()
+	if timeNow.Before(s.loginLockedUntil(accountKey)) || (ip != "" && timeNow.Before(s.loginLockedUntil(ipKey))) {
+		return tokens, ErrLoginLocked
+	}
+
+	getUser, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		// Same answer as a wrong password, don't reveal which emails exist
+		s.loginFailed(ctx, email, ip, nil)
+		return tokens, ErrInvalidCredentials
+	}
+	if err != nil {
+		return tokens, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(getUser.Password), []byte(password)); err != nil {
 		s.logger.Error("login err", slog.Any("err", err.Error()))
+		s.loginFailed(ctx, email, ip, &getUser)
 
-		err = errors.New("wrong email address or password")
-		return "", err
+		return tokens, ErrInvalidCredentials
 	}
 
 	if !getUser.IsEmailVerified {
-		err = errors.New("email address has not been verified")
+		return tokens, ErrEmailNotVerified
+	}
+	if getUser.DeactivatedAt != nil {
+		return tokens, ErrAccountDeactivated
+	}
+
+	mfa, err := s.mfaRepo.Get(ctx, getUser.ID)
+	if err != nil && !errors.Is(err, ErrMFANotFound) {
+		return tokens, err
+	}
+	if mfa.Enabled {
+		// The failures are only forgotten once the code is right too, the
+		// password alone doesn't buy more guesses of it
+		mfaToken, err := s.generateMFAToken(getUser.ID)
+		if err != nil {
+			return tokens, err
+		}
+		return Tokens{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+	s.clearLoginFailures(accountKey)
+
+	// Every login starts a new refresh token family
+	return s.issueTokens(ctx, getUser, uuid.NewString())
+}
+
+// LoginMFA completes the Login of a user with two-factor authentication,
+// mfaToken is what Login returned and code one of their authenticator app or
+// a recovery code. Wrong codes count as failed logins.
+func (s *service) LoginMFA(ctx context.Context, mfaToken string, code string, ip string) (tokens Tokens, err error) {
+	userID, err := s.parseMFAToken(mfaToken)
+	if err != nil {
+		s.logger.Warn("login mfa err", slog.Any("err", err))
+		return tokens, ErrInvalidMFAToken
+	}
+
+	getUser, err := s.repo.GetByID(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return tokens, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return tokens, err
+	}
+
+	accountKey := loginAccountPrefixKey + strings.ToLower(getUser.Email)
+	timeNow := time.Now()
+	if timeNow.Before(s.loginLockedUntil(accountKey)) || (ip != "" && timeNow.Before(s.loginLockedUntil(loginIPPrefixKey+ip))) {
+		return tokens, ErrLoginLocked
+	}
+
+	mfa, err := s.mfaRepo.Get(ctx, getUser.ID)
+	if errors.Is(err, ErrMFANotFound) || (err == nil && !mfa.Enabled) {
+		return tokens, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return tokens, err
+	}
+
+	if !s.checkMFACode(&mfa, code) {
+		s.loginFailed(ctx, getUser.Email, ip, &getUser)
+		return tokens, ErrInvalidMFACode
+	}
+	if err := s.mfaRepo.Save(ctx, mfa); err != nil {
+		return tokens, err
+	}
+	s.clearLoginFailures(accountKey)
+
+	return s.issueTokens(ctx, getUser, uuid.NewString())
+}
+
+// EnrollMFA starts the two-factor authentication of a user with a new
+// secret, it is only enabled once ConfirmMFA got a code of it. Enrolling
+// again before that replaces the secret.
+func (s *service) EnrollMFA(ctx context.Context, userID string) (enrolment MFAEnrolment, err error) {
+	getUser, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return enrolment, err
+	}
+
+	mfa, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil && !errors.Is(err, ErrMFANotFound) {
+		return enrolment, err
+	}
+	if mfa.Enabled {
+		return enrolment, ErrMFAEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return enrolment, err
+	}
+	secretEncrypt, err := goshortcute.AESCBCEncrypt([]byte(secret), []byte(s.appEmailVerificationKey))
+	if err != nil {
+		return enrolment, err
+	}
+
+	err = s.mfaRepo.Save(ctx, MFA{
+		UserID:    userID,
+		Secret:    goshortcute.StringtoBase64Encode(secretEncrypt),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return enrolment, err
+	}
+
+	return MFAEnrolment{
+		Secret: secret,
+		URI:    totp.URI(s.config.MFAIssuer, getUser.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables the two-factor authentication EnrollMFA started, with a
+// first code of the authenticator app. It returns the recovery codes, each
+// one works once instead of a code and they aren't shown again. Wrong codes
+// are counted like failed logins, see mfaCodeFailed.
+func (s *service) ConfirmMFA(ctx context.Context, userID string, code string) (recoveryCodes []string, err error) {
+	if time.Now().Before(s.loginLockedUntil(mfaUserPrefixKey + userID)) {
+		return nil, ErrLoginLocked
+	}
+
+	mfa, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAEnabled
+	}
+
+	step, ok := s.validateTOTP(mfa, code)
+	if !ok {
+		s.mfaCodeFailed(userID)
+		return nil, ErrInvalidMFACode
+	}
+
+	mfa.RecoveryCodes = nil
+	for range mfaRecoveryCodeCount {
+		secret := make([]byte, 5)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		recoveryCode := hex.EncodeToString(secret)
+		recoveryCode = recoveryCode[:5] + "-" + recoveryCode[5:]
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		mfa.RecoveryCodes = append(mfa.RecoveryCodes, hashToken(recoveryCode))
+	}
+	mfa.Enabled = true
+	mfa.LastStep = step
+
+	if err := s.mfaRepo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+	s.clearLoginFailures(mfaUserPrefixKey + userID)
+	s.logger.Info("mfa enabled", slog.String("audit", "mfa.enabled"), slog.String("user_id", userID))
+
+	return recoveryCodes, nil
+}
+
+// DisableMFA turns the two-factor authentication of a user off, with a code
+// of the authenticator app or a recovery code. A role that requires it can't.
+// Wrong codes are counted like in ConfirmMFA.
+func (s *service) DisableMFA(ctx context.Context, userID string, code string) (err error) {
+	if time.Now().Before(s.loginLockedUntil(mfaUserPrefixKey + userID)) {
+		return ErrLoginLocked
+	}
+
+	getUser, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(s.config.MFARequiredRoles, getUser.Role) {
+		return ErrMFARequired
+	}
+
+	mfa, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !mfa.Enabled {
+		return ErrMFANotFound
+	}
+	if !s.checkMFACode(&mfa, code) {
+		s.mfaCodeFailed(userID)
+		return ErrInvalidMFACode
+	}
+
+	if err := s.mfaRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	s.clearLoginFailures(mfaUserPrefixKey + userID)
+	s.logger.Info("mfa disabled", slog.String("audit", "mfa.disabled"), slog.String("user_id", userID))
+
+	return nil
+}
+
+// mfaCodeFailed records a wrong code given to ConfirmMFA or DisableMFA. The
+// user is locked out of both like an account past MaxLoginFailures, a stolen
+// access token can't be used to guess the code turning MFA off.
+func (s *service) mfaCodeFailed(userID string) {
+	attempts, locked := s.recordLoginFailure(mfaUserPrefixKey+userID, s.config.MaxLoginFailures)
+	if locked {
+		s.logger.Warn("mfa locked",
+			slog.String("audit", "mfa.locked"),
+			slog.String("user_id", userID),
+			slog.Int("failures", attempts.Failures),
+			slog.Time("locked_until", attempts.LockedUntil),
+		)
+	}
+}
+
+// checkMFACode tells whether code is a code of mfa not used yet or one of its
+// recovery codes, which is then spent. mfa has to be saved when it is.
+func (s *service) checkMFACode(mfa *MFA, code string) bool {
+	if step, ok := s.validateTOTP(*mfa, code); ok {
+		if step <= mfa.LastStep {
+			return false
+		}
+		mfa.LastStep = step
+		return true
+	}
+
+	recoveryCodeHash := hashToken(strings.ToLower(strings.TrimSpace(code)))
+	for i, hash := range mfa.RecoveryCodes {
+		if hmac.Equal([]byte(hash), []byte(recoveryCodeHash)) {
+			// A new slice, the one read may be shared with the repository
+			mfa.RecoveryCodes = append(append([]string{}, mfa.RecoveryCodes[:i]...), mfa.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) validateTOTP(mfa MFA, code string) (step int64, ok bool) {
+	secretDecode := goshortcute.StringtoBase64Decode(mfa.Secret)
+	secret, err := goshortcute.AESCBCDecrypt([]byte(secretDecode), []byte(s.appEmailVerificationKey))
+	if err != nil {
+		s.logger.Error("mfa secret err", slog.Any("err", err.Error()), slog.String("user_id", mfa.UserID))
+		return 0, false
+	}
+	return totp.Validate(secret, strings.TrimSpace(code), time.Now(), mfaSkew)
+}
+
+// mfaPending tells whether the role of user requires two-factor
+// authentication they haven't enabled yet.
+func (s *service) mfaPending(ctx context.Context, user User) (pending bool, err error) {
+	if !slices.Contains(s.config.MFARequiredRoles, user.Role) {
+		return false, nil
+	}
+
+	mfa, err := s.mfaRepo.Get(ctx, user.ID)
+	if errors.Is(err, ErrMFANotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !mfa.Enabled, nil
+}
+
+// mfaTokenKey signs the tokens of LoginMFA, they can't pass for an access
+// token.
+func (s *service) mfaTokenKey() []byte {
+	return []byte("mfa|" + s.jwtSign)
+}
+
+func (s *service) generateMFAToken(userID string) (signedToken string, err error) {
+	timeNow := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(timeNow),
+		ExpiresAt: jwt.NewNumericDate(timeNow.Add(mfaTokenTTL)),
+	})
+	return token.SignedString(s.mfaTokenKey())
+}
+
+func (s *service) parseMFAToken(mfaToken string) (userID string, err error) {
+	claims := jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(mfaToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		return s.mfaTokenKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
 		return "", err
 	}
+	if claims.Subject == "" {
+		return "", errors.New("mfa token without subject")
+	}
+	return claims.Subject, nil
+}
+
+type loginAttempts struct {
+	Failures    int
+	LockedUntil time.Time
+}
 
-	token, err := s.generateToken(s.jwtSign, getUser.ID, getUser.Role)
+// loginLockedUntil returns until when key is locked out, zero when it isn't.
+func (s *service) loginLockedUntil(key string) (lockedUntil time.Time) {
+	if err := s.cacheRepo.Get(key+loginLockSuffixKey, &lockedUntil); err != nil {
+		s.logger.Error("login attempts err", slog.Any("err", err))
+	}
+	return
+}
+
+// clearLoginFailures forgets the failures under key and lifts its lock.
+func (s *service) clearLoginFailures(key string) {
+	s.cacheRepo.Delete(key + loginFailuresSuffixKey)
+	s.cacheRepo.Delete(key + loginLockSuffixKey)
+}
+
+// recordLoginFailure counts a failure under key and locks it from the max-th
+// one on. The count is incremented atomically, concurrent failures are all
+// counted, and forgotten MaxLoginLockDuration after the last one.
+func (s *service) recordLoginFailure(key string, max int) (attempts loginAttempts, locked bool) {
+	failures, err := s.cacheRepo.Incr(key+loginFailuresSuffixKey, s.config.MaxLoginLockDuration)
+	if err != nil {
+		s.logger.Error("login attempts err", slog.Any("err", err))
+		return attempts, false
+	}
+	attempts.Failures = int(failures)
+
+	if over := attempts.Failures - max; over >= 0 {
+		lock := s.config.MaxLoginLockDuration
+		if over < 32 && s.config.LoginLockDuration<<over < lock {
+			lock = s.config.LoginLockDuration << over
+		}
+		attempts.LockedUntil = time.Now().Add(lock)
+		locked = true
+
+		if err := s.cacheRepo.Set(key+loginLockSuffixKey, attempts.LockedUntil, lock); err != nil {
+			s.logger.Error("login attempts err", slog.Any("err", err))
+		}
+	}
+	return attempts, locked
+}
+
+// loginFailed records a failed login of email from ip, getUser is nil when no
+// account has that email. The account owner is emailed an unlock link the
+// first time it gets locked.
+func (s *service) loginFailed(ctx context.Context, email string, ip string, getUser *User) {
+	attempts, locked := s.recordLoginFailure(loginAccountPrefixKey+strings.ToLower(email), s.config.MaxLoginFailures)
+	if locked {
+		s.logger.Warn("account locked",
+			slog.String("audit", "login.account_locked"),
+			slog.String("email", email),
+			slog.String("ip", ip),
+			slog.Int("failures", attempts.Failures),
+			slog.Time("locked_until", attempts.LockedUntil),
+		)
+		if getUser != nil && attempts.Failures == s.config.MaxLoginFailures {
+			s.sendUnlockEmail(ctx, *getUser)
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+	attempts, locked = s.recordLoginFailure(loginIPPrefixKey+ip, s.config.MaxIPLoginFailures)
+	if locked {
+		s.logger.Warn("ip locked",
+			slog.String("audit", "login.ip_locked"),
+			slog.String("ip", ip),
+			slog.Int("failures", attempts.Failures),
+			slog.Time("locked_until", attempts.LockedUntil),
+		)
+	}
+}
+
+func (s *service) sendUnlockEmail(ctx context.Context, user User) {
+	expAt := time.Now().Add(time.Duration(time.Minute * unlockCodeTTL)).Unix()
+
+	unlockCode := fmt.Sprintf("%v|%v", user.Email, expAt)
+	unlockCodeEncrypt, _ := goshortcute.AESCBCEncrypt([]byte(unlockCode), []byte(s.appEmailVerificationKey))
+	code := goshortcute.StringtoBase64Encode(unlockCodeEncrypt)
+	code += "." + s.signCode("account-unlock", code)
+	unlockLink := s.appDeploymentUrl + "/users/unlock?code=" + url.QueryEscape(code)
+
+	err := s.notifRepo.SendEmail(ctx, user.Fullname, user.Email, SubjectAccountLocked, fmt.Sprintf(EmailBodyAccountLocked, user.Fullname, unlockLink, unlockCodeTTL))
+	if err != nil {
+		s.logger.Error("send unlock email err", slog.Any("err", err), slog.String("user_id", user.ID))
+	}
+}
+
+// UnlockAccount lifts the lock of the account of a code emailed when it was
+// locked. The failures from its client ip still count.
+func (s *service) UnlockAccount(ctx context.Context, unlockCodeEncrypt string) (err error) {
+	unlockCodeEncrypt, signature, _ := strings.Cut(unlockCodeEncrypt, ".")
+	if !hmac.Equal([]byte(signature), []byte(s.signCode("account-unlock", unlockCodeEncrypt))) {
+		s.logger.Warn("unlock account err", slog.Any("err", "invalid signature"))
+		return ErrInvalidUnlockCode
+	}
+
+	unlockCodeDecode := goshortcute.StringtoBase64Decode(unlockCodeEncrypt)
+	unlockCodeDecrypt, err := goshortcute.AESCBCDecrypt([]byte(unlockCodeDecode), []byte(s.appEmailVerificationKey))
+	if err != nil {
+		s.logger.Error("unlock account err", slog.Any("err", err.Error()))
+		return ErrInvalidUnlockCode
+	}
+
+	email, expAtStr, ok := strings.Cut(unlockCodeDecrypt, "|")
+	if !ok {
+		s.logger.Error("unlock account err", slog.Any("err", "malformed unlock code"))
+		return ErrInvalidUnlockCode
+	}
+	ts, err := strconv.ParseInt(expAtStr, 10, 64)
+	if err != nil {
+		s.logger.Error("unlock account err", slog.Any("err", err))
+		return ErrInvalidUnlockCode
+	}
+	if time.Now().After(time.Unix(ts, 0)) {
+		return ErrInvalidUnlockCode
+	}
+
+	s.clearLoginFailures(loginAccountPrefixKey + strings.ToLower(email))
+	s.logger.Info("account unlocked",
+		slog.String("audit", "login.account_unlocked"),
+		slog.String("email", email),
+		slog.String("by", "email"),
+	)
+	return nil
+}
+
+// UnlockUser lifts the lock of the account of user id, on behalf of adminID.
+func (s *service) UnlockUser(ctx context.Context, adminID string, id string) (err error) {
+	getUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.clearLoginFailures(loginAccountPrefixKey + strings.ToLower(getUser.Email))
+	s.clearLoginFailures(mfaUserPrefixKey + getUser.ID)
+	s.logger.Info("account unlocked",
+		slog.String("audit", "login.account_unlocked"),
+		slog.String("email", getUser.Email),
+		slog.String("by", adminID),
+	)
+	return nil
+}
+
+// Refresh trades a refresh token for new Tokens, the old one can't be used
+// again. Using it again anyway means it may have been stolen, its whole
+// family is revoked and the user has to log in again.
+func (s *service) Refresh(ctx context.Context, refreshToken string) (tokens Tokens, err error) {
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return tokens, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return tokens, err
+	}
+
+	if stored.Revoked || time.Now().After(stored.ExpiresAt) {
+		return tokens, ErrInvalidRefreshToken
+	}
+	if stored.Used {
+		return tokens, s.revokeFamily(ctx, stored)
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		ok, err := s.refreshTokenRepo.MarkUsed(ctx, stored.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errRefreshTokenReused
+		}
+
+		getUser, err := s.repo.GetByID(ctx, stored.UserID)
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		tokens, err = s.issueTokens(ctx, getUser, stored.FamilyID)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		return Tokens{}, s.revokeFamily(ctx, stored)
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return tokens, nil
+}
+
+func (s *service) revokeFamily(ctx context.Context, token RefreshToken) (err error) {
+	s.logger.Warn("refresh token reused, revoking its family", slog.String("user_id", token.UserID), slog.String("family_id", token.FamilyID))
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+// Logout revokes token until it expires and, when given, the family of
+// refreshToken. A refresh token that isn't the user's is ignored.
+func (s *service) Logout(ctx context.Context, token AccessToken, refreshToken string) (err error) {
+	if ttl := time.Until(token.ExpiresAt); ttl > 0 {
+		if err := s.cacheRepo.Set(denylistTokenPrefixKey+token.ID, true, ttl); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) || (err == nil && stored.UserID != token.UserID) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID)
+}
+
+// LogoutAll revokes every token of the user of token, on every device. The
+// access tokens issued up to now are denied for as long as any of them can
+// still be valid, the refresh tokens are revoked for good.
+func (s *service) LogoutAll(ctx context.Context, token AccessToken) (err error) {
+	return s.revokeSessions(ctx, token.UserID)
+}
+
+func (s *service) revokeSessions(ctx context.Context, userID string) (err error) {
+	// Microseconds like the iat_us claim, a login in the same second as the
+	// revocation but after it keeps its token
+	err = s.cacheRepo.Set(denylistUserPrefixKey+userID, time.Now().UnixMicro(), s.config.AccessTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeUser(ctx, userID)
+}
+
+// Authenticate verifies a signed access token, see Authenticator.
+func (s *service) Authenticate(ctx context.Context, signedToken string) (token AccessToken, err error) {
+	return s.authenticator.Authenticate(ctx, signedToken)
+}
+
+// IsRevoked reports whether token was logged out, by Logout or LogoutAll.
+func (s *service) IsRevoked(ctx context.Context, token AccessToken) (revoked bool, err error) {
+	return s.authenticator.IsRevoked(ctx, token)
+}
+
+// issueTokens signs an access token for user and stores a new refresh token
+// in familyID.
+func (s *service) issueTokens(ctx context.Context, user User, familyID string) (tokens Tokens, err error) {
+	if user.DeactivatedAt != nil {
+		return tokens, ErrAccountDeactivated
+	}
+
+	mfaPending, err := s.mfaPending(ctx, user)
+	if err != nil {
+		return tokens, err
+	}
+
+	accessToken, err := s.generateToken(s.jwtSign, user.ID, user.Role, mfaPending)
 	if err != nil {
 		s.logger.Error("generate token err", slog.Any("err", err.Error()))
 
 		err = errors.New("generate token error")
-		return "", err
+		return tokens, err
 	}
 
-	return token, err
-}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return tokens, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
 
-func (s *service) generateToken(jwtSign string, id string, role string) (signedToken string, err error) {
-	type jwtClaims struct {
-		ID   string `json:"id"`
-		Role string `json:"role"`
-		jwt.RegisteredClaims
+	timeNow := time.Now()
+	err = s.refreshTokenRepo.Create(ctx, RefreshToken{
+		ID:        uuid.NewString(),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: timeNow.Add(s.config.RefreshTokenTTL),
+		CreatedAt: timeNow,
+	})
+	if err != nil {
+		return tokens, err
 	}
 
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// hashToken is what refresh tokens are stored and looked up by, a leaked
+// table can't be used to refresh.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *service) generateToken(jwtSign string, id string, role string, mfaPending bool) (signedToken string, err error) {
 	timeNow := time.Now()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims{
-		ID:   id,
-		Role: role,
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims{
+		UserID:        id,
+		Role:          role,
+		MFAPending:    mfaPending,
+		IssuedAtMicro: timeNow.UnixMicro(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			IssuedAt:  jwt.NewNumericDate(timeNow),
-			ExpiresAt: jwt.NewNumericDate(timeNow.Add(time.Hour * 24)),
+			ExpiresAt: jwt.NewNumericDate(timeNow.Add(s.config.AccessTokenTTL)),
 		},
 	})
 
@@ -199,6 +1068,291 @@ func (s *service) generateToken(jwtSign string, id string, role string) (signedT
 	return signedToken, nil
 }
 
-func (s *service) GetByEmail(email string) (user User, err error) {
-	return s.repo.GetByEmail(email)
+func (s *service) GetByEmail(ctx context.Context, email string) (user User, err error) {
+	return s.repo.GetByEmail(ctx, email)
+}
+
+func (s *service) GetByID(ctx context.Context, id string) (user User, err error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *service) GetAll(ctx context.Context, filter Filter, page int, limit int) (users []User, err error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.Role != "" && !slices.Contains(Roles, filter.Role) {
+		return nil, ErrInvalidRole
+	}
+	return s.repo.ReadAll(ctx, filter, page, limit)
+}
+
+// ChangeRole gives user id role, on behalf of adminID. Their tokens still
+// claim the old role, they are logged out everywhere.
+func (s *service) ChangeRole(ctx context.Context, adminID string, id string, role string) (err error) {
+	if !slices.Contains(Roles, role) {
+		return ErrInvalidRole
+	}
+	getUser, err := s.getOtherUser(ctx, adminID, id)
+	if err != nil {
+		return err
+	}
+	if getUser.Role == role {
+		return nil
+	}
+
+	getUser.Role = role
+	if err := s.repo.UpdateRole(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("role changed",
+		slog.String("audit", "user.role_changed"),
+		slog.String("user_id", id),
+		slog.String("role", role),
+		slog.String("by", adminID),
+	)
+
+	return s.revokeSessions(ctx, id)
+}
+
+// DeactivateUser keeps user id from logging in and logs them out everywhere,
+// on behalf of adminID, until ActivateUser.
+func (s *service) DeactivateUser(ctx context.Context, adminID string, id string) (err error) {
+	getUser, err := s.getOtherUser(ctx, adminID, id)
+	if err != nil {
+		return err
+	}
+	if getUser.DeactivatedAt != nil {
+		return nil
+	}
+
+	timeNow := time.Now()
+	getUser.DeactivatedAt = &timeNow
+	if err := s.repo.UpdateDeactivation(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("user deactivated", slog.String("audit", "user.deactivated"), slog.String("user_id", id), slog.String("by", adminID))
+
+	return s.revokeSessions(ctx, id)
+}
+
+// ActivateUser lets user id, deactivated by DeactivateUser, log in again.
+func (s *service) ActivateUser(ctx context.Context, adminID string, id string) (err error) {
+	getUser, err := s.getOtherUser(ctx, adminID, id)
+	if err != nil {
+		return err
+	}
+	if getUser.DeactivatedAt == nil {
+		return nil
+	}
+
+	getUser.DeactivatedAt = nil
+	if err := s.repo.UpdateDeactivation(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("user activated", slog.String("audit", "user.activated"), slog.String("user_id", id), slog.String("by", adminID))
+
+	return nil
+}
+
+// VerifyUserEmail marks the email of user id verified without its link, on
+// behalf of adminID.
+func (s *service) VerifyUserEmail(ctx context.Context, adminID string, id string) (err error) {
+	getUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if getUser.IsEmailVerified {
+		return nil
+	}
+
+	getUser.IsEmailVerified = true
+	if err := s.repo.UpdateEmailVerification(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("email verified", slog.String("audit", "user.email_verified"), slog.String("user_id", id), slog.String("by", adminID))
+
+	return nil
+}
+
+// ResetUserPassword replaces the password of user id with a random one, logs
+// them out everywhere and emails them a link to set a new one, on behalf of
+// adminID.
+func (s *service) ResetUserPassword(ctx context.Context, adminID string, id string) (err error) {
+	getUser, err := s.getOtherUser(ctx, adminID, id)
+	if err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return err
+	}
+	encPassword, err := bcrypt.GenerateFromPassword([]byte(base64.RawURLEncoding.EncodeToString(secret)), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	getUser.Password = string(encPassword)
+	if err := s.repo.UpdatePassword(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("password reset", slog.String("audit", "user.password_reset"), slog.String("user_id", id), slog.String("by", adminID))
+
+	if err := s.revokeSessions(ctx, id); err != nil {
+		return err
+	}
+	return s.sendPasswordResetEmail(ctx, getUser)
+}
+
+// getOtherUser gets user id for adminID to manage, an admin can't lock
+// themselves out by managing their own account.
+func (s *service) getOtherUser(ctx context.Context, adminID string, id string) (user User, err error) {
+	if id == adminID {
+		return user, ErrOwnAccount
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// UpdateProfile sets the fullname of user id, trimmed. A blank one is
+// ErrInvalidFullname.
+func (s *service) UpdateProfile(ctx context.Context, id string, fullname string) (err error) {
+	fullname = strings.TrimSpace(fullname)
+	if fullname == "" {
+		return ErrInvalidFullname
+	}
+
+	getUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	getUser.Fullname = fullname
+	return s.repo.UpdateFullname(ctx, getUser)
+}
+
+// ChangePassword sets password for user id when currentPassword is theirs,
+// and logs them out everywhere.
+func (s *service) ChangePassword(ctx context.Context, id string, currentPassword string, password string) (err error) {
+	getUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCurrentPassword(ctx, getUser, currentPassword); err != nil {
+		return err
+	}
+
+	encPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	getUser.Password = string(encPassword)
+	if err := s.repo.UpdatePassword(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("password changed", slog.String("audit", "user.password_changed"), slog.String("user_id", id))
+
+	return s.revokeSessions(ctx, id)
+}
+
+// RequestEmailChange emails a link to email, the new address of user id, it
+// only replaces the current one once the link is opened.
+func (s *service) RequestEmailChange(ctx context.Context, id string, email string, password string) (err error) {
+	getUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCurrentPassword(ctx, getUser, password); err != nil {
+		return err
+	}
+
+	_, err = s.repo.GetByEmail(ctx, email)
+	if err == nil {
+		return ErrEmailRegistered
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	expAt := time.Now().Add(s.config.VerificationCodeTTL).Unix()
+
+	// The code is bound to the current email, changing it once spends it
+	emailChangeCode := fmt.Sprintf("%v|%v|%v|%v", getUser.ID, getUser.Email, email, expAt)
+	emailChangeCodeEncrypt, _ := goshortcute.AESCBCEncrypt([]byte(emailChangeCode), []byte(s.appEmailVerificationKey))
+	code := goshortcute.StringtoBase64Encode(emailChangeCodeEncrypt)
+	code += "." + s.signCode("email-change", code)
+	confirmLink := s.appDeploymentUrl + "/users/email-change?code=" + url.QueryEscape(code)
+
+	ttlMinutes := int(s.config.VerificationCodeTTL.Minutes())
+	return s.notifRepo.SendEmail(ctx, getUser.Fullname, email, SubjectChangeEmail, fmt.Sprintf(EmailBodyChangeEmail, getUser.Fullname, confirmLink, ttlMinutes))
+}
+
+// ConfirmEmailChange switches the email of a user to the address a code sent
+// by RequestEmailChange was sent to. The previous address is told about it.
+func (s *service) ConfirmEmailChange(ctx context.Context, emailChangeCodeEncrypt string) (err error) {
+	emailChangeCodeEncrypt, signature, _ := strings.Cut(emailChangeCodeEncrypt, ".")
+	if !hmac.Equal([]byte(signature), []byte(s.signCode("email-change", emailChangeCodeEncrypt))) {
+		s.logger.Warn("confirm email change err", slog.Any("err", "invalid signature"))
+		return ErrInvalidEmailChangeCode
+	}
+
+	emailChangeCodeDecode := goshortcute.StringtoBase64Decode(emailChangeCodeEncrypt)
+	emailChangeCodeDecrypt, err := goshortcute.AESCBCDecrypt([]byte(emailChangeCodeDecode), []byte(s.appEmailVerificationKey))
+	if err != nil {
+		s.logger.Error("confirm email change err", slog.Any("err", err.Error()))
+		return ErrInvalidEmailChangeCode
+	}
+
+	emailChangeCode := strings.Split(emailChangeCodeDecrypt, "|")
+	if len(emailChangeCode) != 4 {
+		s.logger.Error("confirm email change err", slog.Any("err", "malformed email change code"))
+		return ErrInvalidEmailChangeCode
+	}
+	ts, err := strconv.ParseInt(emailChangeCode[3], 10, 64)
+	if err != nil {
+		s.logger.Error("confirm email change err", slog.Any("err", err))
+		return ErrInvalidEmailChangeCode
+	}
+	if time.Now().After(time.Unix(ts, 0)) {
+		return ErrInvalidEmailChangeCode
+	}
+
+	getUser, err := s.repo.GetByID(ctx, emailChangeCode[0])
+	if errors.Is(err, ErrNotFound) {
+		return ErrInvalidEmailChangeCode
+	}
+	if err != nil {
+		return err
+	}
+	previousEmail := getUser.Email
+	if previousEmail != emailChangeCode[1] {
+		s.logger.Warn("confirm email change err", slog.Any("err", "email changed already"))
+		return ErrInvalidEmailChangeCode
+	}
+
+	getUser.Email = emailChangeCode[2]
+	if err := s.repo.UpdateEmail(ctx, getUser); err != nil {
+		return err
+	}
+	s.logger.Info("email changed",
+		slog.String("audit", "user.email_changed"),
+		slog.String("user_id", getUser.ID),
+		slog.String("previous_email", previousEmail),
+		slog.String("email", getUser.Email),
+	)
+
+	err = s.notifRepo.SendEmail(ctx, getUser.Fullname, previousEmail, SubjectEmailChanged, fmt.Sprintf(EmailBodyEmailChanged, getUser.Fullname, getUser.Email))
+	if err != nil {
+		s.logger.Error("confirm email change err", slog.Any("err", err), slog.String("user_id", getUser.ID))
+	}
+	return nil
+}
+
+// checkCurrentPassword verifies the password of user before a change to their
+// account, wrong ones count as failed logins.
+func (s *service) checkCurrentPassword(ctx context.Context, user User, password string) (err error) {
+	if time.Now().Before(s.loginLockedUntil(loginAccountPrefixKey + strings.ToLower(user.Email))) {
+		return ErrLoginLocked
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.loginFailed(ctx, user.Email, "", &user)
+		return ErrWrongPassword
+	}
+	return nil
 }
@@ -1,24 +1,40 @@
 package user_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	mock_notification "github.com/pobyzaarif/belajarGo2/service/notification/mock"
+	mock_transaction "github.com/pobyzaarif/belajarGo2/service/transaction/mock"
 	"github.com/pobyzaarif/belajarGo2/service/user"
 	mock_user "github.com/pobyzaarif/belajarGo2/service/user/mock"
+	"github.com/pobyzaarif/belajarGo2/util/totp"
+	"github.com/pobyzaarif/belajarGo2/util/ttlcache"
 	"github.com/pobyzaarif/goshortcute"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var loggerOption = slog.HandlerOptions{AddSource: true}
 var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
 
+// noMFA is the MFA repository of users without two-factor authentication.
+func noMFA(ctrl *gomock.Controller) *mock_user.MockMFARepository {
+	m := mock_user.NewMockMFARepository(ctrl)
+	m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(user.MFA{}, user.ErrMFANotFound).AnyTimes()
+	return m
+}
+
 func TestRegister(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -31,7 +47,7 @@ func TestRegister(t *testing.T) {
 			name:      "error on GetByEmail",
 			inputUser: user.User{},
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("").Return(user.User{}, errors.New("record not found"))
+				m.EXPECT().GetByEmail(gomock.Any(), "").Return(user.User{}, errors.New("db con error"))
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   true,
@@ -40,7 +56,7 @@ func TestRegister(t *testing.T) {
 			name:      "error email already exists",
 			inputUser: user.User{Email: "test@example.com"},
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("test@example.com").Return(user.User{ID: "1", Email: "test@example.com"}, nil)
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{ID: "1", Email: "test@example.com"}, nil)
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   true,
@@ -49,21 +65,51 @@ func TestRegister(t *testing.T) {
 			name:      "error when create user",
 			inputUser: user.User{Email: "test@example.com"},
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("test@example.com").Return(user.User{}, nil)
-				m.EXPECT().Create(gomock.Any()).Return(errors.New("db con error"))
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{}, user.ErrNotFound)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("db con error"))
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   true,
 		},
+		{
+			name:      "failed activation email is recorded, the account kept",
+			inputUser: user.User{Email: "test@example.com"},
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{}, user.ErrNotFound)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().UpdateVerificationEmail(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+					assert.Equal(t, user.VerificationEmailFailed, u.VerificationEmailStatus)
+					assert.NotNil(t, u.VerificationEmailSentAt)
+					return nil
+				})
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {
+				m.EXPECT().SendEmail(gomock.Any(), "", "test@example.com", gomock.Any(), gomock.Any()).Return(errors.New("mailjet error"))
+			},
+			wantErr: false,
+		},
+		{
+			name:      "failed record of a failed activation email",
+			inputUser: user.User{Email: "test@example.com"},
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{}, user.ErrNotFound)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().UpdateVerificationEmail(gomock.Any(), gomock.Any()).Return(errors.New("db con error"))
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {
+				m.EXPECT().SendEmail(gomock.Any(), "", "test@example.com", gomock.Any(), gomock.Any()).Return(errors.New("mailjet error"))
+			},
+			wantErr: false,
+		},
 		{
 			name:      "success",
 			inputUser: user.User{Email: "test@example.com"},
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("test@example.com").Return(user.User{}, nil)
-				m.EXPECT().Create(gomock.Any()).Return(nil)
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{}, user.ErrNotFound)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {
-				m.EXPECT().SendEmail("", "test@example.com", gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().SendEmail(gomock.Any(), "", "test@example.com", gomock.Any(), gomock.Any()).Return(nil)
 			},
 			wantErr: false,
 		},
@@ -75,6 +121,9 @@ func TestRegister(t *testing.T) {
 			defer ctrl.Finish()
 			mock_userRepo := mock_user.NewMockRepository(ctrl)
 			mock_notification := mock_notification.NewMockRepository(ctrl)
+			// No transaction is expected, the email is sent once the user is
+			// created and a retried unit of work can't send it twice
+			mock_transactor := mock_transaction.NewMockTransactor(ctrl)
 
 			tt.mockUser(mock_userRepo)
 			tt.mockNotif(mock_notification)
@@ -82,18 +131,24 @@ func TestRegister(t *testing.T) {
 			productService := user.NewService(
 				logger,
 				mock_userRepo,
+				mock_user.NewMockRefreshTokenRepository(ctrl),
+				noMFA(ctrl),
+				newCache(t),
+				mock_transactor,
 				"http://appDeploymentUrl.com",
 				"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
 				"32character32character32characte",
 				mock_notification,
+				user.Config{},
 			)
 
-			id, err := productService.Register(tt.inputUser)
+			id, err := productService.Register(context.Background(), tt.inputUser)
 			if tt.wantErr {
 				assert.Equal(t, "", id)
 				assert.NotNil(t, err)
 			} else {
 				assert.Nil(t, err)
+				assert.NotEmpty(t, id)
 			}
 		})
 	}
@@ -102,7 +157,8 @@ func TestRegister(t *testing.T) {
 func TestVerifyEmail(t *testing.T) {
 	key := []byte("32character32character32characte")
 	tsInThefuture := time.Now().Add(time.Minute * 10).Unix()
-	notExpiredCode, _ := goshortcute.AESCBCEncrypt([]byte(fmt.Sprintf("%s|%d", "email@mail.com", tsInThefuture)), key)
+	notExpiredCodeEncrypt, _ := goshortcute.AESCBCEncrypt([]byte(fmt.Sprintf("%s|%d", "email@mail.com", tsInThefuture)), key)
+	notExpiredCode := goshortcute.StringtoBase64Encode(notExpiredCodeEncrypt)
 
 	tests := []struct {
 		name      string
@@ -150,7 +206,16 @@ func TestVerifyEmail(t *testing.T) {
 			name:      "error ts not expired but get by email error",
 			inputUser: notExpiredCode,
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("email@mail.com").Return(user.User{}, errors.New("db error"))
+				m.EXPECT().GetByEmail(gomock.Any(), "email@mail.com").Return(user.User{}, errors.New("db error"))
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {},
+			wantErr:   true,
+		},
+		{
+			name:      "error ts not expired but email not registered",
+			inputUser: notExpiredCode,
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "email@mail.com").Return(user.User{}, user.ErrNotFound)
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   true,
@@ -159,7 +224,7 @@ func TestVerifyEmail(t *testing.T) {
 			name:      "error ts not expired but get by email succes but the email already verified",
 			inputUser: notExpiredCode,
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("email@mail.com").Return(user.User{IsEmailVerified: true}, nil)
+				m.EXPECT().GetByEmail(gomock.Any(), "email@mail.com").Return(user.User{IsEmailVerified: true}, nil)
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   true,
@@ -168,8 +233,8 @@ func TestVerifyEmail(t *testing.T) {
 			name:      "error ts not expired but get by email succes but error when update email verification",
 			inputUser: notExpiredCode,
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("email@mail.com").Return(user.User{}, nil)
-				m.EXPECT().UpdateEmailVerification(user.User{IsEmailVerified: true}).Return(errors.New("db error"))
+				m.EXPECT().GetByEmail(gomock.Any(), "email@mail.com").Return(user.User{}, nil)
+				m.EXPECT().UpdateEmailVerification(gomock.Any(), user.User{IsEmailVerified: true}).Return(errors.New("db error"))
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   true,
@@ -178,8 +243,8 @@ func TestVerifyEmail(t *testing.T) {
 			name:      "success",
 			inputUser: notExpiredCode,
 			mockUser: func(m *mock_user.MockRepository) {
-				m.EXPECT().GetByEmail("email@mail.com").Return(user.User{}, nil)
-				m.EXPECT().UpdateEmailVerification(user.User{IsEmailVerified: true}).Return(nil)
+				m.EXPECT().GetByEmail(gomock.Any(), "email@mail.com").Return(user.User{}, nil)
+				m.EXPECT().UpdateEmailVerification(gomock.Any(), user.User{IsEmailVerified: true}).Return(nil)
 			},
 			mockNotif: func(m *mock_notification.MockRepository) {},
 			wantErr:   false,
@@ -192,6 +257,12 @@ func TestVerifyEmail(t *testing.T) {
 			defer ctrl.Finish()
 			mock_userRepo := mock_user.NewMockRepository(ctrl)
 			mock_notification := mock_notification.NewMockRepository(ctrl)
+			mock_transactor := mock_transaction.NewMockTransactor(ctrl)
+			mock_transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, fn func(ctx context.Context) error) error {
+					return fn(ctx)
+				},
+			).AnyTimes()
 
 			tt.mockUser(mock_userRepo)
 			tt.mockNotif(mock_notification)
@@ -199,13 +270,604 @@ func TestVerifyEmail(t *testing.T) {
 			productService := user.NewService(
 				logger,
 				mock_userRepo,
+				mock_user.NewMockRefreshTokenRepository(ctrl),
+				noMFA(ctrl),
+				newCache(t),
+				mock_transactor,
 				"http://appDeploymentUrl.com",
 				"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
 				string(key),
 				mock_notification,
+				user.Config{},
+			)
+
+			err := productService.VerifyEmail(context.Background(), tt.inputUser)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		mockUser  func(m *mock_user.MockRepository)
+		mockToken func(m *mock_user.MockRefreshTokenRepository)
+		wantErr   error
+	}{
+		{
+			name:     "error on GetByEmail",
+			email:    "test@example.com",
+			password: "secret",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{}, errors.New("db con error"))
+			},
+			wantErr: errors.New("db con error"),
+		},
+		{
+			name:     "error email not registered",
+			email:    "test@example.com",
+			password: "secret",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{}, user.ErrNotFound)
+			},
+			wantErr: user.ErrInvalidCredentials,
+		},
+		{
+			name:     "error wrong password",
+			email:    "test@example.com",
+			password: "wrong",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{Password: string(hashedPassword), IsEmailVerified: true}, nil)
+			},
+			wantErr: user.ErrInvalidCredentials,
+		},
+		{
+			name:     "error email not verified",
+			email:    "test@example.com",
+			password: "secret",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{Password: string(hashedPassword)}, nil)
+			},
+			wantErr: user.ErrEmailNotVerified,
+		},
+		{
+			name:     "success",
+			email:    "test@example.com",
+			password: "secret",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user.User{ID: "1", Password: string(hashedPassword), Role: "user", IsEmailVerified: true}, nil)
+			},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, token user.RefreshToken) error {
+					if token.UserID != "1" || token.FamilyID == "" || len(token.TokenHash) != 64 {
+						t.Errorf("unexpected refresh token %+v", token)
+					}
+					return nil
+				})
+			},
+			wantErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mock_userRepo := mock_user.NewMockRepository(ctrl)
+			mock_notification := mock_notification.NewMockRepository(ctrl)
+			mock_transactor := mock_transaction.NewMockTransactor(ctrl)
+			mock_transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, fn func(ctx context.Context) error) error {
+					return fn(ctx)
+				},
+			).AnyTimes()
+
+			mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+			tt.mockUser(mock_userRepo)
+			if tt.mockToken != nil {
+				tt.mockToken(mock_tokenRepo)
+			}
+
+			productService := user.NewService(
+				logger,
+				mock_userRepo,
+				mock_tokenRepo,
+				noMFA(ctrl),
+				newCache(t),
+				mock_transactor,
+				"http://appDeploymentUrl.com",
+				"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+				"32character32character32characte",
+				mock_notification,
+				user.Config{},
+			)
+
+			tokens, err := productService.Login(context.Background(), tt.email, tt.password, "")
+			if tt.wantErr != nil {
+				assert.Equal(t, user.Tokens{}, tokens)
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.NotEmpty(t, tokens.AccessToken)
+				assert.NotEmpty(t, tokens.RefreshToken)
+				assert.Equal(t, int64(15*60), tokens.ExpiresIn)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	// sha256 of "refresh-token"
+	hash := "0eb17643d4e9261163783a420859c92c7d212fa9624106a12b510afbec266120"
+	valid := user.RefreshToken{ID: "t1", FamilyID: "f1", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)}
+	used := valid
+	used.Used = true
+	revoked := valid
+	revoked.Revoked = true
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		mockUser  func(m *mock_user.MockRepository)
+		mockToken func(m *mock_user.MockRefreshTokenRepository)
+		wantErr   error
+	}{
+		{
+			name:     "error unknown token",
+			mockUser: func(m *mock_user.MockRepository) {},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(user.RefreshToken{}, user.ErrRefreshTokenNotFound)
+			},
+			wantErr: user.ErrInvalidRefreshToken,
+		},
+		{
+			name:     "error revoked token",
+			mockUser: func(m *mock_user.MockRepository) {},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(revoked, nil)
+			},
+			wantErr: user.ErrInvalidRefreshToken,
+		},
+		{
+			name:     "error expired token",
+			mockUser: func(m *mock_user.MockRepository) {},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(expired, nil)
+			},
+			wantErr: user.ErrInvalidRefreshToken,
+		},
+		{
+			name:     "reused token revokes the family",
+			mockUser: func(m *mock_user.MockRepository) {},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(used, nil)
+				m.EXPECT().RevokeFamily(gomock.Any(), "f1").Return(nil)
+			},
+			wantErr: user.ErrInvalidRefreshToken,
+		},
+		{
+			name:     "token used by a concurrent refresh revokes the family",
+			mockUser: func(m *mock_user.MockRepository) {},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(valid, nil)
+				m.EXPECT().MarkUsed(gomock.Any(), "t1").Return(false, nil)
+				m.EXPECT().RevokeFamily(gomock.Any(), "f1").Return(nil)
+			},
+			wantErr: user.ErrInvalidRefreshToken,
+		},
+		{
+			name: "error user deleted",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByID(gomock.Any(), "1").Return(user.User{}, user.ErrNotFound)
+			},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(valid, nil)
+				m.EXPECT().MarkUsed(gomock.Any(), "t1").Return(true, nil)
+			},
+			wantErr: user.ErrInvalidRefreshToken,
+		},
+		{
+			name: "success rotates within the family",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByID(gomock.Any(), "1").Return(user.User{ID: "1", Role: "user"}, nil)
+			},
+			mockToken: func(m *mock_user.MockRefreshTokenRepository) {
+				m.EXPECT().GetByHash(gomock.Any(), hash).Return(valid, nil)
+				m.EXPECT().MarkUsed(gomock.Any(), "t1").Return(true, nil)
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, token user.RefreshToken) error {
+					if token.FamilyID != "f1" || token.UserID != "1" || token.TokenHash == hash {
+						t.Errorf("unexpected refresh token %+v", token)
+					}
+					return nil
+				})
+			},
+			wantErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mock_userRepo := mock_user.NewMockRepository(ctrl)
+			mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+			mock_transactor := mock_transaction.NewMockTransactor(ctrl)
+			mock_transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, fn func(ctx context.Context) error) error {
+					return fn(ctx)
+				},
+			).AnyTimes()
+
+			tt.mockUser(mock_userRepo)
+			tt.mockToken(mock_tokenRepo)
+
+			productService := user.NewService(
+				logger,
+				mock_userRepo,
+				mock_tokenRepo,
+				noMFA(ctrl),
+				newCache(t),
+				mock_transactor,
+				"http://appDeploymentUrl.com",
+				"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+				"32character32character32characte",
+				mock_notification.NewMockRepository(ctrl),
+				user.Config{AccessTokenTTL: time.Minute},
+			)
+
+			tokens, err := productService.Refresh(context.Background(), "refresh-token")
+			if tt.wantErr != nil {
+				assert.Equal(t, user.Tokens{}, tokens)
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.Nil(t, err)
+				assert.NotEmpty(t, tokens.AccessToken)
+				assert.NotEqual(t, "refresh-token", tokens.RefreshToken)
+				assert.Equal(t, int64(60), tokens.ExpiresIn)
+			}
+		})
+	}
+}
+
+func newCache(t *testing.T) user.SecurityCacheRepository {
+	return ttlcache.New()
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	// sha256 of "refresh-token"
+	hash := "0eb17643d4e9261163783a420859c92c7d212fa9624106a12b510afbec266120"
+	now := time.Now()
+	token := user.AccessToken{ID: "jti1", UserID: "1", IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Minute)}
+	other := user.AccessToken{ID: "jti2", UserID: "1", IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Minute)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	userService := user.NewService(
+		logger,
+		mock_user.NewMockRepository(ctrl),
+		mock_tokenRepo,
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notification.NewMockRepository(ctrl),
+		user.Config{},
+	)
+
+	// Only the token logged out with, and the family of its refresh token
+	mock_tokenRepo.EXPECT().GetByHash(gomock.Any(), hash).Return(user.RefreshToken{UserID: "1", FamilyID: "f1"}, nil)
+	mock_tokenRepo.EXPECT().RevokeFamily(gomock.Any(), "f1").Return(nil)
+	assert.Nil(t, userService.Logout(ctx, token, "refresh-token"))
+
+	revoked, err := userService.IsRevoked(ctx, token)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+	revoked, err = userService.IsRevoked(ctx, other)
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	// Someone else's refresh token is left alone
+	mock_tokenRepo.EXPECT().GetByHash(gomock.Any(), hash).Return(user.RefreshToken{UserID: "2", FamilyID: "f2"}, nil)
+	assert.Nil(t, userService.Logout(ctx, other, "refresh-token"))
+
+	// Every token issued so far, not the ones of a later login
+	mock_tokenRepo.EXPECT().RevokeUser(gomock.Any(), "1").Return(nil)
+	assert.Nil(t, userService.LogoutAll(ctx, token))
+
+	revoked, err = userService.IsRevoked(ctx, user.AccessToken{ID: "jti3", UserID: "1", IssuedAt: now.Add(-time.Minute)})
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+	revoked, err = userService.IsRevoked(ctx, user.AccessToken{ID: "jti4", UserID: "1", IssuedAt: now.Add(2 * time.Second)})
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+	revoked, err = userService.IsRevoked(ctx, user.AccessToken{ID: "jti5", UserID: "2", IssuedAt: now.Add(-time.Minute)})
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+}
+
+func TestLogoutAllSameSecond(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "user@example.com", Role: "user", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(stored, nil).AnyTimes()
+	mock_tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mock_tokenRepo.EXPECT().RevokeUser(gomock.Any(), stored.ID).Return(nil)
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_tokenRepo,
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notification.NewMockRepository(ctrl),
+		user.Config{},
+	)
+
+	// Start on a fresh second, the logins and the logout all happen in it
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	before, err := userService.Login(ctx, stored.Email, "password", "")
+	assert.Nil(t, err)
+	token, err := userService.Authenticate(ctx, before.AccessToken)
+	assert.Nil(t, err)
+
+	assert.Nil(t, userService.LogoutAll(ctx, token))
+	after, err := userService.Login(ctx, stored.Email, "password", "")
+	assert.Nil(t, err)
+
+	_, err = userService.Authenticate(ctx, before.AccessToken)
+	assert.ErrorIs(t, err, user.ErrInvalidAccessToken)
+	token, err = userService.Authenticate(ctx, after.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, stored.ID, token.UserID)
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	secret := "exampleexampleexampleexampleexampleexampleexampleexampleexampleexample"
+	now := time.Now()
+	sign := func(key string, method jwt.SigningMethod, claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(method, claims).SignedString([]byte(key))
+		assert.Nil(t, err)
+		return signed
+	}
+	claims := func(jti string, exp time.Time) jwt.MapClaims {
+		return jwt.MapClaims{"id": "1", "role": "admin", "jti": jti, "iat": now.Unix(), "exp": exp.Unix()}
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cacheRepo := newCache(t)
+	userService := user.NewService(
+		logger,
+		mock_user.NewMockRepository(ctrl),
+		mock_user.NewMockRefreshTokenRepository(ctrl),
+		noMFA(ctrl),
+		cacheRepo,
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		secret,
+		"32character32character32characte",
+		mock_notification.NewMockRepository(ctrl),
+		user.Config{},
+	)
+	// The servers without the user service check tokens on the same cache
+	authenticator := user.NewAuthenticator(secret, cacheRepo)
+
+	token, err := authenticator.Authenticate(ctx, sign(secret, jwt.SigningMethodHS256, claims("jti1", now.Add(time.Minute))))
+	assert.Nil(t, err)
+	assert.Equal(t, "jti1", token.ID)
+	assert.Equal(t, "1", token.UserID)
+	assert.Equal(t, "admin", token.Role)
+	assert.False(t, token.MFAPending)
+
+	assert.Nil(t, userService.Logout(ctx, token, ""))
+	_, err = authenticator.Authenticate(ctx, sign(secret, jwt.SigningMethodHS256, claims("jti1", now.Add(time.Minute))))
+	assert.ErrorIs(t, err, user.ErrInvalidAccessToken)
+
+	for name, signed := range map[string]string{
+		"expired":       sign(secret, jwt.SigningMethodHS256, claims("jti2", now.Add(-time.Minute))),
+		"other secret":  sign("other", jwt.SigningMethodHS256, claims("jti3", now.Add(time.Minute))),
+		"other method":  sign(secret, jwt.SigningMethodHS512, claims("jti4", now.Add(time.Minute))),
+		"without jti":   sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"id": "1", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}),
+		"without exp":   sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"id": "1", "jti": "jti5", "iat": now.Unix()}),
+		"not a token":   "token",
+		"empty":         "",
+		"mfa challenge": sign("mfa|"+secret, jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1", "jti": "jti6", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}),
+	} {
+		_, err := userService.Authenticate(ctx, signed)
+		assert.ErrorIs(t, err, user.ErrInvalidAccessToken, name)
+	}
+
+	// Without a secret nothing is accepted, not even a token signed with none
+	_, err = user.NewAuthenticator("", cacheRepo).Authenticate(ctx, sign("", jwt.SigningMethodHS256, claims("jti7", now.Add(time.Minute))))
+	assert.ErrorIs(t, err, user.ErrInvalidAccessToken)
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "user@example.com", Fullname: "User", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	mock_notif := mock_notification.NewMockRepository(ctrl)
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_tokenRepo,
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notif,
+		user.Config{},
+	)
+
+	// Unknown emails get the same answer, no email is sent
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), "nobody@example.com").Return(user.User{}, user.ErrNotFound)
+	assert.Nil(t, userService.ForgotPassword(ctx, "nobody@example.com"))
+
+	var code string
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(stored, nil)
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, stored.Email, user.SubjectResetPassword, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, name, email, subject, body string) error {
+			_, link, _ := strings.Cut(body, "?code=")
+			link, _, _ = strings.Cut(link, "<br/>")
+			code, _ = url.QueryUnescape(link)
+			return nil
+		},
+	)
+	assert.Nil(t, userService.ForgotPassword(ctx, stored.Email))
+	if !assert.NotEmpty(t, code) {
+		return
+	}
+
+	// A tampered code is refused before anything is looked up
+	assert.ErrorIs(t, userService.ResetPassword(ctx, code+"0", "new-password"), user.ErrInvalidResetCode)
+
+	var updated user.User
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(stored, nil)
+	mock_userRepo.EXPECT().UpdatePassword(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		updated = u
+		return nil
+	})
+	mock_tokenRepo.EXPECT().RevokeUser(gomock.Any(), stored.ID).Return(nil)
+	assert.Nil(t, userService.ResetPassword(ctx, code, "new-password"))
+	assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password")))
+
+	// Sessions from before the reset are logged out
+	revoked, err := userService.IsRevoked(ctx, user.AccessToken{ID: "jti1", UserID: stored.ID, IssuedAt: time.Now().Add(-time.Minute)})
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// The code is spent once the password changed
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(updated, nil)
+	assert.ErrorIs(t, userService.ResetPassword(ctx, code, "another-password"), user.ErrInvalidResetCode)
+}
+
+func TestResendVerification(t *testing.T) {
+	justSent := time.Now().Add(-10 * time.Second)
+	longAgo := time.Now().Add(-time.Hour)
+	unverified := user.User{ID: "1", Email: "user@example.com", VerificationEmailStatus: user.VerificationEmailFailed, VerificationEmailSentAt: &longAgo}
+	throttled := unverified
+	throttled.VerificationEmailSentAt = &justSent
+	verified := unverified
+	verified.IsEmailVerified = true
+
+	tests := []struct {
+		name       string
+		mockUser   func(m *mock_user.MockRepository)
+		mockNotif  func(m *mock_notification.MockRepository)
+		wantStatus string
+		wantErr    bool
+	}{
+		{
+			name: "unknown email is answered the same",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(user.User{}, user.ErrNotFound)
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {},
+		},
+		{
+			name: "error on GetByEmail",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(user.User{}, errors.New("db con error"))
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {},
+			wantErr:   true,
+		},
+		{
+			name: "verified already",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(verified, nil)
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {},
+		},
+		{
+			name: "sent too recently",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(throttled, nil)
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {},
+		},
+		{
+			name: "failed delivery is recorded",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(unverified, nil)
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {
+				m.EXPECT().SendEmail(gomock.Any(), gomock.Any(), "user@example.com", user.SubjectRegisterAccount, gomock.Any()).Return(errors.New("mailjet error"))
+			},
+			wantStatus: user.VerificationEmailFailed,
+		},
+		{
+			name: "success",
+			mockUser: func(m *mock_user.MockRepository) {
+				m.EXPECT().GetByEmail(gomock.Any(), "user@example.com").Return(unverified, nil)
+			},
+			mockNotif: func(m *mock_notification.MockRepository) {
+				m.EXPECT().SendEmail(gomock.Any(), gomock.Any(), "user@example.com", user.SubjectRegisterAccount, gomock.Any()).Return(nil)
+			},
+			wantStatus: user.VerificationEmailSent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mock_userRepo := mock_user.NewMockRepository(ctrl)
+			mock_notif := mock_notification.NewMockRepository(ctrl)
+			tt.mockUser(mock_userRepo)
+			tt.mockNotif(mock_notif)
+			if tt.wantStatus != "" {
+				mock_userRepo.EXPECT().UpdateVerificationEmail(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+					assert.Equal(t, tt.wantStatus, u.VerificationEmailStatus)
+					assert.WithinDuration(t, time.Now(), *u.VerificationEmailSentAt, time.Second)
+					return nil
+				})
+			}
+
+			userService := user.NewService(
+				logger,
+				mock_userRepo,
+				mock_user.NewMockRefreshTokenRepository(ctrl),
+				noMFA(ctrl),
+				newCache(t),
+				mock_transaction.NewMockTransactor(ctrl),
+				"http://appDeploymentUrl.com",
+				"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+				"32character32character32characte",
+				mock_notif,
+				user.Config{},
 			)
 
-			err := productService.VerifyEmail(tt.inputUser)
+			err := userService.ResendVerification(context.Background(), "user@example.com")
 			if tt.wantErr {
 				assert.NotNil(t, err)
 			} else {
@@ -214,3 +876,401 @@ func TestVerifyEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "user@example.com", Fullname: "User", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	mock_notif := mock_notification.NewMockRepository(ctrl)
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(stored, nil).AnyTimes()
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(user.User{}, user.ErrNotFound).AnyTimes()
+	mock_tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_tokenRepo,
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notif,
+		user.Config{MaxLoginFailures: 2, MaxIPLoginFailures: 3, LoginLockDuration: time.Hour},
+	)
+
+	// The owner is emailed an unlock link once, when the account gets locked
+	var code string
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, stored.Email, user.SubjectAccountLocked, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, name, email, subject, body string) error {
+			_, link, _ := strings.Cut(body, "?code=")
+			link, _, _ = strings.Cut(link, "<br/>")
+			code, _ = url.QueryUnescape(link)
+			return nil
+		},
+	)
+	for range 2 {
+		_, err := userService.Login(ctx, stored.Email, "wrong", "10.0.0.1")
+		assert.ErrorIs(t, err, user.ErrInvalidCredentials)
+	}
+
+	// Even the right password is refused while locked, from any ip
+	_, err := userService.Login(ctx, stored.Email, "password", "10.0.0.2")
+	assert.ErrorIs(t, err, user.ErrLoginLocked)
+
+	assert.ErrorIs(t, userService.UnlockAccount(ctx, code+"0"), user.ErrInvalidUnlockCode)
+	assert.Nil(t, userService.UnlockAccount(ctx, code))
+	_, err = userService.Login(ctx, stored.Email, "password", "10.0.0.2")
+	assert.Nil(t, err)
+
+	// The ip that failed twice fails a third time on an unknown account
+	_, err = userService.Login(ctx, "nobody@example.com", "wrong", "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrInvalidCredentials)
+	_, err = userService.Login(ctx, stored.Email, "password", "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrLoginLocked)
+
+	// An admin unlocks the account, the lock of the ip stays
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, stored.Email, user.SubjectAccountLocked, gomock.Any()).Return(nil)
+	for range 2 {
+		_, _ = userService.Login(ctx, stored.Email, "wrong", "10.0.0.3")
+	}
+	mock_userRepo.EXPECT().GetByID(gomock.Any(), stored.ID).Return(stored, nil)
+	assert.Nil(t, userService.UnlockUser(ctx, "admin", stored.ID))
+	_, err = userService.Login(ctx, stored.Email, "password", "10.0.0.3")
+	assert.Nil(t, err)
+	_, err = userService.Login(ctx, stored.Email, "password", "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrLoginLocked)
+}
+
+func TestLoginLockoutConcurrent(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "user@example.com", Fullname: "User", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_notif := mock_notification.NewMockRepository(ctrl)
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(stored, nil).AnyTimes()
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_user.NewMockRefreshTokenRepository(ctrl),
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notif,
+		user.Config{MaxLoginFailures: 5, MaxIPLoginFailures: 100, LoginLockDuration: time.Hour},
+	)
+
+	// Every failure of a parallel guessing counts, exactly one of them is the
+	// 5th and emails the unlock link
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, stored.Email, user.SubjectAccountLocked, gomock.Any()).Return(nil).Times(1)
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := userService.Login(ctx, stored.Email, "wrong", fmt.Sprintf("10.0.0.%d", i))
+			assert.ErrorIs(t, err, user.ErrInvalidCredentials)
+		}()
+	}
+	wg.Wait()
+
+	_, err := userService.Login(ctx, stored.Email, "password", "10.0.1.1")
+	assert.ErrorIs(t, err, user.ErrLoginLocked)
+}
+
+func TestMFA(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "admin@example.com", Fullname: "Admin", Role: "admin", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	mock_mfaRepo := mock_user.NewMockMFARepository(ctrl)
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).Return(stored, nil).AnyTimes()
+	mock_userRepo.EXPECT().GetByID(gomock.Any(), stored.ID).Return(stored, nil).AnyTimes()
+	mock_tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	var mfa *user.MFA
+	mock_mfaRepo.EXPECT().Get(gomock.Any(), stored.ID).DoAndReturn(func(ctx context.Context, userID string) (user.MFA, error) {
+		if mfa == nil {
+			return user.MFA{}, user.ErrMFANotFound
+		}
+		return *mfa, nil
+	}).AnyTimes()
+	mock_mfaRepo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, saved user.MFA) error {
+		mfa = &saved
+		return nil
+	}).AnyTimes()
+	mock_mfaRepo.EXPECT().Delete(gomock.Any(), stored.ID).DoAndReturn(func(ctx context.Context, userID string) error {
+		mfa = nil
+		return nil
+	}).AnyTimes()
+
+	newService := func(config user.Config) user.Service {
+		return user.NewService(
+			logger,
+			mock_userRepo,
+			mock_tokenRepo,
+			mock_mfaRepo,
+			newCache(t),
+			mock_transaction.NewMockTransactor(ctrl),
+			"http://appDeploymentUrl.com",
+			"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+			"32character32character32characte",
+			mock_notification.NewMockRepository(ctrl),
+			config,
+		)
+	}
+	userService := newService(user.Config{MFARequiredRoles: []string{"admin"}})
+
+	mfaPending := func(accessToken string) bool {
+		claims := jwt.MapClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(accessToken, claims)
+		assert.Nil(t, err)
+		pending, _ := claims["mfa_pending"].(bool)
+		return pending
+	}
+
+	// The role requires it, until it is enabled the token says so
+	tokens, err := userService.Login(ctx, stored.Email, "password", "10.0.0.1")
+	assert.Nil(t, err)
+	assert.True(t, mfaPending(tokens.AccessToken))
+
+	enrolment, err := userService.EnrollMFA(ctx, stored.ID)
+	assert.Nil(t, err)
+	assert.Contains(t, enrolment.URI, "secret="+enrolment.Secret)
+	assert.NotEqual(t, enrolment.Secret, mfa.Secret, "the secret is stored encrypted")
+
+	now := time.Now()
+	code, _ := totp.Code(enrolment.Secret, totp.Step(now))
+	_, err = userService.ConfirmMFA(ctx, stored.ID, "000000")
+	assert.ErrorIs(t, err, user.ErrInvalidMFACode)
+	recoveryCodes, err := userService.ConfirmMFA(ctx, stored.ID, code)
+	assert.Nil(t, err)
+	assert.Len(t, recoveryCodes, 10)
+	assert.NotContains(t, mfa.RecoveryCodes, recoveryCodes[0], "only their hash is stored")
+
+	_, err = userService.EnrollMFA(ctx, stored.ID)
+	assert.ErrorIs(t, err, user.ErrMFAEnabled)
+
+	// The password alone only gets a challenge
+	tokens, err = userService.Login(ctx, stored.Email, "password", "10.0.0.1")
+	assert.Nil(t, err)
+	assert.True(t, tokens.MFARequired)
+	assert.Empty(t, tokens.AccessToken)
+
+	_, err = userService.LoginMFA(ctx, tokens.MFAToken, code, "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrInvalidMFACode, "a code works once")
+	_, err = userService.LoginMFA(ctx, "not a token", code, "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrInvalidMFAToken)
+
+	nextCode, _ := totp.Code(enrolment.Secret, totp.Step(now)+1)
+	full, err := userService.LoginMFA(ctx, tokens.MFAToken, nextCode, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, full.RefreshToken)
+	assert.False(t, mfaPending(full.AccessToken))
+
+	// An access token can't pass for a challenge
+	_, err = userService.LoginMFA(ctx, full.AccessToken, nextCode, "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrInvalidMFAToken)
+
+	_, err = userService.LoginMFA(ctx, tokens.MFAToken, recoveryCodes[0], "10.0.0.1")
+	assert.Nil(t, err)
+	_, err = userService.LoginMFA(ctx, tokens.MFAToken, recoveryCodes[0], "10.0.0.1")
+	assert.ErrorIs(t, err, user.ErrInvalidMFACode, "a recovery code works once")
+
+	// Required for the role, it can't be turned off
+	assert.ErrorIs(t, userService.DisableMFA(ctx, stored.ID, recoveryCodes[1]), user.ErrMFARequired)
+
+	userService = newService(user.Config{})
+	assert.ErrorIs(t, userService.DisableMFA(ctx, stored.ID, "000000"), user.ErrInvalidMFACode)
+	assert.Nil(t, userService.DisableMFA(ctx, stored.ID, recoveryCodes[1]))
+	assert.Nil(t, mfa)
+
+	// Wrong codes lock the user out of confirming or disabling, even with the
+	// right code, until an admin unlocks them
+	userService = newService(user.Config{MaxLoginFailures: 3})
+	enrolment, err = userService.EnrollMFA(ctx, stored.ID)
+	assert.Nil(t, err)
+	for range 3 {
+		_, err = userService.ConfirmMFA(ctx, stored.ID, "000000")
+		assert.ErrorIs(t, err, user.ErrInvalidMFACode)
+	}
+	code, _ = totp.Code(enrolment.Secret, totp.Step(time.Now()))
+	_, err = userService.ConfirmMFA(ctx, stored.ID, code)
+	assert.ErrorIs(t, err, user.ErrLoginLocked)
+	assert.ErrorIs(t, userService.DisableMFA(ctx, stored.ID, code), user.ErrLoginLocked)
+
+	assert.Nil(t, userService.UnlockUser(ctx, "admin", stored.ID))
+	_, err = userService.ConfirmMFA(ctx, stored.ID, code)
+	assert.Nil(t, err)
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "user@example.com", Fullname: "User", Role: "user", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	mock_notif := mock_notification.NewMockRepository(ctrl)
+	mock_userRepo.EXPECT().GetByID(gomock.Any(), stored.ID).DoAndReturn(func(ctx context.Context, id string) (user.User, error) {
+		return stored, nil
+	}).AnyTimes()
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), stored.Email).DoAndReturn(func(ctx context.Context, email string) (user.User, error) {
+		return stored, nil
+	}).AnyTimes()
+	mock_tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_tokenRepo,
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notif,
+		user.Config{},
+	)
+
+	_, err := userService.GetAll(ctx, user.Filter{Role: "root"}, 1, 10)
+	assert.ErrorIs(t, err, user.ErrInvalidRole)
+	mock_userRepo.EXPECT().ReadAll(gomock.Any(), user.Filter{Search: "user", Role: "user"}, 2, 5).Return([]user.User{stored}, nil)
+	users, err := userService.GetAll(ctx, user.Filter{Search: " user ", Role: "user"}, 2, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []user.User{stored}, users)
+
+	// Their own account is off limits, so is an unknown role
+	assert.ErrorIs(t, userService.ChangeRole(ctx, stored.ID, stored.ID, "superadmin"), user.ErrOwnAccount)
+	assert.ErrorIs(t, userService.DeactivateUser(ctx, stored.ID, stored.ID), user.ErrOwnAccount)
+	assert.ErrorIs(t, userService.ChangeRole(ctx, "admin", stored.ID, "root"), user.ErrInvalidRole)
+
+	// A new role logs them out everywhere
+	token := user.AccessToken{ID: "jti1", UserID: stored.ID, IssuedAt: time.Now().Add(-time.Minute)}
+	mock_userRepo.EXPECT().UpdateRole(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		stored.Role = u.Role
+		return nil
+	})
+	mock_tokenRepo.EXPECT().RevokeUser(gomock.Any(), stored.ID).Return(nil).Times(3)
+	assert.Nil(t, userService.ChangeRole(ctx, "admin", stored.ID, "admin"))
+	assert.Equal(t, "admin", stored.Role)
+	revoked, err := userService.IsRevoked(ctx, token)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// Deactivated, the right password isn't enough
+	mock_userRepo.EXPECT().UpdateDeactivation(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		stored.DeactivatedAt = u.DeactivatedAt
+		return nil
+	}).Times(2)
+	assert.Nil(t, userService.DeactivateUser(ctx, "admin", stored.ID))
+	_, err = userService.Login(ctx, stored.Email, "password", "")
+	assert.ErrorIs(t, err, user.ErrAccountDeactivated)
+	assert.Nil(t, userService.ActivateUser(ctx, "admin", stored.ID))
+	_, err = userService.Login(ctx, stored.Email, "password", "")
+	assert.Nil(t, err)
+
+	// The old password stops working, a reset link is emailed
+	mock_userRepo.EXPECT().UpdatePassword(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		stored.Password = u.Password
+		return nil
+	})
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, stored.Email, user.SubjectResetPassword, gomock.Any()).Return(nil)
+	assert.Nil(t, userService.ResetUserPassword(ctx, "admin", stored.ID))
+	_, err = userService.Login(ctx, stored.Email, "password", "")
+	assert.ErrorIs(t, err, user.ErrInvalidCredentials)
+}
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+	encPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	stored := user.User{ID: "1", Email: "user@example.com", Fullname: "User", Role: "user", Password: string(encPassword), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock_userRepo := mock_user.NewMockRepository(ctrl)
+	mock_tokenRepo := mock_user.NewMockRefreshTokenRepository(ctrl)
+	mock_notif := mock_notification.NewMockRepository(ctrl)
+	mock_userRepo.EXPECT().GetByID(gomock.Any(), stored.ID).DoAndReturn(func(ctx context.Context, id string) (user.User, error) {
+		return stored, nil
+	}).AnyTimes()
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), "taken@example.com").Return(user.User{ID: "2"}, nil).AnyTimes()
+	mock_userRepo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(user.User{}, user.ErrNotFound).AnyTimes()
+	userService := user.NewService(
+		logger,
+		mock_userRepo,
+		mock_tokenRepo,
+		noMFA(ctrl),
+		newCache(t),
+		mock_transaction.NewMockTransactor(ctrl),
+		"http://appDeploymentUrl.com",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexampleexample",
+		"32character32character32characte",
+		mock_notif,
+		user.Config{},
+	)
+
+	mock_userRepo.EXPECT().UpdateFullname(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		stored.Fullname = u.Fullname
+		return nil
+	})
+	assert.Nil(t, userService.UpdateProfile(ctx, stored.ID, " Renamed "))
+	assert.Equal(t, "Renamed", stored.Fullname)
+	assert.ErrorIs(t, userService.UpdateProfile(ctx, stored.ID, "   "), user.ErrInvalidFullname)
+	assert.Equal(t, "Renamed", stored.Fullname)
+
+	// The current password is required, the change logs out everywhere
+	assert.ErrorIs(t, userService.ChangePassword(ctx, stored.ID, "wrong", "new"), user.ErrWrongPassword)
+	mock_userRepo.EXPECT().UpdatePassword(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		stored.Password = u.Password
+		return nil
+	})
+	mock_tokenRepo.EXPECT().RevokeUser(gomock.Any(), stored.ID).Return(nil)
+	assert.Nil(t, userService.ChangePassword(ctx, stored.ID, "password", "new"))
+	assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("new")))
+
+	assert.ErrorIs(t, userService.RequestEmailChange(ctx, stored.ID, "other@example.com", "password"), user.ErrWrongPassword)
+	assert.ErrorIs(t, userService.RequestEmailChange(ctx, stored.ID, "taken@example.com", "new"), user.ErrEmailRegistered)
+
+	// The link goes to the new address, the email only changes once opened
+	var code string
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, "other@example.com", user.SubjectChangeEmail, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, name, email, subject, body string) error {
+			_, link, _ := strings.Cut(body, "?code=")
+			link, _, _ = strings.Cut(link, "<br/>")
+			code, _ = url.QueryUnescape(link)
+			return nil
+		},
+	)
+	assert.Nil(t, userService.RequestEmailChange(ctx, stored.ID, "other@example.com", "new"))
+	assert.Equal(t, "user@example.com", stored.Email)
+
+	assert.ErrorIs(t, userService.ConfirmEmailChange(ctx, code+"0"), user.ErrInvalidEmailChangeCode)
+	mock_userRepo.EXPECT().UpdateEmail(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) error {
+		stored.Email = u.Email
+		return nil
+	})
+	mock_notif.EXPECT().SendEmail(gomock.Any(), stored.Fullname, "user@example.com", user.SubjectEmailChanged, gomock.Any()).Return(nil)
+	assert.Nil(t, userService.ConfirmEmailChange(ctx, code))
+	assert.Equal(t, "other@example.com", stored.Email)
+
+	// Spent once the email changed
+	assert.ErrorIs(t, userService.ConfirmEmailChange(ctx, code), user.ErrInvalidEmailChangeCode)
+}
@@ -0,0 +1,8 @@
+package workorder
+
+import "github.com/pobyzaarif/belajarGo2/service/errs"
+
+var (
+	ErrNotFound      = errs.New(errs.NotFound, "work order not found")
+	ErrClosedAlready = errs.New(errs.Conflict, "work order closed already")
+)
@@ -0,0 +1,139 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service/workorder/workorderRepo.go
+
+// Package mock_workorder is a generated GoMock package.
+package mock_workorder
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	workorder "github.com/pobyzaarif/belajarGo2/service/workorder"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockRepository) Close(ctx context.Context, id string, closedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close", ctx, id, closedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockRepositoryMockRecorder) Close(ctx, id, closedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRepository)(nil).Close), ctx, id, closedAt)
+}
+
+// CountOpenByCode mocks base method.
+func (m *MockRepository) CountOpenByCode(ctx context.Context, code string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountOpenByCode", ctx, code)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountOpenByCode indicates an expected call of CountOpenByCode.
+func (mr *MockRepositoryMockRecorder) CountOpenByCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountOpenByCode", reflect.TypeOf((*MockRepository)(nil).CountOpenByCode), ctx, code)
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(ctx context.Context, wo workorder.WorkOrder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, wo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(ctx, wo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, wo)
+}
+
+// ReadAll mocks base method.
+func (m *MockRepository) ReadAll(ctx context.Context, status string, page, limit int) ([]workorder.WorkOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAll", ctx, status, page, limit)
+	ret0, _ := ret[0].([]workorder.WorkOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAll indicates an expected call of ReadAll.
+func (mr *MockRepositoryMockRecorder) ReadAll(ctx, status, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAll", reflect.TypeOf((*MockRepository)(nil).ReadAll), ctx, status, page, limit)
+}
+
+// ReadByID mocks base method.
+func (m *MockRepository) ReadByID(ctx context.Context, id string) (workorder.WorkOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByID", ctx, id)
+	ret0, _ := ret[0].(workorder.WorkOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByID indicates an expected call of ReadByID.
+func (mr *MockRepositoryMockRecorder) ReadByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByID", reflect.TypeOf((*MockRepository)(nil).ReadByID), ctx, id)
+}
+
+// ReadRepairCost mocks base method.
+func (m *MockRepository) ReadRepairCost(ctx context.Context) ([]workorder.RepairCost, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadRepairCost", ctx)
+	ret0, _ := ret[0].([]workorder.RepairCost)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadRepairCost indicates an expected call of ReadRepairCost.
+func (mr *MockRepositoryMockRecorder) ReadRepairCost(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadRepairCost", reflect.TypeOf((*MockRepository)(nil).ReadRepairCost), ctx)
+}
+
+// Update mocks base method.
+func (m *MockRepository) Update(ctx context.Context, wo workorder.WorkOrder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, wo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRepositoryMockRecorder) Update(ctx, wo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, wo)
+}
@@ -0,0 +1,27 @@
+package workorder
+
+import "time"
+
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+type (
+	WorkOrder struct {
+		ID            string     `json:"id" bson:"work_order_id"`
+		InventoryCode string     `json:"inventory_code" bson:"inventory_code"`
+		Technician    string     `json:"technician"`
+		Status        string     `json:"status"`
+		Cost          int64      `json:"cost"`
+		Notes         string     `json:"notes"`
+		OpenedAt      time.Time  `json:"opened_at" bson:"opened_at"`
+		ClosedAt      *time.Time `json:"closed_at" bson:"closed_at"`
+	}
+
+	RepairCost struct {
+		InventoryCode string `json:"inventory_code" bson:"_id"`
+		WorkOrders    int    `json:"work_orders" bson:"work_orders"`
+		TotalCost     int64  `json:"total_cost" bson:"total_cost"`
+	}
+)
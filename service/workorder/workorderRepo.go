@@ -0,0 +1,20 @@
+package workorder
+
+import (
+	"context"
+	"time"
+)
+
+// Repository ReadByID returns ErrNotFound when nothing matches. Update writes
+// only the technician, cost and notes of an open work order, Close closes
+// only an open work order, both return ErrClosedAlready otherwise so a closed
+// order stays closed.
+type Repository interface {
+	Create(ctx context.Context, wo WorkOrder) (err error)
+	ReadAll(ctx context.Context, status string, page int, limit int) (wos []WorkOrder, err error)
+	ReadByID(ctx context.Context, id string) (wo WorkOrder, err error)
+	Update(ctx context.Context, wo WorkOrder) (err error)
+	Close(ctx context.Context, id string, closedAt time.Time) (err error)
+	CountOpenByCode(ctx context.Context, code string) (count int64, err error)
+	ReadRepairCost(ctx context.Context) (report []RepairCost, err error)
+}
@@ -0,0 +1,127 @@
+package workorder
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/transaction"
+)
+
+type service struct {
+	logger     *slog.Logger
+	repo       Repository
+	invRepo    inventory.Repository
+	transactor transaction.Transactor
+}
+
+type Service interface {
+	Open(ctx context.Context, wo WorkOrder) (id string, err error)
+	GetAll(ctx context.Context, status string, page int, limit int) (wos []WorkOrder, err error)
+	GetByID(ctx context.Context, id string) (wo WorkOrder, err error)
+	Update(ctx context.Context, wo WorkOrder) (err error)
+	Close(ctx context.Context, id string) (err error)
+	GetRepairCost(ctx context.Context) (report []RepairCost, err error)
+}
+
+func NewService(
+	logger *slog.Logger,
+	repo Repository,
+	invRepo inventory.Repository,
+	transactor transaction.Transactor,
+) Service {
+	return &service{
+		logger:     logger,
+		repo:       repo,
+		invRepo:    invRepo,
+		transactor: transactor,
+	}
+}
+
+func (s *service) Open(ctx context.Context, wo WorkOrder) (id string, err error) {
+	if wo.Cost < 0 {
+		return "", errs.New(errs.Validation, "invalid work order cost")
+	}
+
+	inv, err := s.invRepo.ReadByCode(ctx, wo.InventoryCode)
+	if err != nil {
+		return
+	}
+
+	wo.ID = uuid.NewString()
+	wo.Status = StatusOpen
+	wo.OpenedAt = time.Now()
+	wo.ClosedAt = nil
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, wo); err != nil {
+			return err
+		}
+
+		// An item under repair can't be used, make sure it's flagged as such.
+		// Only the status is written, loans may be changing the stock.
+		if inv.Status == "broken" {
+			return nil
+		}
+		return s.invRepo.SetStatus(ctx, inv.Code, "broken")
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return wo.ID, nil
+}
+
+func (s *service) GetAll(ctx context.Context, status string, page int, limit int) (wos []WorkOrder, err error) {
+	return s.repo.ReadAll(ctx, status, page, limit)
+}
+
+func (s *service) GetByID(ctx context.Context, id string) (wo WorkOrder, err error) {
+	return s.repo.ReadByID(ctx, id)
+}
+
+func (s *service) Update(ctx context.Context, wo WorkOrder) (err error) {
+	if wo.Cost < 0 {
+		return errs.New(errs.Validation, "invalid work order cost")
+	}
+
+	// repo.Update writes only the editable fields and refuses an order
+	// closed meanwhile, a concurrent Close isn't undone
+	return s.repo.Update(ctx, wo)
+}
+
+func (s *service) Close(ctx context.Context, id string) (err error) {
+	wo, err := s.repo.ReadByID(ctx, id)
+	if err != nil {
+		return
+	}
+	if wo.Status == StatusClosed {
+		return ErrClosedAlready
+	}
+
+	// repo.Close refuses an order closed meanwhile, so of two concurrent
+	// closes only one goes on to reactivate the item
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Close(ctx, wo.ID, time.Now()); err != nil {
+			return err
+		}
+
+		// Other units of the same code may still be in the workshop
+		openCount, err := s.repo.CountOpenByCode(ctx, wo.InventoryCode)
+		if err != nil {
+			return err
+		}
+		if openCount > 0 {
+			return nil
+		}
+
+		// A deleted item is left deleted, SetStatus doesn't create it
+		return s.invRepo.SetStatus(ctx, wo.InventoryCode, "active")
+	})
+}
+
+func (s *service) GetRepairCost(ctx context.Context) (report []RepairCost, err error) {
+	return s.repo.ReadRepairCost(ctx)
+}
@@ -0,0 +1,211 @@
+package workorder_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	mock_inventory "github.com/pobyzaarif/belajarGo2/service/inventory/mock"
+	mock_transaction "github.com/pobyzaarif/belajarGo2/service/transaction/mock"
+	"github.com/pobyzaarif/belajarGo2/service/workorder"
+	mock_workorder "github.com/pobyzaarif/belajarGo2/service/workorder/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+var loggerOption = slog.HandlerOptions{AddSource: true}
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, &loggerOption))
+
+var errDB = errors.New("db con error")
+
+type mocks struct {
+	repo       *mock_workorder.MockRepository
+	invRepo    *mock_inventory.MockRepository
+	transactor *mock_transaction.MockTransactor
+}
+
+func newService(ctrl *gomock.Controller) (workorder.Service, mocks) {
+	m := mocks{
+		repo:       mock_workorder.NewMockRepository(ctrl),
+		invRepo:    mock_inventory.NewMockRepository(ctrl),
+		transactor: mock_transaction.NewMockTransactor(ctrl),
+	}
+	// The unit of work runs as is, what it returns is what would be rolled back
+	m.transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	).AnyTimes()
+	return workorder.NewService(logger, m.repo, m.invRepo, m.transactor), m
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	input := workorder.WorkOrder{InventoryCode: "INV001", Technician: "Budi", Cost: 100}
+
+	tests := []struct {
+		name    string
+		input   workorder.WorkOrder
+		mock    func(m mocks)
+		wantErr error
+	}{
+		{
+			name:    "invalid cost",
+			input:   workorder.WorkOrder{InventoryCode: "INV001", Cost: -1},
+			mock:    func(m mocks) {},
+			wantErr: errs.Validation,
+		},
+		{
+			name:  "inventory not found",
+			input: input,
+			mock: func(m mocks) {
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), "INV001").Return(inventory.Inventory{}, inventory.ErrNotFound)
+			},
+			wantErr: inventory.ErrNotFound,
+		},
+		{
+			name:  "failed status fails the unit of work",
+			input: input,
+			mock: func(m mocks) {
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), "INV001").Return(inventory.Inventory{Code: "INV001", Status: "active"}, nil)
+				m.repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				m.invRepo.EXPECT().SetStatus(gomock.Any(), "INV001", "broken").Return(errDB)
+			},
+			wantErr: errDB,
+		},
+		{
+			name:  "flags the item broken",
+			input: input,
+			mock: func(m mocks) {
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), "INV001").Return(inventory.Inventory{Code: "INV001", Status: "active"}, nil)
+				m.repo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, wo workorder.WorkOrder) error {
+					assert.NotEmpty(t, wo.ID)
+					assert.Equal(t, workorder.StatusOpen, wo.Status)
+					assert.Nil(t, wo.ClosedAt)
+					return nil
+				})
+				m.invRepo.EXPECT().SetStatus(gomock.Any(), "INV001", "broken").Return(nil)
+			},
+		},
+		{
+			name:  "item broken already",
+			input: input,
+			mock: func(m mocks) {
+				m.invRepo.EXPECT().ReadByCode(gomock.Any(), "INV001").Return(inventory.Inventory{Code: "INV001", Status: "broken"}, nil)
+				m.repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			workOrderService, m := newService(ctrl)
+			tt.mock(m)
+
+			id, err := workOrderService.Open(ctx, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.NotEmpty(t, id)
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	ctx := context.Background()
+	open := workorder.WorkOrder{ID: "W1", InventoryCode: "INV001", Status: workorder.StatusOpen}
+
+	tests := []struct {
+		name    string
+		mock    func(m mocks)
+		wantErr error
+	}{
+		{
+			name: "work order not found",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "W1").Return(workorder.WorkOrder{}, workorder.ErrNotFound)
+			},
+			wantErr: workorder.ErrNotFound,
+		},
+		{
+			name: "closed already",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "W1").Return(workorder.WorkOrder{ID: "W1", Status: workorder.StatusClosed}, nil)
+			},
+			wantErr: workorder.ErrClosedAlready,
+		},
+		{
+			name: "closed concurrently leaves the item alone",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "W1").Return(open, nil)
+				m.repo.EXPECT().Close(gomock.Any(), "W1", gomock.Any()).Return(workorder.ErrClosedAlready)
+			},
+			wantErr: workorder.ErrClosedAlready,
+		},
+		{
+			name: "other units still in the workshop",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "W1").Return(open, nil)
+				m.repo.EXPECT().Close(gomock.Any(), "W1", gomock.Any()).Return(nil)
+				m.repo.EXPECT().CountOpenByCode(gomock.Any(), "INV001").Return(int64(1), nil)
+			},
+		},
+		{
+			name: "failed status fails the unit of work",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "W1").Return(open, nil)
+				m.repo.EXPECT().Close(gomock.Any(), "W1", gomock.Any()).Return(nil)
+				m.repo.EXPECT().CountOpenByCode(gomock.Any(), "INV001").Return(int64(0), nil)
+				m.invRepo.EXPECT().SetStatus(gomock.Any(), "INV001", "active").Return(errDB)
+			},
+			wantErr: errDB,
+		},
+		{
+			name: "reactivates the item",
+			mock: func(m mocks) {
+				m.repo.EXPECT().ReadByID(gomock.Any(), "W1").Return(open, nil)
+				m.repo.EXPECT().Close(gomock.Any(), "W1", gomock.Any()).Return(nil)
+				m.repo.EXPECT().CountOpenByCode(gomock.Any(), "INV001").Return(int64(0), nil)
+				m.invRepo.EXPECT().SetStatus(gomock.Any(), "INV001", "active").Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			workOrderService, m := newService(ctrl)
+			tt.mock(m)
+
+			err := workOrderService.Close(ctx, "W1")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	workOrderService, m := newService(ctrl)
+
+	err := workOrderService.Update(ctx, workorder.WorkOrder{ID: "W1", Cost: -1})
+	assert.ErrorIs(t, err, errs.Validation)
+
+	m.repo.EXPECT().Update(gomock.Any(), workorder.WorkOrder{ID: "W1", Cost: 10}).Return(workorder.ErrClosedAlready)
+	err = workOrderService.Update(ctx, workorder.WorkOrder{ID: "W1", Cost: 10})
+	assert.ErrorIs(t, err, workorder.ErrClosedAlready)
+
+	m.repo.EXPECT().Update(gomock.Any(), workorder.WorkOrder{ID: "W2", Technician: "Budi", Cost: 10}).Return(nil)
+	err = workOrderService.Update(ctx, workorder.WorkOrder{ID: "W2", Technician: "Budi", Cost: 10})
+	assert.Nil(t, err)
+}
@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -29,34 +32,119 @@ type Config struct {
 	DBPostgreSQLUser     string
 	DBPostgreSQLPassword string
 	DBPostgreSQLName     string
+	DBPostgreSQLSSLMode  string // disable, require, verify-ca or verify-full, empty means require
 
 	DBSQLiteName string
 
 	DBMongoURI  string
 	DBMongoName string
+
+	// Connection pool of DB_DRIVER, zero keeps the database/sql default
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// DBStatementTimeout makes mysql (SELECTs only) and postgres abort
+	// statements running longer, zero means no limit. sqlite has none.
+	DBStatementTimeout time.Duration
+
+	// Connection pool and timeouts of the Mongo client, zero keeps the driver
+	// default
+	DBMongoMaxPoolSize            uint64
+	DBMongoMinPoolSize            uint64
+	DBMongoMaxConnIdleTime        time.Duration
+	DBMongoConnectTimeout         time.Duration
+	DBMongoServerSelectionTimeout time.Duration
+
+	// A database that isn't reachable at startup is retried DBConnectRetries
+	// times, DBConnectRetryDelay apart, before giving up
+	DBConnectRetries    int
+	DBConnectRetryDelay time.Duration
+
+	// Read replicas of DB_DRIVER, host:port (file names for sqlite) sharing
+	// the credentials of the primary. Reads made with Read go to them.
+	DBMySQLReplicaHosts      []string
+	DBPostgreSQLReplicaHosts []string
+	DBSQLiteReplicaNames     []string
+
+	// DBReplicaReadAfterWrite keeps reads on the primary for that long after
+	// a write, covering the replication lag. Zero always reads from replicas.
+	DBReplicaReadAfterWrite time.Duration
+
+	// Replicas are pinged every DBReplicaHealthCheckInterval, 10s when zero,
+	// and skipped while they fail
+	DBReplicaHealthCheckInterval time.Duration
+}
+
+// gormConfig makes gorm translate driver specific errors (e.g. mysql 1062,
+// postgres 23505) into gorm errors such as gorm.ErrDuplicatedKey.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{TranslateError: true}
+}
+
+// IsDuplicateKeyError reports whether err is a unique constraint violation
+// from either gorm or mongo.
+func IsDuplicateKeyError(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) || mongo.IsDuplicateKeyError(err)
+}
+
+// retry calls connect until it succeeds or DBConnectRetries retries failed.
+func (conf *Config) retry(name string, connect func() error) (err error) {
+	for attempt := 0; ; attempt++ {
+		if err = connect(); err == nil || attempt >= conf.DBConnectRetries {
+			return
+		}
+		log.Printf("Failed to connect to %s (attempt %d of %d), retrying in %s: %v", name, attempt+1, conf.DBConnectRetries+1, conf.DBConnectRetryDelay, err)
+		time.Sleep(conf.DBConnectRetryDelay)
+	}
 }
 
-func (conf *Config) GetNoSQLDatabaseConnection() *mongo.Database {
+func (conf *Config) mongoClientOptions() *options.ClientOptions {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
 	opts := options.Client().ApplyURI(conf.DBMongoURI).SetServerAPIOptions(serverAPI)
-	// Create a new client and connect to the server
-	client, err := mongo.Connect(context.TODO(), opts)
-	if err != nil {
-		log.Fatal(err)
+	if conf.DBMongoMaxPoolSize > 0 {
+		opts.SetMaxPoolSize(conf.DBMongoMaxPoolSize)
 	}
-	// Send a ping to confirm a successful connection
-	if err := client.Ping(context.TODO(), readpref.Primary()); err != nil {
-		log.Fatal(err)
+	if conf.DBMongoMinPoolSize > 0 {
+		opts.SetMinPoolSize(conf.DBMongoMinPoolSize)
 	}
-
-	return client.Database(conf.DBMongoName)
+	if conf.DBMongoMaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(conf.DBMongoMaxConnIdleTime)
+	}
+	if conf.DBMongoConnectTimeout > 0 {
+		opts.SetConnectTimeout(conf.DBMongoConnectTimeout)
+	}
+	if conf.DBMongoServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(conf.DBMongoServerSelectionTimeout)
+	}
+	return opts
 }
 
-func (conf *Config) GetDatabaseConnection() *gorm.DB {
-	var err error
-	var db *gorm.DB
-	// conf.DBEnableDebug = true
+// GetNoSQLDatabaseConnection connects to DB_MONGO_URI, retrying as
+// configured, and returns the DB_MONGO_NAME database.
+func (conf *Config) GetNoSQLDatabaseConnection() (db *mongo.Database, err error) {
+	var client *mongo.Client
+	err = conf.retry("mongo", func() (err error) {
+		// Create a new client and connect to the server
+		client, err = mongo.Connect(context.TODO(), conf.mongoClientOptions())
+		if err != nil {
+			return
+		}
+		// Send a ping to confirm a successful connection
+		if err = client.Ping(context.TODO(), readpref.Primary()); err != nil {
+			_ = client.Disconnect(context.TODO())
+		}
+		return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connect to mongo: %w", err)
+	}
 
+	return client.Database(conf.DBMongoName), nil
+}
+
+func (conf *Config) dialector() (dialector gorm.Dialector, err error) {
 	switch conf.DBDriver {
 	case "mysql":
 		dsn := fmt.Sprintf(
@@ -67,41 +155,82 @@ func (conf *Config) GetDatabaseConnection() *gorm.DB {
 			conf.DBMySQLPort,
 			conf.DBMySQLName,
 		)
-
-		db, err = gorm.Open(mysql.Open(dsn))
-		if err != nil {
-			log.Fatal(err)
+		if conf.DBStatementTimeout > 0 {
+			// Unknown dsn params are set as session variables
+			dsn += fmt.Sprintf("&max_execution_time=%d", conf.DBStatementTimeout.Milliseconds())
 		}
+		return mysql.Open(dsn), nil
 	case "sqlite":
-		db, err = gorm.Open(sqlite.Open(conf.DBSQLiteName))
-		if err != nil {
-			log.Fatal(err)
-		}
+		return sqlite.Open(conf.DBSQLiteName), nil
 	case "postgres":
+		sslMode := conf.DBPostgreSQLSSLMode
+		if sslMode == "" {
+			sslMode = "require"
+		}
 		dsn := fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=require",
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 			conf.DBPostgreSQLHost,
 			conf.DBPostgreSQLPort,
 			conf.DBPostgreSQLUser,
 			conf.DBPostgreSQLPassword,
 			conf.DBPostgreSQLName,
+			sslMode,
 		)
-
-		db, err = gorm.Open(postgres.Open(dsn))
-		if err != nil {
-			log.Fatal(err)
+		if conf.DBStatementTimeout > 0 {
+			// Unknown dsn keys are sent as runtime parameters
+			dsn += fmt.Sprintf(" statement_timeout=%d", conf.DBStatementTimeout.Milliseconds())
 		}
-	default:
-		log.Fatal("unsupported driver")
+		return postgres.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("unsupported driver %q", conf.DBDriver)
+}
+
+// GetDatabaseConnection opens DB_DRIVER, retrying as configured, with its
+// pool settings and read replicas.
+func (conf *Config) GetDatabaseConnection() (db *gorm.DB, err error) {
+	// conf.DBEnableDebug = true
+	dialector, err := conf.dialector()
+	if err != nil {
+		return
 	}
 
+	err = conf.retry(conf.DBDriver, func() (err error) {
+		// mysql and postgres ping the server when opened
+		db, err = gorm.Open(dialector, gormConfig())
+		return
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("connect to %s: %w", conf.DBDriver, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	conf.setPool(sqlDB)
+
+	if err = conf.useReplicas(db); err != nil {
+		return nil, fmt.Errorf("%s replicas: %w", conf.DBDriver, err)
 	}
 
 	if conf.DBEnableDebug {
-		return db.Debug()
+		return db.Debug(), nil
 	}
 
-	return db
+	return db, nil
+}
+
+func (conf *Config) setPool(sqlDB *sql.DB) {
+	if conf.DBMaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(conf.DBMaxOpenConns)
+	}
+	if conf.DBMaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(conf.DBMaxIdleConns)
+	}
+	if conf.DBConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(conf.DBConnMaxLifetime)
+	}
+	if conf.DBConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(conf.DBConnMaxIdleTime)
+	}
 }
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestDialector(t *testing.T) {
+	conf := Config{
+		DBDriver:             "postgres",
+		DBPostgreSQLHost:     "localhost",
+		DBPostgreSQLPort:     "5432",
+		DBPostgreSQLUser:     "postgres",
+		DBPostgreSQLPassword: "secret",
+		DBPostgreSQLName:     "belajargo",
+	}
+	dialector, err := conf.dialector()
+	assert.Nil(t, err)
+	assert.Equal(t, "host=localhost port=5432 user=postgres password=secret dbname=belajargo sslmode=require", dialector.(*postgres.Dialector).Config.DSN)
+
+	conf.DBPostgreSQLSSLMode = "disable"
+	conf.DBStatementTimeout = 5 * time.Second
+	dialector, err = conf.dialector()
+	assert.Nil(t, err)
+	assert.Equal(t, "host=localhost port=5432 user=postgres password=secret dbname=belajargo sslmode=disable statement_timeout=5000", dialector.(*postgres.Dialector).Config.DSN)
+
+	conf = Config{
+		DBDriver:           "mysql",
+		DBMySQLHost:        "localhost",
+		DBMySQLPort:        "3306",
+		DBMySQLUser:        "root",
+		DBMySQLName:        "belajargo",
+		DBStatementTimeout: 1500 * time.Millisecond,
+	}
+	dialector, err = conf.dialector()
+	assert.Nil(t, err)
+	assert.Equal(t, "root:@tcp(localhost:3306)/belajargo?charset=utf8mb4&parseTime=True&loc=Local&max_execution_time=1500", dialector.(*mysql.Dialector).Config.DSN)
+
+	conf = Config{DBDriver: "oracle"}
+	_, err = conf.dialector()
+	assert.NotNil(t, err)
+}
+
+func TestGetDatabaseConnectionPool(t *testing.T) {
+	conf := Config{
+		DBDriver:       "sqlite",
+		DBSQLiteName:   filepath.Join(t.TempDir(), "test.db"),
+		DBMaxOpenConns: 3,
+	}
+	db, err := conf.GetDatabaseConnection()
+	assert.Nil(t, err)
+	sqlDB, err := db.DB()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestRetry(t *testing.T) {
+	conf := Config{DBConnectRetries: 2, DBConnectRetryDelay: time.Millisecond}
+
+	attempts := 0
+	err := conf.retry("test", func() error {
+		attempts++
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = conf.retry("test", func() error {
+		attempts++
+		if attempts < 2 {
+			return assert.AnError
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conf := Config{
+		DBDriver:             "sqlite",
+		DBSQLiteName:         filepath.Join(dir, "primary.db"),
+		DBSQLiteReplicaNames: []string{filepath.Join(dir, "replica.db")},
+	}
+
+	// Tell the databases apart by what the same table holds
+	type item struct{ Name string }
+	for name, file := range map[string]string{"primary": conf.DBSQLiteName, "replica": conf.DBSQLiteReplicaNames[0]} {
+		other := Config{DBDriver: "sqlite", DBSQLiteName: file}
+		db, err := other.GetDatabaseConnection()
+		assert.Nil(t, err)
+		assert.Nil(t, db.Exec("CREATE TABLE items (name TEXT)").Error)
+		assert.Nil(t, db.Exec("INSERT INTO items VALUES (?)", name).Error)
+	}
+
+	db, err := conf.GetDatabaseConnection()
+	assert.Nil(t, err)
+	db = db.Table("items")
+	r := db.Config.Plugins["belajargo:replicas"].(*resolver)
+	readFrom := func(conn *gorm.DB) string {
+		var got item
+		assert.Nil(t, conn.First(&got).Error)
+		return got.Name
+	}
+
+	assert.Equal(t, "replica", readFrom(Read(ctx, db)))
+	assert.Equal(t, "primary", readFrom(Conn(ctx, db)))
+	assert.Nil(t, db.Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, "primary", readFrom(Read(ContextWithTx(ctx, tx), db)))
+		return nil
+	}))
+
+	// Reads right after a write stay on the primary
+	r.readAfterWrite = time.Hour
+	assert.Nil(t, Conn(ctx, db).Create(&item{Name: "primary"}).Error)
+	assert.Equal(t, "primary", readFrom(Read(ctx, db)))
+	r.readAfterWrite = 0
+	assert.Equal(t, "replica", readFrom(Read(ctx, db)))
+
+	// A replica failing its health check is ejected
+	assert.Nil(t, r.replicas[0].db.Close())
+	r.checkHealth(time.Second)
+	assert.False(t, r.replicas[0].healthy.Load())
+	assert.Equal(t, "primary", readFrom(Read(ctx, db)))
+
+	// Close stops the health checks along with the connections
+	assert.Nil(t, Close(db))
+	assert.NotNil(t, db.Exec("SELECT 1").Error)
+	select {
+	case <-r.watchDone:
+	default:
+		t.Error("replicas still watched after Close")
+	}
+
+	// Replicas opened before one that fails aren't left open
+	opened, err := sql.Open("sqlite3", conf.DBSQLiteReplicaNames[0])
+	assert.Nil(t, err)
+	_, err = conf.openReplicas(
+		[]string{"replica", "broken"},
+		[]gorm.Dialector{&sqlite.Dialector{Conn: opened}, &sqlite.Dialector{DriverName: "unknown", DSN: "broken.db"}},
+	)
+	assert.NotNil(t, err)
+	assert.NotNil(t, opened.Ping())
+
+	conf = Config{DBDriver: "mysql", DBMySQLUser: "root", DBMySQLName: "belajargo", DBMySQLReplicaHosts: []string{"replica-1:3307"}}
+	_, dialectors, err := conf.replicaDialectors()
+	assert.Nil(t, err)
+	if assert.Len(t, dialectors, 1) {
+		assert.Equal(t, "root:@tcp(replica-1:3307)/belajargo?charset=utf8mb4&parseTime=True&loc=Local", dialectors[0].(*mysql.Dialector).Config.DSN)
+	}
+
+	conf.DBMySQLReplicaHosts = []string{"replica-1"}
+	_, _, err = conf.replicaDialectors()
+	assert.NotNil(t, err)
+}
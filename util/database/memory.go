@@ -0,0 +1,18 @@
+package database
+
+// Page returns the items of page (1-based) when items are cut in pages of
+// limit, the in-memory counterpart of OFFSET/LIMIT.
+func Page[T any](items []T, page int, limit int) []T {
+	if page < 1 || limit < 1 {
+		return nil
+	}
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return nil
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
@@ -0,0 +1,396 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	migrationTable     = "schema_migrations"
+	migrationLockTable = "schema_migrations_lock"
+	migrationLockName  = "belajargo_schema_migrations"
+
+	// migrationLockKey is the postgres advisory lock key, any constant that
+	// doesn't collide with other advisory locks of the database works
+	migrationLockKey = 7354900111
+
+	defaultMigrationLockTimeout = time.Minute
+)
+
+var (
+	ErrMigrationLocked       = errors.New("migration lock is held by another process")
+	ErrMigrationIrreversible = errors.New("migration has no down script")
+	ErrMigrationUnknown      = errors.New("applied migration is unknown to this build")
+)
+
+// migrationFileName matches <version>_<name>.<up|down>[.<driver>].sql, a file
+// suffixed with a driver overrides the generic one for that driver.
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)(?:\.(mysql|postgres|sqlite))?\.sql$`)
+
+// Migration is one versioned schema change, Up applies it and Down reverts it.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a known migration and when it was applied, AppliedAt is
+// nil for pending ones.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// LoadMigrations reads the migrations in fsys for driver, sorted by version.
+func LoadMigrations(fsys fs.FS, driver string) (migrations []Migration, err error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	overridden := map[string]bool{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		if match[4] != "" && match[4] != driver {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		key := match[1] + "." + match[3]
+		if match[4] == "" && overridden[key] {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+		if match[4] != "" {
+			overridden[key] = true
+		}
+	}
+
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrations returns the migrations embedded in the binary for driver.
+func Migrations(driver string) (migrations []Migration, err error) {
+	fsys, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(fsys, driver)
+}
+
+// splitStatements splits a script on the semicolons ending a line, drivers
+// don't agree on running several statements in a single Exec.
+func splitStatements(script string) (statements []string) {
+	for _, stmt := range regexp.MustCompile(`;\s*(\n|$)`).Split(script, -1) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+// Migrator applies versioned migrations to a gorm database (mysql, postgres
+// or sqlite), recording them in the schema_migrations table.
+//
+// Each migration runs in its own transaction. Postgres and sqlite roll a
+// failed migration back entirely, mysql commits DDL implicitly so a failed
+// migration there may need manual cleanup.
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	LockTimeout time.Duration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:          db,
+		migrations:  migrations,
+		LockTimeout: defaultMigrationLockTimeout,
+	}
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied.
+func (m *Migrator) Up(ctx context.Context) (applied []MigrationStatus, err error) {
+	err = m.withLock(ctx, func(db *gorm.DB) error {
+		done, err := appliedVersions(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			appliedAt := time.Now()
+			if err := run(db, migration.Up, func(tx *gorm.DB) error {
+				return tx.Table(migrationTable).Create(&schemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: appliedAt,
+				}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, MigrationStatus{Version: migration.Version, Name: migration.Name, AppliedAt: &appliedAt})
+		}
+		return nil
+	})
+	return
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted []MigrationStatus, err error) {
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	err = m.withLock(ctx, func(db *gorm.DB) error {
+		var rows []schemaMigration
+		if err := db.Table(migrationTable).Order("version DESC").Limit(steps).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			migration, ok := known[row.Version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s: %w", row.Version, row.Name, ErrMigrationUnknown)
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrMigrationIrreversible)
+			}
+
+			if err := run(db, migration.Down, func(tx *gorm.DB) error {
+				return tx.Table(migrationTable).Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, MigrationStatus{Version: migration.Version, Name: migration.Name})
+		}
+		return nil
+	})
+	return
+}
+
+// Status lists the known migrations and whether they are applied.
+func (m *Migrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	if err = m.ensureTables(ctx); err != nil {
+		return
+	}
+
+	done, err := appliedVersions(m.db.WithContext(ctx))
+	if err != nil {
+		return
+	}
+
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ForceUnlock releases a lock left behind by a process that died while
+// migrating. Only sqlite needs it, mysql and postgres locks end with the
+// connection holding them.
+func (m *Migrator) ForceUnlock(ctx context.Context) (err error) {
+	if m.db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+	if err = m.ensureTables(ctx); err != nil {
+		return
+	}
+	return m.db.WithContext(ctx).Exec("DELETE FROM " + migrationLockTable).Error
+}
+
+func run(db *gorm.DB, script string, record func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) (err error) {
+	db := m.db.WithContext(ctx)
+	err = db.Exec("CREATE TABLE IF NOT EXISTS " + migrationTable + ` (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`).Error
+	if err != nil || m.db.Dialector.Name() != "sqlite" {
+		return
+	}
+	return db.Exec("CREATE TABLE IF NOT EXISTS " + migrationLockTable + " (id INT PRIMARY KEY, locked_at TIMESTAMP NOT NULL)").Error
+}
+
+func appliedVersions(db *gorm.DB) (done map[int64]time.Time, err error) {
+	var rows []schemaMigration
+	if err = db.Table(migrationTable).Find(&rows).Error; err != nil {
+		return
+	}
+
+	done = make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
+
+// withLock runs fn while holding the migration lock so two processes never
+// migrate at once. mysql and postgres use their advisory locks, sqlite a row
+// in schema_migrations_lock. fn is given db on the connection holding the
+// lock, it migrates with a single connection so DB_MAX_OPEN_CONNS=1 works.
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) (err error) {
+	if err = m.ensureTables(ctx); err != nil {
+		return
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return
+	}
+	// Advisory locks belong to a session, lock and unlock on the same connection
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var unlock func() error
+	switch m.db.Dialector.Name() {
+	case "mysql":
+		var got sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, int(m.LockTimeout.Seconds())).Scan(&got)
+		if err == nil && got.Int64 != 1 {
+			err = ErrMigrationLocked
+		}
+		unlock = func() error {
+			_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLockName)
+			return err
+		}
+	case "postgres":
+		err = retryLock(ctx, m.LockTimeout, func() (ok bool, err error) {
+			err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockKey).Scan(&ok)
+			return
+		})
+		unlock = func() error {
+			_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+			return err
+		}
+	default:
+		err = retryLock(ctx, m.LockTimeout, func() (ok bool, err error) {
+			_, err = conn.ExecContext(ctx, "INSERT INTO "+migrationLockTable+" (id, locked_at) VALUES (1, ?)", time.Now())
+			if translator, ok := m.db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+				err = translator.Translate(err)
+			}
+			if IsDuplicateKeyError(err) {
+				return false, nil
+			}
+			return err == nil, err
+		})
+		unlock = func() error {
+			_, err := conn.ExecContext(context.Background(), "DELETE FROM "+migrationLockTable+" WHERE id = 1")
+			return err
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if errUnlock := unlock(); errUnlock != nil && err == nil {
+			err = errUnlock
+		}
+	}()
+
+	// What gorm's DB.Connection does, every statement of db uses conn
+	db := m.db.WithContext(ctx)
+	db.Statement.ConnPool = conn
+	return fn(db)
+}
+
+// retryLock calls try until it gets the lock, ctx is done or timeout passes.
+func retryLock(ctx context.Context, timeout time.Duration, try func() (ok bool, err error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := try()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigration is one versioned change to a Mongo database, usually index
+// creation, Up applies it and Down reverts it.
+type MongoMigration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+type mongoSchemaMigration struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// MongoMigrator is the Mongo counterpart of Migrator, it records applied
+// migrations in the schema_migrations collection and holds a lock document in
+// schema_migrations_lock while migrating.
+type MongoMigrator struct {
+	db          *mongo.Database
+	migrations  []MongoMigration
+	LockTimeout time.Duration
+}
+
+func NewMongoMigrator(db *mongo.Database, migrations []MongoMigration) *MongoMigrator {
+	sorted := append([]MongoMigration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &MongoMigrator{
+		db:          db,
+		migrations:  sorted,
+		LockTimeout: defaultMigrationLockTimeout,
+	}
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied.
+func (m *MongoMigrator) Up(ctx context.Context) (applied []MigrationStatus, err error) {
+	err = m.withLock(ctx, func() error {
+		done, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			appliedAt := time.Now()
+			if err := migration.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := m.db.Collection(migrationTable).InsertOne(ctx, mongoSchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: appliedAt,
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, MigrationStatus{Version: migration.Version, Name: migration.Name, AppliedAt: &appliedAt})
+		}
+		return nil
+	})
+	return
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *MongoMigrator) Down(ctx context.Context, steps int) (reverted []MigrationStatus, err error) {
+	known := make(map[int64]MongoMigration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	err = m.withLock(ctx, func() error {
+		opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(steps))
+		cursor, err := m.db.Collection(migrationTable).Find(ctx, bson.M{}, opts)
+		if err != nil {
+			return err
+		}
+		var rows []mongoSchemaMigration
+		if err := cursor.All(ctx, &rows); err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			migration, ok := known[row.Version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s: %w", row.Version, row.Name, ErrMigrationUnknown)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrMigrationIrreversible)
+			}
+
+			if err := migration.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := m.db.Collection(migrationTable).DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, MigrationStatus{Version: migration.Version, Name: migration.Name})
+		}
+		return nil
+	})
+	return
+}
+
+// Status lists the known migrations and whether they are applied.
+func (m *MongoMigrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	done, err := m.appliedVersions(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ForceUnlock releases a lock left behind by a process that died while
+// migrating.
+func (m *MongoMigrator) ForceUnlock(ctx context.Context) (err error) {
+	_, err = m.db.Collection(migrationLockTable).DeleteOne(ctx, bson.M{"_id": migrationLockName})
+	return
+}
+
+func (m *MongoMigrator) appliedVersions(ctx context.Context) (done map[int64]time.Time, err error) {
+	cursor, err := m.db.Collection(migrationTable).Find(ctx, bson.M{})
+	if err != nil {
+		return
+	}
+	var rows []mongoSchemaMigration
+	if err = cursor.All(ctx, &rows); err != nil {
+		return
+	}
+
+	done = make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
+
+// withLock runs fn while holding the lock document, inserting it fails on the
+// duplicate _id as long as another process holds it.
+func (m *MongoMigrator) withLock(ctx context.Context, fn func() error) (err error) {
+	col := m.db.Collection(migrationLockTable)
+	err = retryLock(ctx, m.LockTimeout, func() (ok bool, err error) {
+		_, err = col.InsertOne(ctx, bson.M{"_id": migrationLockName, "locked_at": time.Now()})
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		_, errUnlock := col.DeleteOne(context.Background(), bson.M{"_id": migrationLockName})
+		if errUnlock != nil && err == nil {
+			err = errUnlock
+		}
+	}()
+	return fn()
+}
+
+// createIndex creates model on collection, a no-op when an index with the
+// same name and keys exists already.
+func createIndex(ctx context.Context, db *mongo.Database, collection string, model mongo.IndexModel) error {
+	_, err := db.Collection(collection).Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// dropIndex drops the index name of collection, missing indexes are ignored
+// so a half applied migration can still be reverted.
+func dropIndex(ctx context.Context, db *mongo.Database, collection string, name string) error {
+	_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/util/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newSQLite(t *testing.T) *gorm.DB {
+	databaseConfig := database.Config{
+		DBDriver:     "sqlite",
+		DBSQLiteName: filepath.Join(t.TempDir(), "test.db"),
+	}
+	db, err := databaseConfig.GetDatabaseConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_b.up.sql":          {Data: []byte("CREATE TABLE b (id INT);")},
+		"0002_add_b.down.sql":        {Data: []byte("DROP TABLE b;")},
+		"0001_add_a.up.sql":          {Data: []byte("CREATE TABLE a (id INT);")},
+		"0001_add_a.up.postgres.sql": {Data: []byte("CREATE TABLE a (id SERIAL);")},
+		"README.md":                  {Data: []byte("not a migration")},
+	}
+
+	migrations, err := database.LoadMigrations(fsys, "sqlite")
+	assert.Nil(t, err)
+	if assert.Len(t, migrations, 2) {
+		assert.Equal(t, database.Migration{Version: 1, Name: "add_a", Up: "CREATE TABLE a (id INT);"}, migrations[0])
+		assert.Equal(t, database.Migration{Version: 2, Name: "add_b", Up: "CREATE TABLE b (id INT);", Down: "DROP TABLE b;"}, migrations[1])
+	}
+
+	migrations, err = database.LoadMigrations(fsys, "postgres")
+	assert.Nil(t, err)
+	if assert.Len(t, migrations, 2) {
+		assert.Equal(t, "CREATE TABLE a (id SERIAL);", migrations[0].Up)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLite(t)
+
+	migrations, err := database.Migrations("sqlite")
+	assert.Nil(t, err)
+	migrator := database.NewMigrator(db, migrations)
+
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(migrations))
+	assert.True(t, db.Migrator().HasTable("bg_refresh_tokens"))
+
+	// Nothing left to apply the second time
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 0)
+
+	reverted, err := migrator.Down(ctx, 2)
+	assert.Nil(t, err)
+	if assert.Len(t, reverted, 2) {
+		assert.Equal(t, migrations[len(migrations)-1].Version, reverted[0].Version)
+		assert.Equal(t, migrations[len(migrations)-2].Version, reverted[1].Version)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, statuses, len(migrations)) {
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+	}
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 2)
+
+	// Every down migration reverts its up migration
+	reverted, err = migrator.Down(ctx, len(migrations))
+	assert.Nil(t, err)
+	assert.Len(t, reverted, len(migrations))
+	assert.False(t, db.Migrator().HasTable("bg_users"))
+	assert.False(t, db.Migrator().HasTable("bg_refresh_tokens"))
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(migrations))
+}
+
+func TestMigratorSingleConnection(t *testing.T) {
+	// The lock holds the only connection, migrating must not wait for another
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	databaseConfig := database.Config{
+		DBDriver:       "sqlite",
+		DBSQLiteName:   filepath.Join(t.TempDir(), "test.db"),
+		DBMaxOpenConns: 1,
+	}
+	db, err := databaseConfig.GetDatabaseConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := database.Migrations("sqlite")
+	assert.Nil(t, err)
+	migrator := database.NewMigrator(db, migrations)
+
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(migrations))
+
+	reverted, err := migrator.Down(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, 1)
+}
+
+func TestMigratorRollbackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLite(t)
+
+	migrator := database.NewMigrator(db, []database.Migration{
+		{Version: 1, Name: "add_a", Up: "CREATE TABLE a (id INT);"},
+		{Version: 2, Name: "add_b", Up: "CREATE TABLE b (id INT);\nCREATE TABLE a (id INT);"},
+	})
+
+	applied, err := migrator.Up(ctx)
+	assert.NotNil(t, err)
+	assert.Len(t, applied, 1)
+	assert.True(t, db.Migrator().HasTable("a"))
+	assert.False(t, db.Migrator().HasTable("b"))
+
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Nil(t, statuses[1].AppliedAt)
+	}
+}
+
+func TestMigratorLock(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLite(t)
+
+	migrator := database.NewMigrator(db, []database.Migration{
+		{Version: 1, Name: "add_a", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+	})
+	migrator.LockTimeout = 300 * time.Millisecond
+
+	// A lock left behind by a crashed run
+	_, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Exec("INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", time.Now()).Error)
+
+	_, err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, database.ErrMigrationLocked)
+	assert.False(t, db.Migrator().HasTable("a"))
+
+	assert.Nil(t, migrator.ForceUnlock(ctx))
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 1)
+
+	// The lock is released after a run
+	_, err = migrator.Down(ctx, 1)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,7 @@
+CREATE TABLE bg_inventories (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    stock INT NOT NULL DEFAULT 0,
+    description TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT ''
+);
@@ -1,5 +1,3 @@
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
 CREATE TABLE bg_users (
     id VARCHAR(40) PRIMARY KEY,
     email VARCHAR(254) NOT NULL UNIQUE,
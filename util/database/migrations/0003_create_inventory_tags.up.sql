@@ -0,0 +1,7 @@
+CREATE TABLE bg_inventory_tags (
+    code VARCHAR(50) NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (code, tag)
+);
+
+CREATE INDEX idx_bg_inventory_tags_tag ON bg_inventory_tags (tag);
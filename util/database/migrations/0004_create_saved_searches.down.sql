@@ -0,0 +1 @@
+DROP TABLE bg_saved_searches;
@@ -0,0 +1,7 @@
+CREATE TABLE bg_saved_searches (
+    id VARCHAR(40) PRIMARY KEY,
+    user_id VARCHAR(40) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    filter TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
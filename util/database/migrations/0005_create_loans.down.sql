@@ -0,0 +1 @@
+DROP TABLE bg_loans;
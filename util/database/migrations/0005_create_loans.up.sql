@@ -0,0 +1,13 @@
+CREATE TABLE bg_loans (
+    id VARCHAR(40) PRIMARY KEY,
+    inventory_code VARCHAR(50) NOT NULL,
+    user_id VARCHAR(40) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    checked_out_at TIMESTAMP NOT NULL,
+    due_at TIMESTAMP NOT NULL,
+    returned_at TIMESTAMP NULL,
+    reminder_sent_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_bg_loans_user_id ON bg_loans (user_id);
+CREATE INDEX idx_bg_loans_due_at ON bg_loans (due_at);
@@ -0,0 +1 @@
+DROP TABLE bg_work_orders;
@@ -0,0 +1,13 @@
+CREATE TABLE bg_work_orders (
+    id VARCHAR(40) PRIMARY KEY,
+    inventory_code VARCHAR(50) NOT NULL,
+    serial VARCHAR(100) NOT NULL DEFAULT '',
+    technician VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('open', 'closed')),
+    cost BIGINT NOT NULL DEFAULT 0,
+    notes TEXT,
+    opened_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_bg_work_orders_inventory_code ON bg_work_orders (inventory_code, status);
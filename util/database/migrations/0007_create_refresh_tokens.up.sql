@@ -0,0 +1,12 @@
+CREATE TABLE bg_refresh_tokens (
+    id VARCHAR(40) PRIMARY KEY,
+    family_id VARCHAR(40) NOT NULL,
+    user_id VARCHAR(40) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_bg_refresh_tokens_family_id ON bg_refresh_tokens (family_id);
@@ -0,0 +1 @@
+DROP INDEX idx_bg_refresh_tokens_user_id;
@@ -0,0 +1 @@
+CREATE INDEX idx_bg_refresh_tokens_user_id ON bg_refresh_tokens (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE bg_users DROP COLUMN verification_email_sent_at;
+ALTER TABLE bg_users DROP COLUMN verification_email_status;
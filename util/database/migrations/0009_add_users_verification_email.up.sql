@@ -0,0 +1,2 @@
+ALTER TABLE bg_users ADD COLUMN verification_email_status VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE bg_users ADD COLUMN verification_email_sent_at TIMESTAMP NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE bg_user_mfa (
+    user_id VARCHAR(40) PRIMARY KEY,
+    secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    recovery_codes TEXT,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE bg_users DROP COLUMN deactivated_at;
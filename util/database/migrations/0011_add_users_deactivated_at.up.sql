@@ -0,0 +1 @@
+ALTER TABLE bg_users ADD COLUMN deactivated_at TIMESTAMP NULL;
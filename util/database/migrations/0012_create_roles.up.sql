@@ -0,0 +1,5 @@
+CREATE TABLE bg_roles (
+    name VARCHAR(40) PRIMARY KEY,
+    permissions TEXT,
+    updated_at TIMESTAMP NOT NULL
+);
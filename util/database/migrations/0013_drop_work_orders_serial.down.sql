@@ -0,0 +1 @@
+ALTER TABLE bg_work_orders ADD COLUMN serial VARCHAR(100) NOT NULL DEFAULT '';
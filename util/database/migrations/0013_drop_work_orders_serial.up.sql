@@ -0,0 +1 @@
+ALTER TABLE bg_work_orders DROP COLUMN serial;
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations are the index migrations of the Mongo repositories, append
+// new ones with the next version, never edit an applied one.
+func MongoMigrations() []MongoMigration {
+	return []MongoMigration{
+		{
+			Version: 1,
+			Name:    "create_inventories_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				if err := createIndex(ctx, db, "inventories", mongo.IndexModel{
+					Keys:    bson.D{{Key: "code", Value: 1}},
+					Options: options.Index().SetName("code_1").SetUnique(true),
+				}); err != nil {
+					return err
+				}
+				return createIndex(ctx, db, "inventories", mongo.IndexModel{
+					Keys:    bson.D{{Key: "tags", Value: 1}},
+					Options: options.Index().SetName("tags_1"),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				if err := dropIndex(ctx, db, "inventories", "tags_1"); err != nil {
+					return err
+				}
+				return dropIndex(ctx, db, "inventories", "code_1")
+			},
+		},
+		{
+			Version: 2,
+			Name:    "create_users_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db, "users", mongo.IndexModel{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_1").SetUnique(true),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				return dropIndex(ctx, db, "users", "email_1")
+			},
+		},
+		{
+			Version: 3,
+			Name:    "create_saved_searches_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db, "saved_searches", mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}},
+					Options: options.Index().SetName("user_id_1_name_1").SetUnique(true),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				return dropIndex(ctx, db, "saved_searches", "user_id_1_name_1")
+			},
+		},
+		{
+			Version: 4,
+			Name:    "create_loans_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				if err := createIndex(ctx, db, "loans", mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("user_id_1"),
+				}); err != nil {
+					return err
+				}
+				return createIndex(ctx, db, "loans", mongo.IndexModel{
+					Keys:    bson.D{{Key: "due_at", Value: 1}},
+					Options: options.Index().SetName("due_at_1"),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				if err := dropIndex(ctx, db, "loans", "due_at_1"); err != nil {
+					return err
+				}
+				return dropIndex(ctx, db, "loans", "user_id_1")
+			},
+		},
+		{
+			Version: 5,
+			Name:    "create_work_orders_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db, "work_orders", mongo.IndexModel{
+					Keys:    bson.D{{Key: "inventory_code", Value: 1}, {Key: "status", Value: 1}},
+					Options: options.Index().SetName("inventory_code_1_status_1"),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				return dropIndex(ctx, db, "work_orders", "inventory_code_1_status_1")
+			},
+		},
+		{
+			// Like the sql primary keys, ids are unique
+			Version: 6,
+			Name:    "create_unique_id_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				if err := createIndex(ctx, db, "users", mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("user_id_1").SetUnique(true),
+				}); err != nil {
+					return err
+				}
+				return createIndex(ctx, db, "saved_searches", mongo.IndexModel{
+					Keys:    bson.D{{Key: "search_id", Value: 1}},
+					Options: options.Index().SetName("search_id_1").SetUnique(true),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				if err := dropIndex(ctx, db, "saved_searches", "search_id_1"); err != nil {
+					return err
+				}
+				return dropIndex(ctx, db, "users", "user_id_1")
+			},
+		},
+		{
+			Version: 7,
+			Name:    "create_refresh_tokens_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				if err := createIndex(ctx, db, "refresh_tokens", mongo.IndexModel{
+					Keys:    bson.D{{Key: "token_hash", Value: 1}},
+					Options: options.Index().SetName("token_hash_1").SetUnique(true),
+				}); err != nil {
+					return err
+				}
+				if err := createIndex(ctx, db, "refresh_tokens", mongo.IndexModel{
+					Keys:    bson.D{{Key: "token_id", Value: 1}},
+					Options: options.Index().SetName("token_id_1").SetUnique(true),
+				}); err != nil {
+					return err
+				}
+				return createIndex(ctx, db, "refresh_tokens", mongo.IndexModel{
+					Keys:    bson.D{{Key: "family_id", Value: 1}},
+					Options: options.Index().SetName("family_id_1"),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				for _, name := range []string{"family_id_1", "token_id_1", "token_hash_1"} {
+					if err := dropIndex(ctx, db, "refresh_tokens", name); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 8,
+			Name:    "index_refresh_tokens_user_id",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db, "refresh_tokens", mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("user_id_1"),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				return dropIndex(ctx, db, "refresh_tokens", "user_id_1")
+			},
+		},
+		{
+			Version: 9,
+			Name:    "create_user_mfa_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db, "user_mfa", mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("user_id_1").SetUnique(true),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				return dropIndex(ctx, db, "user_mfa", "user_id_1")
+			},
+		},
+		{
+			Version: 10,
+			Name:    "create_roles_indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return createIndex(ctx, db, "roles", mongo.IndexModel{
+					Keys:    bson.D{{Key: "name", Value: 1}},
+					Options: options.Index().SetName("name_1").SetUnique(true),
+				})
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				return dropIndex(ctx, db, "roles", "name_1")
+			},
+		},
+	}
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	readKey      = "belajargo:read"
+	resolverName = "belajargo:replicas"
+)
+
+// Read is Conn for queries that may be served by a read replica. They stay on
+// the primary inside a transaction, when no replica is configured or healthy,
+// and for DBReplicaReadAfterWrite after a write.
+func Read(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return Conn(ctx, db).Set(readKey, true)
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// resolver is the gorm plugin sending the queries marked by Read to the
+// healthy replicas, round robin.
+type resolver struct {
+	replicas       []*replica
+	next           atomic.Uint64
+	lastWrite      atomic.Int64
+	readAfterWrite time.Duration
+	stopWatch      context.CancelFunc
+	watchDone      chan struct{}
+}
+
+func (r *resolver) Name() string {
+	return resolverName
+}
+
+func (r *resolver) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("belajargo:route_read", r.route); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("belajargo:route_read", r.route); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("belajargo:record_write", r.recordWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("belajargo:record_write", r.recordWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("belajargo:record_write", r.recordWrite); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("belajargo:record_write", r.recordWrite)
+}
+
+func (r *resolver) route(db *gorm.DB) {
+	if read, _ := db.Get(readKey); read != true {
+		return
+	}
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if time.Since(time.Unix(0, r.lastWrite.Load())) < r.readAfterWrite {
+		return
+	}
+	if replica := r.pick(); replica != nil {
+		db.Statement.ConnPool = replica.db
+	}
+}
+
+func (r *resolver) recordWrite(db *gorm.DB) {
+	if db.Error == nil {
+		r.lastWrite.Store(time.Now().UnixNano())
+	}
+}
+
+// pick returns the next healthy replica, nil when there is none.
+func (r *resolver) pick() *replica {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if replica := r.replicas[(start+i)%n]; replica.healthy.Load() {
+			return replica
+		}
+	}
+	return nil
+}
+
+// checkHealth pings every replica, ejecting the ones that fail until they
+// answer again.
+func (r *resolver) checkHealth(timeout time.Duration) {
+	for _, replica := range r.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := replica.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if replica.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			log.Printf("Replica %s is healthy again, reading from it", replica.name)
+		} else {
+			log.Printf("Replica %s is unhealthy, reading from the others: %v", replica.name, err)
+		}
+	}
+}
+
+// watch checks the health of the replicas every interval until ctx is done.
+func (r *resolver) watch(ctx context.Context, interval time.Duration) {
+	defer close(r.watchDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkHealth(interval)
+		}
+	}
+}
+
+// close stops the health checks, waiting for a running one, and closes the
+// replicas.
+func (r *resolver) close() (err error) {
+	r.stopWatch()
+	<-r.watchDone
+	return r.closeReplicas()
+}
+
+func (r *resolver) closeReplicas() (err error) {
+	for _, replica := range r.replicas {
+		err = errors.Join(err, replica.db.Close())
+	}
+	return
+}
+
+// replicaDialectors returns the replicas of DB_DRIVER by name, they share the
+// credentials and settings of the primary.
+func (conf *Config) replicaDialectors() (names []string, dialectors []gorm.Dialector, err error) {
+	switch conf.DBDriver {
+	case "mysql":
+		names = conf.DBMySQLReplicaHosts
+	case "postgres":
+		names = conf.DBPostgreSQLReplicaHosts
+	case "sqlite":
+		names = conf.DBSQLiteReplicaNames
+	}
+
+	for _, name := range names {
+		replicaConf := *conf
+		switch conf.DBDriver {
+		case "mysql":
+			replicaConf.DBMySQLHost, replicaConf.DBMySQLPort, err = net.SplitHostPort(name)
+		case "postgres":
+			replicaConf.DBPostgreSQLHost, replicaConf.DBPostgreSQLPort, err = net.SplitHostPort(name)
+		case "sqlite":
+			replicaConf.DBSQLiteName = name
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dialector, err := replicaConf.dialector()
+		if err != nil {
+			return nil, nil, err
+		}
+		dialectors = append(dialectors, dialector)
+	}
+	return
+}
+
+// openReplicas opens the connection pools of the replicas, when one fails
+// those opened before it are closed.
+func (conf *Config) openReplicas(names []string, dialectors []gorm.Dialector) (r *resolver, err error) {
+	r = &resolver{readAfterWrite: conf.DBReplicaReadAfterWrite, watchDone: make(chan struct{})}
+	for i, dialector := range dialectors {
+		replicaDB, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			return nil, errors.Join(err, r.closeReplicas())
+		}
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			return nil, errors.Join(err, r.closeReplicas())
+		}
+		conf.setPool(sqlDB)
+
+		replica := &replica{name: names[i], db: sqlDB}
+		replica.healthy.Store(true)
+		r.replicas = append(r.replicas, replica)
+	}
+	return r, nil
+}
+
+// useReplicas opens the configured replicas and routes the reads of db to
+// them. A replica that is down at startup is only ejected, not fatal.
+func (conf *Config) useReplicas(db *gorm.DB) (err error) {
+	names, dialectors, err := conf.replicaDialectors()
+	if err != nil || len(dialectors) == 0 {
+		return
+	}
+
+	r, err := conf.openReplicas(names, dialectors)
+	if err != nil {
+		return err
+	}
+
+	interval := conf.DBReplicaHealthCheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	r.checkHealth(interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stopWatch = cancel
+	go r.watch(ctx, interval)
+
+	return db.Use(r)
+}
+
+// Close closes db, opened by GetDatabaseConnection, along with its replicas
+// and their health checks.
+func Close(db *gorm.DB) (err error) {
+	if plugin, ok := db.Config.Plugins[resolverName].(*resolver); ok {
+		err = plugin.close()
+	}
+
+	sqlDB, errDB := db.DB()
+	if errDB != nil {
+		return errors.Join(err, errDB)
+	}
+	return errors.Join(err, sqlDB.Close())
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// ContextWithTx returns a copy of ctx carrying the gorm transaction tx.
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the gorm transaction carried by ctx, if any.
+func TxFromContext(ctx context.Context) (tx *gorm.DB, ok bool) {
+	tx, ok = ctx.Value(txKey{}).(*gorm.DB)
+	return
+}
+
+// Conn returns db bound to ctx. When ctx carries a transaction the query joins
+// it instead, keeping the table db was scoped to.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Table(db.Statement.Table).WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
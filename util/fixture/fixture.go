@@ -0,0 +1,212 @@
+package fixture
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/service/user"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures
+var fixtureFiles embed.FS
+
+var validRoles = map[string]bool{
+	"user":       true,
+	"admin":      true,
+	"superadmin": true,
+}
+
+var validStatuses = map[string]bool{
+	"active": true,
+	"broken": true,
+}
+
+type (
+	// Set is a named group of fixtures, read from every .yaml, .yml and .json
+	// file of its directory.
+	Set struct {
+		Inventories []inventory.Inventory `json:"inventories" yaml:"inventories"`
+		Users       []User                `json:"users" yaml:"users"`
+	}
+
+	// User is a fixture account, Password is plain text and gets hashed when
+	// the user is seeded.
+	User struct {
+		Email           string `json:"email" yaml:"email"`
+		Password        string `json:"password" yaml:"password"`
+		Fullname        string `json:"fullname" yaml:"fullname"`
+		Role            string `json:"role" yaml:"role"`
+		IsEmailVerified bool   `json:"is_email_verified" yaml:"is_email_verified"`
+	}
+
+	// Result counts what a Seed call created and what already existed.
+	Result struct {
+		InventoriesCreated int
+		InventoriesSkipped int
+		UsersCreated       int
+		UsersSkipped       int
+	}
+)
+
+// Sets lists the fixture sets found in fsys, one per directory.
+func Sets(fsys fs.FS) (names []string, err error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// LoadSet reads the fixture set name from fsys, files are merged in name order.
+func LoadSet(fsys fs.FS, name string) (set Set, err error) {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return set, fmt.Errorf("fixture set %s: %w", name, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		file := path.Join(name, entry.Name())
+
+		var unmarshal func(data []byte, v any) error
+		switch strings.ToLower(path.Ext(file)) {
+		case ".yaml", ".yml":
+			unmarshal = yaml.Unmarshal
+		case ".json":
+			unmarshal = json.Unmarshal
+		default:
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return set, err
+		}
+		var part Set
+		if err := unmarshal(content, &part); err != nil {
+			return set, fmt.Errorf("fixture %s: %w", file, err)
+		}
+		set.Inventories = append(set.Inventories, part.Inventories...)
+		set.Users = append(set.Users, part.Users...)
+	}
+
+	return set, set.validate()
+}
+
+// Builtin returns the fixture sets embedded in the binary.
+func Builtin() fs.FS {
+	fsys, _ := fs.Sub(fixtureFiles, "fixtures")
+	return fsys
+}
+
+func (set Set) validate() error {
+	for i, inv := range set.Inventories {
+		if inv.Code == "" || inv.Name == "" {
+			return fmt.Errorf("inventory %d: code and name are required", i)
+		}
+		if !validStatuses[inv.Status] {
+			return fmt.Errorf("inventory %s: invalid status %q", inv.Code, inv.Status)
+		}
+		if _, err := inventory.NormalizeTags(inv.Tags); err != nil {
+			return fmt.Errorf("inventory %s: %w", inv.Code, err)
+		}
+	}
+	for i, u := range set.Users {
+		if u.Email == "" || u.Password == "" {
+			return fmt.Errorf("user %d: email and password are required", i)
+		}
+		if !validRoles[u.Role] {
+			return fmt.Errorf("user %s: invalid role %q", u.Email, u.Role)
+		}
+	}
+	return nil
+}
+
+// Seeder writes fixture sets through the repositories, so it works with any
+// backend they are built on.
+type Seeder struct {
+	inventoryRepo inventory.Repository
+	userRepo      user.Repository
+}
+
+func NewSeeder(inventoryRepo inventory.Repository, userRepo user.Repository) *Seeder {
+	return &Seeder{
+		inventoryRepo: inventoryRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// Seed creates the inventories and users of set that don't exist yet, found by
+// code and email. Existing records are left untouched, so seeding twice is a
+// no-op and never resets data changed since.
+func (s *Seeder) Seed(ctx context.Context, set Set) (result Result, err error) {
+	for _, inv := range set.Inventories {
+		_, err = s.inventoryRepo.ReadByCode(ctx, inv.Code)
+		if err == nil {
+			result.InventoriesSkipped++
+			continue
+		}
+		if !errors.Is(err, inventory.ErrNotFound) {
+			return result, fmt.Errorf("inventory %s: %w", inv.Code, err)
+		}
+
+		inv.Tags, _ = inventory.NormalizeTags(inv.Tags)
+		if err = s.inventoryRepo.Create(ctx, inv); err != nil {
+			return result, fmt.Errorf("inventory %s: %w", inv.Code, err)
+		}
+		if len(inv.Tags) > 0 {
+			if err = s.inventoryRepo.AddTags(ctx, inv.Code, inv.Tags); err != nil {
+				return result, fmt.Errorf("inventory %s: %w", inv.Code, err)
+			}
+		}
+		result.InventoriesCreated++
+	}
+
+	for _, u := range set.Users {
+		_, err = s.userRepo.GetByEmail(ctx, u.Email)
+		if err == nil {
+			result.UsersSkipped++
+			continue
+		}
+		if !errors.Is(err, user.ErrNotFound) {
+			return result, fmt.Errorf("user %s: %w", u.Email, err)
+		}
+
+		encPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return result, fmt.Errorf("user %s: %w", u.Email, err)
+		}
+		if err = s.userRepo.Create(ctx, user.User{
+			ID:              uuid.NewString(),
+			Email:           u.Email,
+			Password:        string(encPassword),
+			Fullname:        u.Fullname,
+			Role:            u.Role,
+			IsEmailVerified: u.IsEmailVerified,
+		}); err != nil {
+			return result, fmt.Errorf("user %s: %w", u.Email, err)
+		}
+		result.UsersCreated++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package fixture_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	invRepo "github.com/pobyzaarif/belajarGo2/repository/inventory"
+	"github.com/pobyzaarif/belajarGo2/repository/repotest"
+	userRepo "github.com/pobyzaarif/belajarGo2/repository/user"
+	"github.com/pobyzaarif/belajarGo2/service/inventory"
+	"github.com/pobyzaarif/belajarGo2/util/fixture"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoadSet(t *testing.T) {
+	fsys := fstest.MapFS{
+		"small/a.yaml": {Data: []byte("inventories:\n  - {code: A1, name: Cable, stock: 1, status: active, tags: [it]}\n")},
+		"small/b.json": {Data: []byte(`{"users": [{"email": "a@example.com", "password": "secret", "role": "admin"}]}`)},
+		"small/README": {Data: []byte("ignored")},
+		"bad/a.yaml":   {Data: []byte("users:\n  - {email: a@example.com, password: secret, role: root}\n")},
+	}
+
+	sets, err := fixture.Sets(fsys)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bad", "small"}, sets)
+
+	set, err := fixture.LoadSet(fsys, "small")
+	assert.Nil(t, err)
+	if assert.Len(t, set.Inventories, 1) && assert.Len(t, set.Users, 1) {
+		assert.Equal(t, inventory.Inventory{Code: "A1", Name: "Cable", Stock: 1, Status: "active", Tags: []string{"it"}}, set.Inventories[0])
+		assert.Equal(t, fixture.User{Email: "a@example.com", Password: "secret", Role: "admin"}, set.Users[0])
+	}
+
+	_, err = fixture.LoadSet(fsys, "bad")
+	assert.NotNil(t, err)
+
+	_, err = fixture.LoadSet(fsys, "missing")
+	assert.NotNil(t, err)
+}
+
+func TestBuiltinSets(t *testing.T) {
+	sets, err := fixture.Sets(fixture.Builtin())
+	assert.Nil(t, err)
+	assert.Contains(t, sets, "demo")
+	assert.Contains(t, sets, "test")
+
+	for _, name := range sets {
+		_, err := fixture.LoadSet(fixture.Builtin(), name)
+		assert.Nil(t, err, name)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	db := repotest.SQLite(t)
+
+	set, err := fixture.LoadSet(fixture.Builtin(), "test")
+	assert.Nil(t, err)
+
+	invs := invRepo.NewGormRepository(db)
+	users := userRepo.NewGormRepository(db)
+	seeder := fixture.NewSeeder(invs, users)
+
+	result, err := seeder.Seed(ctx, set)
+	assert.Nil(t, err)
+	assert.Equal(t, fixture.Result{InventoriesCreated: len(set.Inventories), UsersCreated: len(set.Users)}, result)
+
+	// Seeding again changes nothing
+	result, err = seeder.Seed(ctx, set)
+	assert.Nil(t, err)
+	assert.Equal(t, fixture.Result{InventoriesSkipped: len(set.Inventories), UsersSkipped: len(set.Users)}, result)
+
+	got, err := invs.ReadAll(ctx, inventory.Filter{Tags: []string{"it"}}, 1, 10)
+	assert.Nil(t, err)
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, "TST001", got[0].Code)
+	}
+
+	admin, err := users.GetByEmail(ctx, "admin@test.local")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", admin.Role)
+	assert.True(t, admin.IsEmailVerified)
+	assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("admin123")))
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	redis "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"gorm.io/gorm"
+)
+
+// Gorm pings the database behind db.
+func Gorm(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// Mongo pings the primary of the server db lives on.
+func Mongo(db *mongo.Database) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.Client().Ping(ctx, readpref.Primary())
+	})
+}
+
+func Redis(client redis.UniversalClient) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
+
+// RabbitMQ opens and closes a connection to the broker at url.
+func RabbitMQ(url string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		conn, err := amqp.DialConfig(url, amqp.Config{
+			Dial: func(network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				conn, err := dialer.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				// Bound the handshake too, not only the dial
+				if deadline, ok := ctx.Deadline(); ok {
+					_ = conn.SetDeadline(deadline)
+				}
+				return conn, nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// HTTP calls url and fails when it doesn't answer or answers with a server
+// error, e.g. to see whether the mail provider is reachable.
+func HTTP(url string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s answered %s", url, resp.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// LivenessHandler serves /healthz, 200 as long as the app answers. The
+// dependencies are reported too, but restarting the app wouldn't bring a
+// database back so they don't change the status code.
+func (h *Health) LivenessHandler() http.Handler {
+	return h.handler(false)
+}
+
+// ReadinessHandler serves /readyz, 503 while a required dependency is down so
+// no traffic is sent to the app until it is back.
+func (h *Health) ReadinessHandler() http.Handler {
+	return h.handler(true)
+}
+
+func (h *Health) handler(readiness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+
+		code := http.StatusOK
+		if readiness && report.Status == StatusDown {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// WatchGRPC sets the serving status of services on server, "" being the
+// whole server, from a check every interval until ctx is done. They are
+// NOT_SERVING while a required dependency is down.
+func (h *Health) WatchGRPC(ctx context.Context, server *grpchealth.Server, interval time.Duration, services ...string) {
+	update := func() {
+		status := healthpb.HealthCheckResponse_SERVING
+		if h.Check(ctx).Status == StatusDown {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		for _, service := range services {
+			server.SetServingStatus(service, status)
+		}
+	}
+
+	update()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package health checks the dependencies of an app, its databases, caches,
+// brokers and providers, for the /healthz and /readyz endpoints and the gRPC
+// health service.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker reports whether a dependency can be used, nil when it can.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc turns a function into a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of checking one dependency.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check, down when a required one is.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type check struct {
+	name     string
+	required bool
+	checker  Checker
+}
+
+// Health runs the registered checks concurrently, each one given at most
+// timeout. Checks are registered at startup, before serving.
+type Health struct {
+	timeout time.Duration
+	checks  []check
+}
+
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Register adds a dependency the app can't serve without, it isn't ready
+// while the check fails.
+func (h *Health) Register(name string, checker Checker) {
+	h.checks = append(h.checks, check{name: name, required: true, checker: checker})
+}
+
+// RegisterOptional adds a dependency that is only reported, e.g. a mail
+// provider whose outage shouldn't take the app out of rotation.
+func (h *Health) RegisterOptional(name string, checker Checker) {
+	h.checks = append(h.checks, check{name: name, checker: checker})
+}
+
+func (h *Health) Check(ctx context.Context) (report Report) {
+	report = Report{Status: StatusUp, Checks: make([]Result, len(h.checks))}
+
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = h.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Required && result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return
+}
+
+func (h *Health) run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.checker.Check(ctx)
+	result := Result{
+		Name:      c.name,
+		Status:    StatusUp,
+		Required:  c.required,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package httperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/pobyzaarif/belajarGo2/service/errs"
+)
+
+// Status maps a domain error to its http status code, a request that ran out
+// of time is a gateway timeout and anything else is an internal server error.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, errs.Validation):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.Unauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, errs.Forbidden):
+		return http.StatusForbidden
+	case errors.Is(err, errs.NotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.Conflict):
+		return http.StatusConflict
+	case errors.Is(err, errs.TooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,43 @@
+// Package rediscache is the go-cache Redis repository with atomic counters,
+// shared by every instance of the apps. Like ttlcache it's for the logged out
+// tokens and failed login counters, Redis only evicts keys before they expire
+// when a maxmemory policy tells it to.
+package rediscache
+
+import (
+	"context"
+	"time"
+
+	cache "github.com/pobyzaarif/go-cache"
+	redis "github.com/redis/go-redis/v9"
+)
+
+type Cache struct {
+	*cache.RedisCacheRepository
+	client *redis.Client
+}
+
+func New(client *redis.Client) *Cache {
+	return &Cache{
+		RedisCacheRepository: cache.NewRedisCacheRepository(client),
+		client:               client,
+	}
+}
+
+// Incr adds one to the counter under key and returns it, the counter goes
+// away expiration after its last increment. INCR is atomic, concurrent
+// increments from any instance are all counted.
+func (c *Cache) Incr(key string, expiration time.Duration) (count int64, err error) {
+	var incr *redis.IntCmd
+	_, err = c.client.TxPipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(context.TODO(), key)
+		if expiration > 0 {
+			pipe.Expire(context.TODO(), key, expiration)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
@@ -0,0 +1,57 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pobyzaarif/belajarGo2/util/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, the last 6 of its 8 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		code, err := totp.Code(secret, totp.Step(time.Unix(unix, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.Nil(t, err)
+
+	now := time.Now()
+	code, err := totp.Code(secret, totp.Step(now))
+	assert.Nil(t, err)
+
+	step, ok := totp.Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// A step of drift is tolerated, two aren't
+	_, ok = totp.Validate(secret, code, now.Add(totp.Period), 1)
+	assert.True(t, ok)
+	_, ok = totp.Validate(secret, code, now.Add(2*totp.Period), 1)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("belajarGo2", "user@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/belajarGo2:user@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=belajarGo2")
+}
@@ -0,0 +1,131 @@
+// Package ttlcache is an in memory cache.Repository whose entries only go
+// away when they expire or are deleted, never to make room for others. It's
+// for state that must not be lost while it's valid, such as logged out tokens
+// and failed login counters, where an evicting cache could be flooded into
+// forgetting them.
+package ttlcache
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Set drops the expired entries nobody read.
+const sweepInterval = time.Minute
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Cache stores values as JSON, like the go-cache repositories, so what comes
+// out of Get is the same whichever of them is used.
+type Cache struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func New() *Cache {
+	return &Cache{
+		entries:   map[string]entry{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Set stores value under key for expiration, forever when it's zero.
+func (c *Cache) Set(key string, value interface{}, expiration time.Duration) (err error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.lastSweep) >= sweepInterval {
+		c.sweep(now)
+	}
+
+	e := entry{value: data}
+	if expiration > 0 {
+		e.expiresAt = now.Add(expiration)
+	}
+	c.entries[key] = e
+	return nil
+}
+
+// Get decodes the value of key into data, leaving data alone on a miss.
+func (c *Cache) Get(key string, data interface{}) (err error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && e.expired(c.now()) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(e.value, data)
+}
+
+// Incr adds one to the counter under key and returns it, the counter goes
+// away expiration after its last increment. Concurrent increments are all
+// counted.
+func (c *Cache) Incr(key string, expiration time.Duration) (count int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if e, ok := c.entries[key]; ok && !e.expired(now) {
+		if err := json.Unmarshal(e.value, &count); err != nil {
+			return 0, err
+		}
+	}
+	count++
+
+	data, err := json.Marshal(count)
+	if err != nil {
+		return 0, err
+	}
+	e := entry{value: data}
+	if expiration > 0 {
+		e.expiresAt = now.Add(expiration)
+	}
+	c.entries[key] = e
+	return count, nil
+}
+
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Len returns the number of entries, expired ones not swept yet included.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+func (c *Cache) sweep(now time.Time) {
+	for key, e := range c.entries {
+		if e.expired(now) {
+			delete(c.entries, key)
+		}
+	}
+	c.lastSweep = now
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
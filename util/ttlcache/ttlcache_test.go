@@ -0,0 +1,95 @@
+package ttlcache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cache "github.com/pobyzaarif/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ cache.Repository = (*Cache)(nil)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := New()
+	c.now = func() time.Time { return now }
+
+	type attempts struct {
+		Count int `json:"count"`
+	}
+	assert.Nil(t, c.Set("attempts", attempts{Count: 3}, time.Minute))
+	assert.Nil(t, c.Set("revoked", true, 0))
+
+	var got attempts
+	assert.Nil(t, c.Get("attempts", &got))
+	assert.Equal(t, 3, got.Count)
+
+	// A miss leaves data alone
+	var missing attempts
+	assert.Nil(t, c.Get("unknown", &missing))
+	assert.Equal(t, attempts{}, missing)
+
+	// Nothing is evicted however many entries there are
+	for i := 0; i < 10000; i++ {
+		assert.Nil(t, c.Set(fmt.Sprintf("flood:%d", i), i, time.Minute))
+	}
+	var revoked bool
+	assert.Nil(t, c.Get("revoked", &revoked))
+	assert.True(t, revoked)
+
+	// Entries go away once they expire, the unread ones with the next sweep
+	now = now.Add(sweepInterval + time.Second)
+	got = attempts{}
+	assert.Nil(t, c.Get("attempts", &got))
+	assert.Equal(t, attempts{}, got)
+
+	assert.Nil(t, c.Set("after", 1, time.Minute))
+	assert.Equal(t, 2, c.Len())
+
+	c.Delete("revoked")
+	revoked = false
+	assert.Nil(t, c.Get("revoked", &revoked))
+	assert.False(t, revoked)
+}
+
+func TestCacheIncr(t *testing.T) {
+	now := time.Now()
+	c := New()
+	c.now = func() time.Time { return now }
+
+	// Concurrent increments are all counted
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Incr("failures", time.Minute)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	var count int64
+	assert.Nil(t, c.Get("failures", &count))
+	assert.Equal(t, int64(50), count)
+
+	// Every increment pushes the expiration back
+	now = now.Add(50 * time.Second)
+	count, err := c.Incr("failures", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(51), count)
+
+	now = now.Add(50 * time.Second)
+	count, err = c.Incr("failures", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(52), count)
+
+	// An expired counter starts over
+	now = now.Add(time.Minute)
+	count, err = c.Incr("failures", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}